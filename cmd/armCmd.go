@@ -7,11 +7,19 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/manisbindra/az-mpf/pkg/domain"
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/ARMTemplateShared"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/ARMTemplateDeployment"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/ARMTemplateValidate"
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/ARMTemplateWhatIf"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/azureAPI"
+	graphpermissionmanager "github.com/manisbindra/az-mpf/pkg/infrastructure/graphPermissionManager"
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/permissionCatalog"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/permissionPredictor"
 	resourceGroupManager "github.com/manisbindra/az-mpf/pkg/infrastructure/resourceGroupManager"
 	sproleassignmentmanager "github.com/manisbindra/az-mpf/pkg/infrastructure/spRoleAssignmentManager"
 	"github.com/manisbindra/az-mpf/pkg/presentation"
@@ -24,8 +32,27 @@ var flgResourceGroupNamePfx string
 var flgDeploymentNamePfx string
 var flgLocation string
 var flgTemplateFilePath string
+var flgTemplateSpecID string
 var flgParametersFilePath string
 var flgFullDeployment bool
+var flgDeploymentTimeout time.Duration
+var flgCheckerMode string
+var flgParameters []string
+var flgPredictPermissions bool
+var flgVerifyMinimal bool
+var flgVerifySufficiency bool
+var flgDiscoveryStrategy string
+var flgBroadRoleName string
+var flgBroadProviders []string
+var flgUpdateScenario bool
+var flgUpdateParametersFilePath string
+var flgGrantGraphPermissions bool
+
+const (
+	checkerModeWhatIf   = "whatIf"
+	checkerModeValidate = "validate"
+	checkerModeHybrid   = "hybrid"
+)
 
 // armCmd represents the arm command
 
@@ -47,14 +74,27 @@ func NewARMCommand() *cobra.Command {
 	armCmd.Flags().StringVarP(&flgDeploymentNamePfx, "deploymentNamePfx", "", "testDeploy", "Deployment Name Prefix")
 
 	armCmd.Flags().StringVarP(&flgTemplateFilePath, "templateFilePath", "", "", "Path to ARM Template File")
-	armCmd.MarkFlagRequired("templateFilePath")
+	armCmd.Flags().StringVarP(&flgTemplateSpecID, "templateSpecID", "", "", "Resource ID of a Template Spec version to use instead of the ARM Template File")
+	armCmd.MarkFlagsOneRequired("templateFilePath", "templateSpecID")
+	armCmd.MarkFlagsMutuallyExclusive("templateFilePath", "templateSpecID")
 
-	armCmd.Flags().StringVarP(&flgParametersFilePath, "parametersFilePath", "", "", "Path to Template Parameters File")
-	armCmd.MarkFlagRequired("parametersFilePath")
+	armCmd.Flags().StringVarP(&flgParametersFilePath, "parametersFilePath", "", "", "Path to Template Parameters File. Values are generated for required parameters missing from the file")
+	armCmd.Flags().StringArrayVarP(&flgParameters, "parameter", "", []string{}, "Parameter value in the key=value format, overriding the parameters file. Can be specified multiple times")
 
 	armCmd.Flags().StringVarP(&flgLocation, "location", "", "eastus", "Location")
 
-	// armCmd.Flags().BoolVarP(&flgFullDeployment, "fullDeployment", "", false, "Full Deployment")
+	armCmd.Flags().BoolVarP(&flgFullDeployment, "fullDeployment", "", false, "Create the resources with a full deployment instead of using What-If. Authorization errors of nested deployments are also discovered in this mode")
+	armCmd.Flags().StringVarP(&flgCheckerMode, "checkerMode", "", checkerModeWhatIf, "Mode used to discover permissions without creating resources: whatIf, validate (faster preflight checks only) or hybrid (converge with validate, then confirm with whatIf)")
+	armCmd.Flags().DurationVarP(&flgDeploymentTimeout, "deploymentTimeout", "", ARMTemplateDeployment.DefaultDeploymentTimeout, "How long to wait for a full deployment to complete, before the authorization errors of the operations which failed so far are used")
+	armCmd.MarkFlagsMutuallyExclusive("fullDeployment", "checkerMode")
+
+	armCmd.Flags().BoolVarP(&flgPredictPermissions, "predictPermissions", "", false, "Seed the custom role with permissions predicted from the template, and report which predicted permissions were confirmed or pruned")
+
+	armCmd.Flags().BoolVarP(&flgVerifyMinimal, "verifyMinimal", "", false, "After the permissions are discovered, remove permissions from the role and rerun the checks to mark each permission as required or unnecessary")
+	armCmd.Flags().BoolVarP(&flgVerifySufficiency, "verifySufficiency", "", false, "After the permissions are discovered, rerun the checks once with a fresh custom role containing exactly the permissions found, and report whether it passed")
+	addDiscoveryStrategyFlags(armCmd)
+	addUpdateScenarioFlags(armCmd)
+	addGraphPermissionsFlags(armCmd)
 
 	return armCmd
 }
@@ -67,8 +107,15 @@ func getMPFARM(cmd *cobra.Command, args []string) {
 	log.Debugf("ResourceGroupNamePfx: %s\n", flgResourceGroupNamePfx)
 	log.Debugf("DeploymentNamePfx: %s\n", flgDeploymentNamePfx)
 	log.Infof("TemplateFilePath: %s\n", flgTemplateFilePath)
+	log.Infof("TemplateSpecID: %s\n", flgTemplateSpecID)
 	log.Infof("ParametersFilePath: %s\n", flgParametersFilePath)
 
+	if flgTemplateSpecID != "" {
+		templateSpecTemplatePath, cleanup := getTemplateSpecTemplateFile(flgTemplateSpecID)
+		defer cleanup()
+		flgTemplateFilePath = templateSpecTemplatePath
+	}
+
 	// validate if template and parameters files exists
 	if _, err := os.Stat(flgTemplateFilePath); os.IsNotExist(err) {
 		log.Fatal("Template File does not exist")
@@ -79,15 +126,20 @@ func getMPFARM(cmd *cobra.Command, args []string) {
 		log.Errorf("Error getting absolute path for ARM template file: %v\n", err)
 	}
 
-	if _, err := os.Stat(flgParametersFilePath); os.IsNotExist(err) {
-		log.Fatal("Parameters File does not exist")
-	}
+	if flgParametersFilePath != "" {
+		if _, err := os.Stat(flgParametersFilePath); os.IsNotExist(err) {
+			log.Fatal("Parameters File does not exist")
+		}
 
-	flgParametersFilePath, err := getAbsolutePath(flgParametersFilePath)
-	if err != nil {
-		log.Errorf("Error getting absolute path for ARM template parameters file: %v\n", err)
+		flgParametersFilePath, err = getAbsolutePath(flgParametersFilePath)
+		if err != nil {
+			log.Errorf("Error getting absolute path for ARM template parameters file: %v\n", err)
+		}
 	}
 
+	deploymentParametersFilePath, cleanupParameters := getDeploymentParametersFile(flgTemplateFilePath, flgParametersFilePath)
+	defer cleanupParameters()
+
 	ctx := context.Background()
 
 	mpfConfig := getRootMPFConfig()
@@ -99,7 +151,7 @@ func getMPFARM(cmd *cobra.Command, args []string) {
 	deploymentName := fmt.Sprintf("%s-%s", flgDeploymentNamePfx, mpfSharedUtils.GenerateRandomString(7))
 	armConfig := &ARMTemplateShared.ArmTemplateAdditionalConfig{
 		TemplateFilePath:   flgTemplateFilePath,
-		ParametersFilePath: flgParametersFilePath,
+		ParametersFilePath: deploymentParametersFilePath,
 		DeploymentName:     deploymentName,
 	}
 
@@ -113,17 +165,32 @@ func getMPFARM(cmd *cobra.Command, args []string) {
 	var initialPermissionsToAdd []string
 	var permissionsToAddToResult []string
 
-	deploymentAuthorizationCheckerCleaner = ARMTemplateWhatIf.NewARMTemplateWhatIfAuthorizationChecker(flgSubscriptionID, *armConfig)
+	checkerARMConfig, cleanupUpdateParameters := getCheckerARMConfig(*armConfig, flgUpdateParametersFilePath)
+	defer cleanupUpdateParameters()
+
+	deploymentAuthorizationCheckerCleaner = getARMDeploymentAuthorizationCheckerCleaner(checkerARMConfig)
 	initialPermissionsToAdd = []string{"Microsoft.Resources/deployments/*", "Microsoft.Resources/subscriptions/operationresults/read"}
 	permissionsToAddToResult = []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
 
 	mpfService = usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, deploymentAuthorizationCheckerCleaner, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, true, false, true)
+	mpfService.SetVerifyMinimal(flgVerifyMinimal)
+	if flgVerifySufficiency {
+		mpfService.SetSufficiencyVerificationRole(getNewMPFRole())
+	}
+	setDiscoveryStrategy(mpfService)
+	setGraphPermissionManager(mpfService)
+	if flgPredictPermissions {
+		setPredictedARMTemplatePermissions(mpfService, flgTemplateFilePath)
+	}
+	if flgUpdateScenario {
+		mpfService.SetInitialStateDeployer(ARMTemplateDeployment.NewARMTemplateInitialStateDeployer(flgSubscriptionID, *armConfig))
+	}
 
 	displayOptions := getDislayOptions(flgShowDetailedOutput, flgJSONOutput, mpfConfig.ResourceGroup.ResourceGroupResourceID)
 
 	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
 	if err != nil {
-		if len(mpfResult.RequiredPermissions) > 0 {
+		if len(mpfResult.RequiredPermissions) > 0 || len(mpfResult.GraphPermissions) > 0 {
 			fmt.Println("Error occurred while getting minimum permissions required. However, some permissions were identified prior to the error.")
 			displayResult(mpfResult, displayOptions)
 		}
@@ -133,6 +200,176 @@ func getMPFARM(cmd *cobra.Command, args []string) {
 	displayResult(mpfResult, displayOptions)
 }
 
+// getTemplateSpecTemplateFile saves the template of the template spec version, with linked templates inlined, to a temporary file
+func getTemplateSpecTemplateFile(templateSpecID string) (string, func()) {
+	log.Infof("Fetching Template Spec: %s\n", templateSpecID)
+
+	bearerToken, err := azureAPI.NewAzureAPIClients(flgSubscriptionID).GetDefaultAPIBearerToken()
+	if err != nil {
+		log.Fatalf("Error getting bearer token to fetch template spec: %v\n", err)
+	}
+
+	template, err := ARMTemplateShared.GetTemplateSpecTemplate(templateSpecID, bearerToken)
+	if err != nil {
+		log.Fatalf("Error fetching template spec: %v\n", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "az-mpf-templatespec-")
+	if err != nil {
+		log.Fatalf("Error creating temporary directory for template spec: %v\n", err)
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(tmpDir)
+	}
+
+	templateFilePath := filepath.Join(tmpDir, "templateSpec.json")
+	err = mpfSharedUtils.WriteJson(templateFilePath, template)
+	if err != nil {
+		cleanup()
+		log.Fatalf("Error saving template spec template: %v\n", err)
+	}
+
+	log.Infoln("Template Spec template saved at:", templateFilePath)
+	return templateFilePath, cleanup
+}
+
+// getDeploymentParametersFile saves the parameters of the parameters file, merged with the --parameter overrides and
+// generated values for the missing required parameters, to a temporary file. The values are generated once, so that all
+// iterations deploy the same resources
+func getDeploymentParametersFile(templateFilePath string, parametersFilePath string) (string, func()) {
+	parameterOverrides, err := ARMTemplateShared.ParseParameterOverrides(flgParameters)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	deploymentParameters, err := ARMTemplateShared.GetDeploymentParameters(templateFilePath, parametersFilePath, parameterOverrides)
+	if err != nil {
+		log.Fatalf("Error getting deployment parameters: %v\n", err)
+	}
+
+	return writeDeploymentParametersFile(deploymentParameters)
+}
+
+// writeDeploymentParametersFile saves the deployment parameters to a temporary file, which is removed by the returned cleanup function.
+// The file holds the generated securestring values, so it is also removed by an exit handler when the command exits on an error,
+// as deferred cleanup functions do not run then
+func writeDeploymentParametersFile(deploymentParameters map[string]interface{}) (string, func()) {
+	tmpDir, err := os.MkdirTemp("", "az-mpf-parameters-")
+	if err != nil {
+		log.Fatalf("Error creating temporary directory for deployment parameters: %v\n", err)
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(tmpDir)
+	}
+	log.RegisterExitHandler(cleanup)
+
+	deploymentParametersFilePath := filepath.Join(tmpDir, "parameters.json")
+	err = mpfSharedUtils.WriteJson(deploymentParametersFilePath, deploymentParameters)
+	if err != nil {
+		cleanup()
+		log.Fatalf("Error saving deployment parameters: %v\n", err)
+	}
+
+	return deploymentParametersFilePath, cleanup
+}
+
+func addUpdateScenarioFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&flgUpdateScenario, "updateScenario", "", false, "Deploy the template with the credentials MPF runs with first, then discover the permissions required to update the deployed resources with a second deployment")
+	cmd.Flags().StringVarP(&flgUpdateParametersFilePath, "updateParametersFilePath", "", "", "Path to the Parameters File of the second deployment of the update scenario. Its parameters are merged into the parameters of the first deployment")
+}
+
+// getCheckerARMConfig returns the config of the deployment of the checker. In the update scenario with an update parameters
+// file, the checker deploys the parameters of the update parameters file merged into the parameters of the initial deployment
+func getCheckerARMConfig(armConfig ARMTemplateShared.ArmTemplateAdditionalConfig, updateParametersFilePath string) (ARMTemplateShared.ArmTemplateAdditionalConfig, func()) {
+	if !flgUpdateScenario || updateParametersFilePath == "" {
+		return armConfig, func() {}
+	}
+
+	if _, err := os.Stat(updateParametersFilePath); os.IsNotExist(err) {
+		log.Fatal("Update Parameters File does not exist")
+	}
+
+	updateDeploymentParameters, err := ARMTemplateShared.GetUpdateDeploymentParameters(armConfig.ParametersFilePath, updateParametersFilePath)
+	if err != nil {
+		log.Fatalf("Error getting update deployment parameters: %v\n", err)
+	}
+
+	updateDeploymentParametersFilePath, cleanup := writeDeploymentParametersFile(updateDeploymentParameters)
+	armConfig.ParametersFilePath = updateDeploymentParametersFilePath
+	return armConfig, cleanup
+}
+
+func setPredictedARMTemplatePermissions(mpfService *usecase.MPFService, templateFilePath string) {
+	predictedPermissions, err := permissionPredictor.PredictARMTemplateFilePermissions(templateFilePath)
+	if err != nil {
+		log.Warnf("Unable to predict permissions from template, continuing without prediction: %v\n", err)
+		return
+	}
+	log.Infof("Predicted %d permissions from template\n", len(predictedPermissions))
+	mpfService.SetPredictedPermissions(predictedPermissions)
+}
+
+func addDiscoveryStrategyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&flgDiscoveryStrategy, "discoveryStrategy", "", "additive", "Strategy to discover the permissions, additive adds the permissions of each authorization error to the role, subtractive starts from a broad set of permissions and removes the ones which are not required")
+	cmd.Flags().StringVarP(&flgBroadRoleName, "broadRoleName", "", "", "Built-in role whose actions are used as the broad set of permissions for subtractive discovery, for example Contributor")
+	cmd.Flags().StringSliceVarP(&flgBroadProviders, "broadProviders", "", []string{}, "Resource provider namespaces whose operations are used as the broad set of permissions for subtractive discovery, for example Microsoft.Network")
+}
+
+func setDiscoveryStrategy(mpfService *usecase.MPFService) {
+	switch flgDiscoveryStrategy {
+	case "additive":
+		mpfService.SetDiscoveryStrategy(usecase.NewAdditiveDiscoveryStrategy())
+	case "subtractive":
+		if flgBroadRoleName == "" && len(flgBroadProviders) == 0 {
+			log.Fatalln("broadRoleName or broadProviders is required for subtractive discovery")
+		}
+		broadPermissions, err := permissionCatalog.NewPermissionCatalog(flgSubscriptionID).GetBroadPermissions(flgBroadRoleName, flgBroadProviders)
+		if err != nil {
+			log.Fatalf("Error getting broad permissions: %v\n", err)
+		}
+		log.Infof("Starting subtractive discovery from %d broad permissions\n", len(broadPermissions))
+		mpfService.SetDiscoveryStrategy(usecase.NewSubtractiveDiscoveryStrategy(broadPermissions))
+	default:
+		log.Fatalf("Invalid discovery strategy: %s, valid values are additive and subtractive\n", flgDiscoveryStrategy)
+	}
+}
+
+func addGraphPermissionsFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&flgGrantGraphPermissions, "grantGraphPermissions", "", false, "Grant the Microsoft Graph application permissions found to the service principal, so that permissions are discovered past Microsoft Graph authorization errors. The credentials MPF runs with require the AppRoleAssignment.ReadWrite.All permission. The permissions granted are revoked when MPF completes")
+}
+
+func setGraphPermissionManager(mpfService *usecase.MPFService) {
+	if flgGrantGraphPermissions {
+		mpfService.SetGraphPermissionManager(graphpermissionmanager.NewGraphPermissionManager(flgSubscriptionID))
+	}
+}
+
+func getARMDeploymentAuthorizationCheckerCleaner(armConfig ARMTemplateShared.ArmTemplateAdditionalConfig) usecase.DeploymentAuthorizationCheckerCleaner {
+	if flgFullDeployment {
+		log.Infoln("Full deployment mode, resources will be created")
+		deploymentChecker := ARMTemplateDeployment.NewARMTemplateDeploymentAuthorizationChecker(flgSubscriptionID, armConfig)
+		if flgDeploymentTimeout > 0 {
+			deploymentChecker.SetDeploymentTimeout(flgDeploymentTimeout)
+		}
+		return deploymentChecker
+	}
+
+	switch flgCheckerMode {
+	case checkerModeWhatIf:
+		return ARMTemplateWhatIf.NewARMTemplateWhatIfAuthorizationChecker(flgSubscriptionID, armConfig)
+	case checkerModeValidate:
+		return ARMTemplateValidate.NewARMTemplateValidateAuthorizationChecker(flgSubscriptionID, armConfig)
+	case checkerModeHybrid:
+		return usecase.NewStagedDeploymentAuthorizationChecker(
+			usecase.DeploymentAuthorizationCheckerStage{Name: checkerModeValidate, Checker: ARMTemplateValidate.NewARMTemplateValidateAuthorizationChecker(flgSubscriptionID, armConfig)},
+			usecase.DeploymentAuthorizationCheckerStage{Name: checkerModeWhatIf, Checker: ARMTemplateWhatIf.NewARMTemplateWhatIfAuthorizationChecker(flgSubscriptionID, armConfig)},
+		)
+	}
+
+	log.Fatalf("Invalid checker mode: %s, valid values are %s, %s and %s\n", flgCheckerMode, checkerModeWhatIf, checkerModeValidate, checkerModeHybrid)
+	return nil
+}
+
 func getDislayOptions(flgShowDetailedOutput bool, flgJSONOutput bool, rgResourceId string) presentation.DisplayOptions {
 	return presentation.DisplayOptions{
 		ShowDetailedOutput:             flgShowDetailedOutput,
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/ARMTemplateShared"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/azdProject"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/bicepCompiler"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
+	resourceGroupManager "github.com/manisbindra/az-mpf/pkg/infrastructure/resourceGroupManager"
+	sproleassignmentmanager "github.com/manisbindra/az-mpf/pkg/infrastructure/spRoleAssignmentManager"
+	"github.com/manisbindra/az-mpf/pkg/usecase"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flgAzdProjectDir  string
+	flgAzdEnvironment string
+)
+
+func NewAzdCommand() *cobra.Command {
+	azdCmd := &cobra.Command{
+		Use:   "azd",
+		Short: "Find minimum permissions required for the Bicep infrastructure of an Azure Developer CLI project",
+		Long: `Find minimum permissions required for the Bicep infrastructure of an Azure Developer CLI (azd) project. The infrastructure path and module
+are read from azure.yaml, and the ${VAR} references of the parameters file are substituted with the values of the .azure/<environment>/.env file,
+as azd does before provisioning. Templates with targetScope = 'subscription' are deployed at subscription scope. For example:
+
+az-mpf azd --projectDir ./todo-nodejs-mongo --environment dev --bicepExecPath /usr/local/bin/bicep`,
+		Run: getMPFAzd,
+	}
+
+	azdCmd.Flags().StringVarP(&flgAzdProjectDir, "projectDir", "", "", "Path to the azd Project Directory, which contains azure.yaml")
+	azdCmd.MarkFlagRequired("projectDir")
+	azdCmd.Flags().StringVarP(&flgAzdEnvironment, "environment", "", "", "Name of the azd environment, AZURE_ENV_NAME or the default environment of the project when not set")
+
+	azdCmd.Flags().StringVarP(&flgBicepExecPath, "bicepExecPath", "", "", "Bicep Executable Path")
+	azdCmd.MarkFlagRequired("bicepExecPath")
+
+	azdCmd.Flags().StringVarP(&flgResourceGroupNamePfx, "resourceGroupNamePfx", "", "testdeployrg", "Resource Group Name Prefix")
+	azdCmd.Flags().StringVarP(&flgDeploymentNamePfx, "deploymentNamePfx", "", "testDeploy", "Deployment Name Prefix")
+	azdCmd.Flags().StringArrayVarP(&flgParameters, "parameter", "", []string{}, "Parameter value in the key=value format, overriding the parameters file. Can be specified multiple times")
+	azdCmd.Flags().StringVarP(&flgLocation, "location", "", "eastus", "Location, overriding AZURE_LOCATION of the azd environment when set. Used when the environment has no location otherwise")
+
+	azdCmd.Flags().BoolVarP(&flgFullDeployment, "fullDeployment", "", false, "Create the resources with a full deployment instead of using What-If. Not supported for templates deployed at subscription scope, as the resource groups they create are not deleted by MPF")
+	azdCmd.Flags().StringVarP(&flgCheckerMode, "checkerMode", "", checkerModeWhatIf, "Mode used to discover permissions without creating resources: whatIf, validate (faster preflight checks only) or hybrid (converge with validate, then confirm with whatIf)")
+	azdCmd.MarkFlagsMutuallyExclusive("fullDeployment", "checkerMode")
+
+	azdCmd.Flags().BoolVarP(&flgPredictPermissions, "predictPermissions", "", false, "Seed the custom role with permissions predicted from the compiled template, and report which predicted permissions were confirmed or pruned")
+
+	azdCmd.Flags().BoolVarP(&flgVerifyMinimal, "verifyMinimal", "", false, "After the permissions are discovered, remove permissions from the role and rerun the checks to mark each permission as required or unnecessary")
+	azdCmd.Flags().BoolVarP(&flgVerifySufficiency, "verifySufficiency", "", false, "After the permissions are discovered, rerun the checks once with a fresh custom role containing exactly the permissions found, and report whether it passed")
+	addDiscoveryStrategyFlags(azdCmd)
+	addGraphPermissionsFlags(azdCmd)
+
+	return azdCmd
+}
+
+func getMPFAzd(cmd *cobra.Command, args []string) {
+	setLogLevel()
+
+	log.Info("Executing MPF for azd")
+	log.Infof("ProjectDir: %s\n", flgAzdProjectDir)
+	log.Infof("Environment: %s\n", flgAzdEnvironment)
+	log.Infof("BicepExecPath: %s\n", flgBicepExecPath)
+
+	if _, err := os.Stat(flgBicepExecPath); os.IsNotExist(err) {
+		log.Fatal("Bicep Executable does not exist")
+	}
+
+	bicepExecPath, err := getAbsolutePath(flgBicepExecPath)
+	if err != nil {
+		log.Fatalf("Error getting absolute path for bicep executable: %v\n", err)
+	}
+
+	projectDir, err := getAbsolutePath(flgAzdProjectDir)
+	if err != nil {
+		log.Fatalf("Error getting absolute path for azd project directory: %v\n", err)
+	}
+
+	project, err := azdProject.LoadAzdProject(projectDir, flgAzdEnvironment)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	mpfConfig := getRootMPFConfig()
+
+	// the infrastructure is deployed to the subscription of MPF, by its service principal
+	project.SetEnv(azdProject.SubscriptionIDKey, flgSubscriptionID)
+	if cmd.Flags().Changed("location") {
+		project.SetEnv(azdProject.LocationKey, flgLocation)
+	}
+	project.SetEnvDefault(azdProject.LocationKey, flgLocation)
+	project.SetEnvDefault(azdProject.PrincipalIDKey, mpfConfig.SP.SPObjectID)
+	location := project.Env[azdProject.LocationKey]
+
+	bicepFilePath := project.BicepFilePath()
+	log.Infof("Environment Name: %s\n", project.EnvironmentName)
+	log.Infof("BicepFilePath: %s\n", bicepFilePath)
+	log.Infof("Location: %s\n", location)
+
+	if _, err := os.Stat(bicepFilePath); os.IsNotExist(err) {
+		log.Fatalf("Bicep File of azd project does not exist: %s\n", bicepFilePath)
+	}
+
+	compiler, err := bicepCompiler.NewBicepCompiler(bicepExecPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	compiler.SetEnv(project.GetEnvList())
+
+	// the temporary files, such as the parameters files with the substituted values of the environment, are also removed
+	// before exiting on errors, as deferred functions do not run then. The exit handler removes them when the shared
+	// helpers of the commands exit on errors
+	cleanupSubstitutedParameters := func() {}
+	cleanupParameters := func() {}
+	cleanup := func() {
+		compiler.Cleanup()
+		cleanupSubstitutedParameters()
+		cleanupParameters()
+	}
+	defer cleanup()
+	log.RegisterExitHandler(cleanup)
+
+	armTemplatePath, err := compiler.Build(bicepFilePath)
+	if err != nil {
+		cleanup()
+		log.Fatal(err)
+	}
+
+	armParametersPath := project.ParametersFilePath()
+	log.Infof("ParametersFilePath: %s\n", armParametersPath)
+	if strings.HasSuffix(armParametersPath, ".bicepparam") {
+		armParametersPath, err = compiler.BuildParams(armParametersPath, bicepFilePath)
+		if err != nil {
+			cleanup()
+			log.Fatal(err)
+		}
+	} else if armParametersPath != "" {
+		substitutedParametersPath, cleanupSubstituted, err := project.SubstituteParametersFile(armParametersPath)
+		if err != nil {
+			cleanup()
+			log.Fatal(err)
+		}
+		armParametersPath = substitutedParametersPath
+		cleanupSubstitutedParameters = cleanupSubstituted
+	}
+
+	var deploymentParametersFilePath string
+	deploymentParametersFilePath, cleanupParameters = getDeploymentParametersFile(armTemplatePath, armParametersPath)
+
+	subscriptionScope, err := ARMTemplateShared.IsSubscriptionDeploymentTemplate(armTemplatePath)
+	if err != nil {
+		cleanup()
+		log.Fatal(err)
+	}
+	if subscriptionScope {
+		log.Infoln("Template of azd project is deployed at subscription scope")
+		// the resource groups a subscription scoped template creates are not deleted by the cleanup of full deployments
+		if flgFullDeployment {
+			cleanup()
+			log.Fatal("Full deployment is not supported for templates deployed at subscription scope, as the resource groups created by the template are not deleted by MPF. Use the whatIf or validate checker mode instead")
+		}
+	}
+
+	ctx := context.Background()
+
+	mpfRG := domain.ResourceGroup{}
+	mpfRG.ResourceGroupName = fmt.Sprintf("%s-%s", flgResourceGroupNamePfx, mpfSharedUtils.GenerateRandomString(7))
+	mpfRG.ResourceGroupResourceID = fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", flgSubscriptionID, mpfRG.ResourceGroupName)
+	mpfRG.Location = location
+	mpfConfig.ResourceGroup = mpfRG
+	deploymentName := fmt.Sprintf("%s-%s", flgDeploymentNamePfx, mpfSharedUtils.GenerateRandomString(7))
+	armConfig := ARMTemplateShared.ArmTemplateAdditionalConfig{
+		TemplateFilePath:   armTemplatePath,
+		ParametersFilePath: deploymentParametersFilePath,
+		DeploymentName:     deploymentName,
+		SubscriptionScope:  subscriptionScope,
+		Location:           location,
+	}
+
+	var rgManager usecase.ResourceGroupManager
+	var spRoleAssignmentManager usecase.ServicePrincipalRolemAssignmentManager
+	rgManager = resourceGroupManager.NewResourceGroupManager(flgSubscriptionID)
+	spRoleAssignmentManager = sproleassignmentmanager.NewSPRoleAssignmentManager(flgSubscriptionID)
+
+	deploymentAuthorizationCheckerCleaner := getARMDeploymentAuthorizationCheckerCleaner(armConfig)
+	initialPermissionsToAdd := []string{"Microsoft.Resources/deployments/*", "Microsoft.Resources/subscriptions/operationresults/read"}
+	permissionsToAddToResult := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
+
+	// the resource group of MPF is only needed by templates deployed to a resource group
+	mpfService := usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, deploymentAuthorizationCheckerCleaner, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, true, false, !subscriptionScope)
+	mpfService.SetVerifyMinimal(flgVerifyMinimal)
+	if flgVerifySufficiency {
+		mpfService.SetSufficiencyVerificationRole(getNewMPFRole())
+	}
+	setDiscoveryStrategy(mpfService)
+	setGraphPermissionManager(mpfService)
+	if flgPredictPermissions {
+		setPredictedARMTemplatePermissions(mpfService, armTemplatePath)
+	}
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+
+	displayOptions := getDislayOptions(flgShowDetailedOutput, flgJSONOutput, mpfConfig.ResourceGroup.ResourceGroupResourceID)
+
+	if err != nil {
+		if len(mpfResult.RequiredPermissions) > 0 || len(mpfResult.GraphPermissions) > 0 {
+			fmt.Println("Error occurred while getting minimum permissions required. However, some permissions were identified prior to the error.")
+			displayResult(mpfResult, displayOptions)
+		}
+		cleanup()
+		log.Fatal(err)
+	}
+
+	displayResult(mpfResult, displayOptions)
+}
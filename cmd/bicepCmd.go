@@ -7,13 +7,12 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"github.com/manisbindra/az-mpf/pkg/domain"
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/ARMTemplateShared"
-	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/ARMTemplateWhatIf"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/ARMTemplateDeployment"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/bicepCompiler"
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
 	resourceGroupManager "github.com/manisbindra/az-mpf/pkg/infrastructure/resourceGroupManager"
 	sproleassignmentmanager "github.com/manisbindra/az-mpf/pkg/infrastructure/spRoleAssignmentManager"
@@ -44,18 +43,28 @@ func NewBicepCommand() *cobra.Command {
 	bicepCmd.Flags().StringVarP(&flgResourceGroupNamePfx, "resourceGroupNamePfx", "", "testdeployrg", "Resource Group Name Prefix")
 	bicepCmd.Flags().StringVarP(&flgDeploymentNamePfx, "deploymentNamePfx", "", "testDeploy", "Deployment Name Prefix")
 
-	bicepCmd.Flags().StringVarP(&flgBicepFilePath, "bicepFilePath", "", "", "Path to bicep File")
-	bicepCmd.MarkFlagRequired("bicepFilePath")
+	bicepCmd.Flags().StringVarP(&flgBicepFilePath, "bicepFilePath", "", "", "Path to bicep File. Optional if the parameters file is a .bicepparam file, in which case the file of its using declaration is used")
 
-	bicepCmd.Flags().StringVarP(&flgParametersFilePath, "parametersFilePath", "", "", "Path to bicep Parameters File")
-	bicepCmd.MarkFlagRequired("parametersFilePath")
+	bicepCmd.Flags().StringVarP(&flgParametersFilePath, "parametersFilePath", "", "", "Path to bicep Parameters File, either a JSON parameters file or a .bicepparam file. Values are generated for required parameters missing from the file")
+	bicepCmd.Flags().StringArrayVarP(&flgParameters, "parameter", "", []string{}, "Parameter value in the key=value format, overriding the parameters file. Can be specified multiple times")
 
 	bicepCmd.Flags().StringVarP(&flgBicepExecPath, "bicepExecPath", "", "", "Bicep Executable Path")
 	bicepCmd.MarkFlagRequired("bicepExecPath")
 
 	bicepCmd.Flags().StringVarP(&flgLocation, "location", "", "eastus", "Location")
 
-	// bicepCmd.Flags().BoolVarP(&flgFullDeployment, "fullDeployment", "", false, "Full Deployment")
+	bicepCmd.Flags().BoolVarP(&flgFullDeployment, "fullDeployment", "", false, "Create the resources with a full deployment instead of using What-If. Authorization errors of nested deployments are also discovered in this mode")
+	bicepCmd.Flags().StringVarP(&flgCheckerMode, "checkerMode", "", checkerModeWhatIf, "Mode used to discover permissions without creating resources: whatIf, validate (faster preflight checks only) or hybrid (converge with validate, then confirm with whatIf)")
+	bicepCmd.Flags().DurationVarP(&flgDeploymentTimeout, "deploymentTimeout", "", ARMTemplateDeployment.DefaultDeploymentTimeout, "How long to wait for a full deployment to complete, before the authorization errors of the operations which failed so far are used")
+	bicepCmd.MarkFlagsMutuallyExclusive("fullDeployment", "checkerMode")
+
+	bicepCmd.Flags().BoolVarP(&flgPredictPermissions, "predictPermissions", "", false, "Seed the custom role with permissions predicted from the compiled template, and report which predicted permissions were confirmed or pruned")
+
+	bicepCmd.Flags().BoolVarP(&flgVerifyMinimal, "verifyMinimal", "", false, "After the permissions are discovered, remove permissions from the role and rerun the checks to mark each permission as required or unnecessary")
+	bicepCmd.Flags().BoolVarP(&flgVerifySufficiency, "verifySufficiency", "", false, "After the permissions are discovered, rerun the checks once with a fresh custom role containing exactly the permissions found, and report whether it passed")
+	addDiscoveryStrategyFlags(bicepCmd)
+	addUpdateScenarioFlags(bicepCmd)
+	addGraphPermissionsFlags(bicepCmd)
 
 	return bicepCmd
 }
@@ -71,43 +80,81 @@ func getMPFBicep(cmd *cobra.Command, args []string) {
 	log.Infof("ParametersFilePath: %s\n", flgParametersFilePath)
 	log.Infof("BicepExecPath: %s\n", flgBicepExecPath)
 
-	// validate if template and parameters files exists
-	if _, err := os.Stat(flgBicepFilePath); os.IsNotExist(err) {
-		log.Fatal("Bicep File does not exist")
-	}
-
 	if _, err := os.Stat(flgBicepExecPath); os.IsNotExist(err) {
 		log.Fatal("Bicep Executable does not exist")
 	}
 
-	if _, err := os.Stat(flgParametersFilePath); os.IsNotExist(err) {
-		log.Fatal("Parameters File does not exist")
-	}
-
 	flgBicepExecPath, err := getAbsolutePath(flgBicepExecPath)
 	if err != nil {
 		log.Errorf("Error getting absolute path for bicep executable: %v\n", err)
 	}
 
+	if flgParametersFilePath != "" {
+		if _, err := os.Stat(flgParametersFilePath); os.IsNotExist(err) {
+			log.Fatal("Parameters File does not exist")
+		}
+
+		flgParametersFilePath, err = getAbsolutePath(flgParametersFilePath)
+		if err != nil {
+			log.Errorf("Error getting absolute path for parameters file: %v\n", err)
+		}
+	}
+
+	isBicepparam := strings.HasSuffix(flgParametersFilePath, ".bicepparam")
+	if flgBicepFilePath == "" {
+		if !isBicepparam {
+			log.Fatal("Bicep File Path is required, unless the parameters file is a .bicepparam file")
+		}
+		flgBicepFilePath, err = bicepCompiler.GetBicepFileFromBicepparam(flgParametersFilePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// validate if bicep file exists
+	if _, err := os.Stat(flgBicepFilePath); os.IsNotExist(err) {
+		log.Fatal("Bicep File does not exist")
+	}
+
 	flgBicepFilePath, err := getAbsolutePath(flgBicepFilePath)
 	if err != nil {
 		log.Errorf("Error getting absolute path for bicep file: %v\n", err)
 	}
 
-	flgParametersFilePath, err := getAbsolutePath(flgParametersFilePath)
+	compiler, err := bicepCompiler.NewBicepCompiler(flgBicepExecPath)
 	if err != nil {
-		log.Errorf("Error getting absolute path for parameters file: %v\n", err)
+		log.Fatal(err)
 	}
+	defer compiler.Cleanup()
+	// the parameters compiled from a .bicepparam file may hold secrets, and are also removed when the command exits on an error
+	log.RegisterExitHandler(func() { _ = compiler.Cleanup() })
 
-	armTemplatePath := strings.TrimSuffix(flgBicepFilePath, ".bicep") + ".json"
-	bicepCmd := exec.Command(flgBicepExecPath, "build", flgBicepFilePath, "--outfile", armTemplatePath)
-	bicepCmd.Dir = filepath.Dir(flgBicepFilePath)
-
-	_, err = bicepCmd.CombinedOutput()
+	armTemplatePath, err := compiler.Build(flgBicepFilePath)
 	if err != nil {
-		log.Errorf("error running bicep build: %s", err)
+		compiler.Cleanup()
+		log.Fatal(err)
+	}
+
+	armParametersPath := flgParametersFilePath
+	if isBicepparam {
+		armParametersPath, err = compiler.BuildParams(flgParametersFilePath, flgBicepFilePath)
+		if err != nil {
+			compiler.Cleanup()
+			log.Fatal(err)
+		}
+	}
+
+	deploymentParametersFilePath, cleanupParameters := getDeploymentParametersFile(armTemplatePath, armParametersPath)
+	defer cleanupParameters()
+
+	updateParametersPath := flgUpdateParametersFilePath
+	if flgUpdateScenario && strings.HasSuffix(updateParametersPath, ".bicepparam") {
+		updateParametersPath, err = compiler.BuildParams(flgUpdateParametersFilePath, flgBicepFilePath)
+		if err != nil {
+			compiler.Cleanup()
+			log.Fatal(err)
+		}
 	}
-	log.Infoln("Bicep build successful, ARM Template created at:", armTemplatePath)
 
 	ctx := context.Background()
 
@@ -120,7 +167,7 @@ func getMPFBicep(cmd *cobra.Command, args []string) {
 	deploymentName := fmt.Sprintf("%s-%s", flgDeploymentNamePfx, mpfSharedUtils.GenerateRandomString(7))
 	armConfig := &ARMTemplateShared.ArmTemplateAdditionalConfig{
 		TemplateFilePath:   armTemplatePath,
-		ParametersFilePath: flgParametersFilePath,
+		ParametersFilePath: deploymentParametersFilePath,
 		DeploymentName:     deploymentName,
 	}
 
@@ -134,31 +181,37 @@ func getMPFBicep(cmd *cobra.Command, args []string) {
 	var initialPermissionsToAdd []string
 	var permissionsToAddToResult []string
 
-	deploymentAuthorizationCheckerCleaner = ARMTemplateWhatIf.NewARMTemplateWhatIfAuthorizationChecker(flgSubscriptionID, *armConfig)
+	checkerARMConfig, cleanupUpdateParameters := getCheckerARMConfig(*armConfig, updateParametersPath)
+	defer cleanupUpdateParameters()
+
+	deploymentAuthorizationCheckerCleaner = getARMDeploymentAuthorizationCheckerCleaner(checkerARMConfig)
 	initialPermissionsToAdd = []string{"Microsoft.Resources/deployments/*", "Microsoft.Resources/subscriptions/operationresults/read"}
 	permissionsToAddToResult = []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
 
 	mpfService = usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, deploymentAuthorizationCheckerCleaner, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, true, false, true)
-
-	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
-	if err != nil {
-		log.Fatal(err)
+	mpfService.SetVerifyMinimal(flgVerifyMinimal)
+	if flgVerifySufficiency {
+		mpfService.SetSufficiencyVerificationRole(getNewMPFRole())
 	}
-
-	log.Infoln("Deleting Generated ARM Template file...")
-	// delete generated ARM template file
-	err = os.Remove(armTemplatePath)
-	if err != nil {
-		log.Errorf("Error deleting Generated ARM template file: %v\n", err)
+	setDiscoveryStrategy(mpfService)
+	setGraphPermissionManager(mpfService)
+	if flgPredictPermissions {
+		setPredictedARMTemplatePermissions(mpfService, armTemplatePath)
+	}
+	if flgUpdateScenario {
+		mpfService.SetInitialStateDeployer(ARMTemplateDeployment.NewARMTemplateInitialStateDeployer(flgSubscriptionID, *armConfig))
 	}
 
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+
 	displayOptions := getDislayOptions(flgShowDetailedOutput, flgJSONOutput, mpfConfig.ResourceGroup.ResourceGroupResourceID)
 
 	if err != nil {
-		if len(mpfResult.RequiredPermissions) > 0 {
+		if len(mpfResult.RequiredPermissions) > 0 || len(mpfResult.GraphPermissions) > 0 {
 			fmt.Println("Error occurred while getting minimum permissions required. However, some permissions were identified prior to the error.")
 			displayResult(mpfResult, displayOptions)
 		}
+		compiler.Cleanup()
 		log.Fatal(err)
 	}
 
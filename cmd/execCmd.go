@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/command"
+	resourceGroupManager "github.com/manisbindra/az-mpf/pkg/infrastructure/resourceGroupManager"
+	sproleassignmentmanager "github.com/manisbindra/az-mpf/pkg/infrastructure/spRoleAssignmentManager"
+	"github.com/manisbindra/az-mpf/pkg/usecase"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flgCleanupCommand string
+	flgAzPath         string
+)
+
+func NewExecCommand() *cobra.Command {
+	execCmd := &cobra.Command{
+		Use:   "exec -- <command> [args...]",
+		Short: "Find minimum permissions required for a command, such as an Azure CLI or PowerShell script",
+		Long: `Find minimum permissions required for a command, such as an Azure CLI or PowerShell script, by running it with the credentials of the service principal
+and scanning its output for authorization errors. The credentials are exported in the ARM_* and AZURE_* environment variables, and the service principal
+is logged in to the Azure CLI in a configuration directory of its own, which is set as AZURE_CONFIG_DIR. For example:
+
+az-mpf exec --cleanupCommand "az group delete --name rg-mpf --yes" -- ./deploy.sh rg-mpf eastus2`,
+		Args: cobra.MinimumNArgs(1),
+		Run:  getMPFExec,
+	}
+
+	execCmd.Flags().StringVarP(&flgCleanupCommand, "cleanupCommand", "", "", "Shell command run with the credentials MPF runs with after each run of the command, to delete the resources it created")
+	execCmd.Flags().StringVarP(&flgWorkingDir, "workingDir", "", "", "Directory the command and the cleanup command run in, the current directory when not set")
+	execCmd.Flags().StringVarP(&flgAzPath, "azPath", "", "az", "Path to the Azure CLI executable, used to log the service principal in. The login is skipped when the Azure CLI is not found")
+
+	execCmd.Flags().BoolVarP(&flgVerifyMinimal, "verifyMinimal", "", false, "After the permissions are discovered, remove permissions from the role and rerun the checks to mark each permission as required or unnecessary")
+	execCmd.Flags().BoolVarP(&flgVerifySufficiency, "verifySufficiency", "", false, "After the permissions are discovered, rerun the checks once with a fresh custom role containing exactly the permissions found, and report whether it passed")
+	addDiscoveryStrategyFlags(execCmd)
+	addGraphPermissionsFlags(execCmd)
+
+	return execCmd
+}
+
+func getMPFExec(cmd *cobra.Command, args []string) {
+	setLogLevel()
+
+	log.Info("Executing MPF for command")
+	log.Infof("Command: %v\n", args)
+	log.Infof("CleanupCommand: %s\n", flgCleanupCommand)
+
+	if flgCleanupCommand == "" {
+		log.Warnln("No cleanup command is set, the resources created by the command are not deleted between runs")
+	}
+
+	ctx := context.Background()
+	mpfConfig := getRootMPFConfig()
+
+	var rgManager usecase.ResourceGroupManager
+	var spRoleAssignmentManager usecase.ServicePrincipalRolemAssignmentManager
+	rgManager = resourceGroupManager.NewResourceGroupManager(flgSubscriptionID)
+	spRoleAssignmentManager = sproleassignmentmanager.NewSPRoleAssignmentManager(flgSubscriptionID)
+
+	initialPermissionsToAdd := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
+	permissionsToAddToResult := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
+
+	commandAuthorizationChecker := command.NewCommandAuthorizationChecker(args, flgCleanupCommand, flgWorkingDir, flgAzPath)
+	defer commandAuthorizationChecker.RemoveAzureConfigDir()
+
+	mpfService := usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, commandAuthorizationChecker, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, false, false, false)
+	mpfService.SetVerifyMinimal(flgVerifyMinimal)
+	if flgVerifySufficiency {
+		mpfService.SetSufficiencyVerificationRole(getNewMPFRole())
+	}
+	setDiscoveryStrategy(mpfService)
+	setGraphPermissionManager(mpfService)
+
+	displayOptions := getDislayOptions(flgShowDetailedOutput, flgJSONOutput, mpfConfig.ResourceGroup.ResourceGroupResourceID)
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	if err != nil {
+		if len(mpfResult.RequiredPermissions) > 0 || len(mpfResult.GraphPermissions) > 0 {
+			fmt.Println("Error occurred while getting minimum permissions required. However, some permissions were identified prior to the error.")
+			displayResult(mpfResult, displayOptions)
+		}
+		commandAuthorizationChecker.RemoveAzureConfigDir()
+		log.Fatal(err)
+	}
+
+	displayResult(mpfResult, displayOptions)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/bicepCompiler"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/permissionPredictor"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// credential flags of the root command, which are not needed for predictions
+var credentialFlags = []string{"subscriptionID", "tenantID", "spClientID", "spObjectID", "spClientSecret"}
+
+func NewPredictCommand() *cobra.Command {
+
+	predictCmd := &cobra.Command{
+		Use:   "predict",
+		Short: "Predict the permissions required by an ARM template or bicep file without deploying it",
+		Long: `Predict the permissions required by an ARM template or bicep file without deploying it. For example:
+
+	The resources of the template, including nested deployments and existing resources, are analysed offline to predict
+	the write and read permissions, and the linked actions required. No Azure credentials are needed.`,
+		Example: `az-mpf predict --templateFilePath ./samples/templates/aks.json
+		az-mpf predict --bicepFilePath ./samples/bicep/aks-private-subnet.bicep --bicepExecPath /usr/local/bin/bicep`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			for _, flagName := range credentialFlags {
+				err := cmd.Flags().SetAnnotation(flagName, cobra.BashCompOneRequiredFlag, []string{"false"})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Run: getPredictedPermissions,
+	}
+
+	predictCmd.Flags().StringVarP(&flgTemplateFilePath, "templateFilePath", "", "", "Path to ARM Template File")
+	predictCmd.Flags().StringVarP(&flgBicepFilePath, "bicepFilePath", "", "", "Path to bicep File")
+	predictCmd.MarkFlagsOneRequired("templateFilePath", "bicepFilePath")
+	predictCmd.MarkFlagsMutuallyExclusive("templateFilePath", "bicepFilePath")
+
+	predictCmd.Flags().StringVarP(&flgBicepExecPath, "bicepExecPath", "", "", "Bicep Executable Path, required for bicep files")
+	predictCmd.MarkFlagsRequiredTogether("bicepFilePath", "bicepExecPath")
+
+	return predictCmd
+}
+
+func getPredictedPermissions(cmd *cobra.Command, args []string) {
+	setLogLevel()
+
+	log.Info("Executing MPF permission prediction")
+
+	templateFilePath := flgTemplateFilePath
+	if flgBicepFilePath != "" {
+		compiler, err := bicepCompiler.NewBicepCompiler(flgBicepExecPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer compiler.Cleanup()
+
+		bicepFilePath, err := getAbsolutePath(flgBicepFilePath)
+		if err != nil {
+			log.Errorf("Error getting absolute path for bicep file: %v\n", err)
+		}
+
+		templateFilePath, err = compiler.Build(bicepFilePath)
+		if err != nil {
+			compiler.Cleanup()
+			log.Fatal(err)
+		}
+	}
+
+	if _, err := os.Stat(templateFilePath); os.IsNotExist(err) {
+		log.Fatal("Template File does not exist")
+	}
+
+	predictedPermissions, err := permissionPredictor.PredictARMTemplateFilePermissions(templateFilePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if flgJSONOutput {
+		jsonBytes, err := json.Marshal(predictedPermissions)
+		if err != nil {
+			log.Fatalf("Error converting output to JSON :%v \n", err)
+		}
+		fmt.Println(string(jsonBytes))
+		return
+	}
+
+	fmt.Println("------------------------------------------------------------------------------------------------------------------------------------------")
+	fmt.Println("Predicted Permissions:")
+	fmt.Println("------------------------------------------------------------------------------------------------------------------------------------------")
+	for _, perm := range predictedPermissions {
+		fmt.Println(perm)
+	}
+	fmt.Println("------------------------------------------------------------------------------------------------------------------------------------------")
+}
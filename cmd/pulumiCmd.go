@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/pulumi"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
+	resourceGroupManager "github.com/manisbindra/az-mpf/pkg/infrastructure/resourceGroupManager"
+	sproleassignmentmanager "github.com/manisbindra/az-mpf/pkg/infrastructure/spRoleAssignmentManager"
+	"github.com/manisbindra/az-mpf/pkg/usecase"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flgPulumiPath   string
+	flgPulumiStack  string
+	flgPulumiConfig []string
+	flgPulumiPhases []string
+)
+
+func NewPulumiCommand() *cobra.Command {
+	pulumiCmd := &cobra.Command{
+		Use:   "pulumi",
+		Short: "Find minimum permissions required for a Pulumi stack",
+		Long: `Find minimum permissions required for a Pulumi stack, by running pulumi up and destroy with the credentials of the service principal.
+The stack is created in a local file backend of MPF, so that the backend of the project is not used. For example:
+
+az-mpf pulumi --pulumiPath /usr/local/bin/pulumi --workingDir ./samples/pulumi/vnet --config azure-native:location=eastus2`,
+		Run: getMPFPulumi,
+	}
+
+	pulumiCmd.Flags().StringVarP(&flgPulumiPath, "pulumiPath", "", "", "Path to Pulumi Executable")
+	pulumiCmd.MarkFlagRequired("pulumiPath")
+
+	pulumiCmd.Flags().StringVarP(&flgWorkingDir, "workingDir", "", "", "Path to the Pulumi Project Directory, which contains Pulumi.yaml")
+	pulumiCmd.MarkFlagRequired("workingDir")
+
+	pulumiCmd.Flags().StringVarP(&flgPulumiStack, "stack", "", "mpf", "Name of the stack created in the local file backend of MPF. The project must not have a Pulumi.<stack>.yaml configuration file for the stack, as the file is removed with the stack")
+	pulumiCmd.Flags().StringArrayVarP(&flgPulumiConfig, "config", "", []string{}, "Stack configuration as key=value, passed to pulumi up with --config, can be specified multiple times")
+	pulumiCmd.Flags().StringSliceVarP(&flgPulumiPhases, "phases", "", []string{pulumi.PulumiPhaseUp, pulumi.PulumiPhaseDestroy}, "Pulumi phases to discover permissions for, up and destroy. With only up, the destroy permissions are not discovered, and the resources are destroyed with the credentials MPF runs with")
+
+	pulumiCmd.Flags().BoolVarP(&flgVerifyMinimal, "verifyMinimal", "", false, "After the permissions are discovered, remove permissions from the role and rerun the checks to mark each permission as required or unnecessary")
+	pulumiCmd.Flags().BoolVarP(&flgVerifySufficiency, "verifySufficiency", "", false, "After the permissions are discovered, rerun the checks once with a fresh custom role containing exactly the permissions found, and report whether it passed")
+	addDiscoveryStrategyFlags(pulumiCmd)
+	addGraphPermissionsFlags(pulumiCmd)
+
+	return pulumiCmd
+}
+
+func getMPFPulumi(cmd *cobra.Command, args []string) {
+	setLogLevel()
+
+	log.Info("Executing MPF for Pulumi")
+	log.Infof("PulumiPath: %s\n", flgPulumiPath)
+	log.Infof("WorkingDir: %s\n", flgWorkingDir)
+	log.Infof("Stack: %s\n", flgPulumiStack)
+
+	if _, err := os.Stat(filepath.Join(flgWorkingDir, "Pulumi.yaml")); os.IsNotExist(err) {
+		log.Fatalf("Pulumi Project File Pulumi.yaml does not exist in Working Directory: %s\n", flgWorkingDir)
+	}
+
+	workingDir, err := getAbsolutePath(flgWorkingDir)
+	if err != nil {
+		log.Fatalf("Error getting absolute path for pulumi working directory: %v\n", err)
+	}
+
+	if _, err := os.Stat(flgPulumiPath); os.IsNotExist(err) {
+		log.Fatalf("Pulumi Executable does not exist: %s\n", flgPulumiPath)
+	}
+
+	pulumiPath, err := getAbsolutePath(flgPulumiPath)
+	if err != nil {
+		log.Fatalf("Error getting absolute path for pulumi executable: %v\n", err)
+	}
+
+	ctx := context.Background()
+	mpfConfig := getRootMPFConfig()
+
+	var rgManager usecase.ResourceGroupManager
+	var spRoleAssignmentManager usecase.ServicePrincipalRolemAssignmentManager
+	rgManager = resourceGroupManager.NewResourceGroupManager(flgSubscriptionID)
+	spRoleAssignmentManager = sproleassignmentmanager.NewSPRoleAssignmentManager(flgSubscriptionID)
+
+	initialPermissionsToAdd := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
+	permissionsToAddToResult := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
+
+	// The files of MPF runs are kept in a run directory of MPF, and not in the pulumi project directory
+	runDir, err := mpfSharedUtils.NewRunDir("pulumi", workingDir)
+	if err != nil {
+		log.Fatalf("Error creating MPF run directory: %v\n", err)
+	}
+	defer runDir.Release()
+
+	pulumiAuthorizationChecker := pulumi.NewPulumiAuthorizationChecker(workingDir, runDir, pulumiPath, flgPulumiStack)
+	err = pulumiAuthorizationChecker.SetConfig(flgPulumiConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = pulumiAuthorizationChecker.SetPhases(flgPulumiPhases)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// delete permissions are not added for each write, so that they are found in the destroy phase, and not in the role for the up phase
+	mpfService := usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, pulumiAuthorizationChecker, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, false, false, false)
+	mpfService.SetVerifyMinimal(flgVerifyMinimal)
+	if flgVerifySufficiency {
+		mpfService.SetSufficiencyVerificationRole(getNewMPFRole())
+	}
+	setDiscoveryStrategy(mpfService)
+	setGraphPermissionManager(mpfService)
+
+	displayOptions := getDislayOptions(flgShowDetailedOutput, flgJSONOutput, mpfConfig.ResourceGroup.ResourceGroupResourceID)
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	if err != nil {
+		if len(mpfResult.RequiredPermissions) > 0 || len(mpfResult.GraphPermissions) > 0 {
+			fmt.Println("Error occurred while getting minimum permissions required. However, some permissions were identified prior to the error.")
+			displayResult(mpfResult, displayOptions)
+		}
+		log.Fatal(err)
+	}
+
+	displayResult(mpfResult, displayOptions)
+}
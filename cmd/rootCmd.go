@@ -79,6 +79,10 @@ func NewRootCommand() *cobra.Command {
 	rootCmd.AddCommand(NewARMCommand())
 	rootCmd.AddCommand(NewBicepCommand())
 	rootCmd.AddCommand(NewTerraformCommand())
+	rootCmd.AddCommand(NewPulumiCommand())
+	rootCmd.AddCommand(NewExecCommand())
+	rootCmd.AddCommand(NewAzdCommand())
+	rootCmd.AddCommand(NewPredictCommand())
 
 	return rootCmd
 }
@@ -135,13 +139,7 @@ func setLogLevel() {
 }
 
 func getRootMPFConfig() domain.MPFConfig {
-	mpfRole := domain.Role{}
-
-	roleDefUUID, _ := uuid.NewRandom()
-	mpfRole.RoleDefinitionID = roleDefUUID.String()
-	mpfRole.RoleDefinitionName = fmt.Sprintf("tmp-rol-%s", mpfSharedUtils.GenerateRandomString(7))
-	mpfRole.RoleDefinitionResourceID = fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Authorization/roleDefinitions/%s", flgSubscriptionID, mpfRole.RoleDefinitionID)
-	log.Infoln("roleDefinitionResourceID:", mpfRole.RoleDefinitionResourceID)
+	mpfRole := getNewMPFRole()
 
 	return domain.MPFConfig{
 		SubscriptionID: flgSubscriptionID,
@@ -155,6 +153,19 @@ func getRootMPFConfig() domain.MPFConfig {
 	}
 }
 
+// getNewMPFRole returns a new temporary custom role, with a random ID and name
+func getNewMPFRole() domain.Role {
+	mpfRole := domain.Role{}
+
+	roleDefUUID, _ := uuid.NewRandom()
+	mpfRole.RoleDefinitionID = roleDefUUID.String()
+	mpfRole.RoleDefinitionName = fmt.Sprintf("tmp-rol-%s", mpfSharedUtils.GenerateRandomString(7))
+	mpfRole.RoleDefinitionResourceID = fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Authorization/roleDefinitions/%s", flgSubscriptionID, mpfRole.RoleDefinitionID)
+	log.Infoln("roleDefinitionResourceID:", mpfRole.RoleDefinitionResourceID)
+
+	return mpfRole
+}
+
 func getAbsolutePath(path string) (string, error) {
 	absPath := path
 	if !filepath.IsAbs(path) {
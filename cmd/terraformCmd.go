@@ -7,8 +7,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/manisbindra/az-mpf/pkg/domain"
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/terraform"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
 	resourceGroupManager "github.com/manisbindra/az-mpf/pkg/infrastructure/resourceGroupManager"
 	sproleassignmentmanager "github.com/manisbindra/az-mpf/pkg/infrastructure/spRoleAssignmentManager"
 	"github.com/manisbindra/az-mpf/pkg/usecase"
@@ -23,6 +26,26 @@ var (
 	flgVarFilePath                    string
 	flgImportExistingResourcesToState bool
 	flgTargetModule                   string
+	flgPhases                         []string
+	flgIsolate                        bool
+	flgVarFiles                       []string
+	flgVars                           []string
+	flgTargets                        []string
+	flgParallelism                    int
+	flgRefresh                        bool
+	flgLockTimeout                    string
+	flgWorkspace                      string
+	flgBackendConfigFiles             []string
+	flgCreateResourceGroup            bool
+	flgInjectRunContext               bool
+	flgResourceGroupNameVar           string
+	flgLocationVar                    string
+	flgSubscriptionIDVar              string
+	flgRandomSuffixVar                string
+	flgPlanOnly                       bool
+	flgUpdateVarFiles                 []string
+	flgPluginDirs                     []string
+	flgTFEnvPassthrough               []string
 )
 
 const FoundPermissionsFromFailedRunFilename = ".permissionsFromFailedRun.json"
@@ -54,6 +77,40 @@ func NewTerraformCommand() *cobra.Command {
 
 	terraformCmd.Flags().StringVarP(&flgTargetModule, "targetModule", "", "", "The Terraform module to Target Module to run MPF on")
 
+	terraformCmd.Flags().StringArrayVarP(&flgVarFiles, "varFile", "", []string{}, "Path to a Terraform Variable File, can be specified multiple times")
+	terraformCmd.Flags().StringArrayVarP(&flgVars, "var", "", []string{}, "Terraform variable as key=value, can be specified multiple times")
+	terraformCmd.Flags().StringArrayVarP(&flgTargets, "target", "", []string{}, "Terraform resource or module address to target, can be specified multiple times")
+	terraformCmd.Flags().IntVarP(&flgParallelism, "parallelism", "", 0, "Number of concurrent terraform operations, the terraform default is used when not set")
+	terraformCmd.Flags().BoolVarP(&flgRefresh, "refresh", "", true, "Refresh the state before terraform plan and destroy, --refresh=false skips the refresh")
+	terraformCmd.Flags().StringVarP(&flgLockTimeout, "lockTimeout", "", "", "Duration to retry acquiring the terraform state lock, for example 60s")
+	terraformCmd.Flags().StringVarP(&flgWorkspace, "workspace", "", "", "Terraform workspace to select, the workspace is created if it does not exist")
+	terraformCmd.Flags().StringArrayVarP(&flgPluginDirs, "pluginDir", "", []string{}, "Directory of a local provider mirror passed to terraform init as -plugin-dir, so that providers are installed without access to the registry. Can be specified multiple times")
+	terraformCmd.Flags().StringSliceVarP(&flgTFEnvPassthrough, "tfEnvPassthrough", "", []string{}, "Names of environment variables passed through to terraform, in addition to HOME, TF_PLUGIN_CACHE_DIR, TF_CLI_CONFIG_FILE and the proxy and certificate variables. TF_DATA_DIR is only passed through when it is listed")
+	terraformCmd.Flags().StringArrayVarP(&flgBackendConfigFiles, "backendConfig", "", []string{}, "Path to a Terraform backend configuration file passed to terraform init, can be specified multiple times")
+
+	terraformCmd.Flags().StringSliceVarP(&flgPhases, "phases", "", []string{terraform.TFPhaseApply, terraform.TFPhaseDestroy}, "Terraform phases to discover permissions for, apply and destroy. With only apply, the destroy permissions are not discovered, and the resources are destroyed with the credentials MPF runs with")
+	terraformCmd.Flags().BoolVarP(&flgPlanOnly, "planOnly", "", false, "Only run terraform plan, with refresh, against the infrastructure already deployed for the state, to discover the read permissions needed to plan. Nothing is applied or destroyed. For ARM templates and Bicep, the equivalent is the default whatIf checker mode of the arm and bicep commands")
+	terraformCmd.Flags().BoolVarP(&flgUpdateScenario, "updateScenario", "", false, "Apply the module with the credentials MPF runs with first, then discover the permissions required to update the deployed resources with a second apply")
+	terraformCmd.Flags().StringArrayVarP(&flgUpdateVarFiles, "updateVarFile", "", []string{}, "Path to a Terraform Variable File added to the var files of the second apply of the update scenario, can be specified multiple times")
+
+	terraformCmd.Flags().BoolVarP(&flgCreateResourceGroup, "createResourceGroup", "", false, "Create a resource group for the run, which is deleted once MPF completes. Its name is passed to the module with --injectRunContext")
+	terraformCmd.Flags().StringVarP(&flgResourceGroupNamePfx, "resourceGroupNamePfx", "", "testdeployrg", "Resource Group Name Prefix")
+	terraformCmd.Flags().StringVarP(&flgLocation, "location", "", "eastus", "Location")
+	terraformCmd.Flags().BoolVarP(&flgInjectRunContext, "injectRunContext", "", false, "Pass the resource group name, location, subscription ID and a random suffix of the run as terraform variables, for the variables declared by the module")
+	terraformCmd.Flags().StringVarP(&flgResourceGroupNameVar, "resourceGroupNameVar", "", "mpf_resource_group_name", "Name of the terraform variable for the resource group name of the run")
+	terraformCmd.Flags().StringVarP(&flgLocationVar, "locationVar", "", "mpf_location", "Name of the terraform variable for the location of the run")
+	terraformCmd.Flags().StringVarP(&flgSubscriptionIDVar, "subscriptionIDVar", "", "mpf_subscription_id", "Name of the terraform variable for the subscription ID of the run")
+	terraformCmd.Flags().StringVarP(&flgRandomSuffixVar, "randomSuffixVar", "", "mpf_random_suffix", "Name of the terraform variable for a random suffix, to make the resource names of the run unique")
+
+	terraformCmd.Flags().BoolVarP(&flgIsolate, "isolate", "", false, "Run terraform in a temporary copy of the working directory, with a local backend and a fresh state, so that the .terraform directory, state and backend of the working directory are not used")
+
+	terraformCmd.Flags().BoolVarP(&flgPredictPermissions, "predictPermissions", "", false, "Seed the custom role with permissions predicted from terraform plan, and report which predicted permissions were confirmed or pruned")
+
+	terraformCmd.Flags().BoolVarP(&flgVerifyMinimal, "verifyMinimal", "", false, "After the permissions are discovered, remove permissions from the role and rerun the checks to mark each permission as required or unnecessary")
+	terraformCmd.Flags().BoolVarP(&flgVerifySufficiency, "verifySufficiency", "", false, "After the permissions are discovered, rerun the checks once with a fresh custom role containing exactly the permissions found, and report whether it passed")
+	addDiscoveryStrategyFlags(terraformCmd)
+	addGraphPermissionsFlags(terraformCmd)
+
 	return terraformCmd
 }
 
@@ -102,6 +159,13 @@ func getMPFTerraform(cmd *cobra.Command, args []string) {
 	ctx := context.Background()
 
 	mpfConfig := getRootMPFConfig()
+	if flgCreateResourceGroup {
+		mpfRG := domain.ResourceGroup{}
+		mpfRG.ResourceGroupName = fmt.Sprintf("%s-%s", flgResourceGroupNamePfx, mpfSharedUtils.GenerateRandomString(7))
+		mpfRG.ResourceGroupResourceID = fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", flgSubscriptionID, mpfRG.ResourceGroupName)
+		mpfRG.Location = flgLocation
+		mpfConfig.ResourceGroup = mpfRG
+	}
 
 	var rgManager usecase.ResourceGroupManager
 	var spRoleAssignmentManager usecase.ServicePrincipalRolemAssignmentManager
@@ -113,14 +177,30 @@ func getMPFTerraform(cmd *cobra.Command, args []string) {
 
 	initialPermissionsToAdd := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
 	permissionsToAddToResult := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
+	if flgPlanOnly {
+		// the role to plan against the deployed infrastructure is a read only role
+		initialPermissionsToAdd = []string{"Microsoft.Resources/deployments/read"}
+		permissionsToAddToResult = []string{"Microsoft.Resources/deployments/read"}
+		if flgIsolate {
+			log.Warnln("The isolated workspace has a fresh state, so the plan does not read the infrastructure deployed for the state of the working directory")
+		}
+	}
 
-	// Check if permissions file from previous failed run exists
-	if terraform.DoesTFFileExist(flgWorkingDir, FoundPermissionsFromFailedRunFilename) {
-		prevResult, err := terraform.LoadMPFResultFromFile(flgWorkingDir, FoundPermissionsFromFailedRunFilename)
+	// The files of MPF runs are kept in a run directory of MPF, and not in the terraform module directory. The run directory is
+	// keyed by the working directory, also in an isolated workspace, so that the runs of the module share the failed run permissions
+	runDir, err := mpfSharedUtils.NewRunDir("terraform", flgWorkingDir)
+	if err != nil {
+		log.Fatalf("Error creating MPF run directory: %v\n", err)
+	}
+	defer runDir.Release()
+
+	// Check if permissions file from previous failed run of the module exists
+	if terraform.DoesTFFileExist(runDir.ModuleDir, FoundPermissionsFromFailedRunFilename) {
+		prevResult, err := terraform.LoadMPFResultFromFile(runDir.ModuleDir, FoundPermissionsFromFailedRunFilename)
 		if err != nil {
 			log.Warnf("Error loading permissions from previous failed run: %v\n, continuing....", err)
 		}
-		prevRunFoundPermissions := prevResult.RequiredPermissions[""]
+		prevRunFoundPermissions := getPreviousRunPermissions(prevResult)
 		if len(prevRunFoundPermissions) > 0 {
 			log.Warnf("Found permissions from previous failed run: %v\n Adding the Permissions....", prevRunFoundPermissions)
 			initialPermissionsToAdd = append(initialPermissionsToAdd, prevRunFoundPermissions...)
@@ -128,26 +208,205 @@ func getMPFTerraform(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	deploymentAuthorizationCheckerCleaner = terraform.NewTerraformAuthorizationChecker(flgWorkingDir, flgTFPath, flgVarFilePath, flgImportExistingResourcesToState, flgTargetModule)
-	mpfService = usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, deploymentAuthorizationCheckerCleaner, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, false, true, false)
+	terraformOptions := getTerraformOptions()
+	if flgInjectRunContext {
+		terraformOptions.Vars = append(getRunContextVars(flgWorkingDir, mpfConfig), terraformOptions.Vars...)
+	}
+
+	tfWorkingDir := flgWorkingDir
+	cleanupWorkspace := func() {}
+	if flgIsolate {
+		workspaceDir, err := terraform.CreateIsolatedWorkspace(flgWorkingDir)
+		if err != nil {
+			log.Fatalf("Error creating isolated terraform workspace: %v\n", err)
+		}
+		tfWorkingDir = workspaceDir
+		cleanupWorkspace = func() {
+			err := os.RemoveAll(workspaceDir)
+			if err != nil {
+				log.Warnf("Error removing isolated terraform workspace %s: %v\n", workspaceDir, err)
+			}
+		}
+	}
+	defer cleanupWorkspace()
+
+	terraformAuthorizationChecker := terraform.NewTerraformAuthorizationChecker(tfWorkingDir, runDir, flgTFPath, flgVarFilePath, flgImportExistingResourcesToState, flgTargetModule)
+	err = terraformAuthorizationChecker.SetOptions(getCheckerTerraformOptions(terraformOptions))
+	if err != nil {
+		cleanupWorkspace()
+		log.Fatal(err)
+	}
+	if flgUpdateScenario {
+		err = terraformAuthorizationChecker.SetInitialStateOptions(terraformOptions)
+		if err != nil {
+			cleanupWorkspace()
+			log.Fatal(err)
+		}
+	}
+	err = terraformAuthorizationChecker.SetPhases(flgPhases)
+	if err != nil {
+		cleanupWorkspace()
+		log.Fatal(err)
+	}
+	err = terraformAuthorizationChecker.SetIsolated(flgIsolate)
+	if err != nil {
+		cleanupWorkspace()
+		log.Fatal(err)
+	}
+	terraformAuthorizationChecker.SetPlanOnly(flgPlanOnly)
+	terraformAuthorizationChecker.SetEnvPassthrough(flgTFEnvPassthrough)
+
+	deploymentAuthorizationCheckerCleaner = terraformAuthorizationChecker
+	// delete permissions are not added for each write, so that they are found in the destroy phase, and not in the role for the apply phase
+	mpfService = usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, deploymentAuthorizationCheckerCleaner, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, false, false, flgCreateResourceGroup)
+	mpfService.SetVerifyMinimal(flgVerifyMinimal)
+	if flgVerifySufficiency {
+		mpfService.SetSufficiencyVerificationRole(getNewMPFRole())
+	}
+	setDiscoveryStrategy(mpfService)
+	setGraphPermissionManager(mpfService)
+	if flgUpdateScenario {
+		mpfService.SetInitialStateDeployer(terraformAuthorizationChecker)
+	}
+	if flgPredictPermissions {
+		predictedPermissions, err := terraformAuthorizationChecker.PredictPermissions()
+		if err != nil {
+			log.Warnf("Unable to predict permissions from terraform plan, continuing without prediction: %v\n", err)
+		} else {
+			log.Infof("Predicted %d permissions from terraform plan\n", len(predictedPermissions))
+			mpfService.SetPredictedPermissions(predictedPermissions)
+		}
+	}
 
 	displayOptions := getDislayOptions(flgShowDetailedOutput, flgJSONOutput, mpfConfig.ResourceGroup.ResourceGroupResourceID)
 
 	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
 	if err != nil {
-		if len(mpfResult.RequiredPermissions) > 0 {
+		if len(mpfResult.RequiredPermissions) > 0 || len(mpfResult.GraphPermissions) > 0 {
 			fmt.Println("Error occurred while getting minimum permissions required. However, some permissions were identified prior to the error.")
-			_ = terraform.SaveMPFResultsToFile(flgWorkingDir, FoundPermissionsFromFailedRunFilename, mpfResult)
+			_ = terraform.SaveMPFResultsToFile(runDir.ModuleDir, FoundPermissionsFromFailedRunFilename, mpfResult)
 
 			displayResult(mpfResult, displayOptions)
 		}
+		cleanupWorkspace()
 		log.Fatal(err)
 	}
 
-	if terraform.DoesTFFileExist(flgWorkingDir, FoundPermissionsFromFailedRunFilename) {
-		_ = terraform.DeleteTFFile(flgWorkingDir, FoundPermissionsFromFailedRunFilename)
+	if terraform.DoesTFFileExist(runDir.ModuleDir, FoundPermissionsFromFailedRunFilename) {
+		_ = terraform.DeleteTFFile(runDir.ModuleDir, FoundPermissionsFromFailedRunFilename)
 	}
 
 	displayResult(mpfResult, displayOptions)
 
+	if flgPlanOnly && !flgJSONOutput {
+		fmt.Println("The permissions above are the permissions to run terraform plan against the deployed infrastructure.")
+		fmt.Println("For ARM templates and Bicep, the equivalent is the default whatIf checker mode of the arm and bicep commands, which runs What-If without creating resources.")
+	}
+}
+
+// getTerraformOptions returns the terraform CLI options of the flags, with the var file path and target module flags
+// added to the var files and targets. File paths are made absolute, as terraform may run in an isolated workspace
+func getTerraformOptions() terraform.TerraformOptions {
+	varFiles := append([]string{}, flgVarFiles...)
+	if flgVarFilePath != "" {
+		varFiles = append([]string{flgVarFilePath}, varFiles...)
+	}
+
+	targets := append([]string{}, flgTargets...)
+	if flgTargetModule != "" {
+		targets = append([]string{flgTargetModule}, targets...)
+	}
+
+	return terraform.TerraformOptions{
+		VarFiles:           getExistingAbsolutePaths(varFiles, "Terraform Variable File"),
+		Vars:               flgVars,
+		Targets:            targets,
+		Parallelism:        flgParallelism,
+		NoRefresh:          !flgRefresh,
+		LockTimeout:        flgLockTimeout,
+		Workspace:          flgWorkspace,
+		BackendConfigFiles: getExistingAbsolutePaths(flgBackendConfigFiles, "Terraform Backend Configuration File"),
+		PluginDirs:         getExistingAbsolutePaths(flgPluginDirs, "Terraform Plugin Directory"),
+	}
+}
+
+// getCheckerTerraformOptions returns the options of the checker, which in the update scenario are the options of the
+// initial state with the update var files
+func getCheckerTerraformOptions(terraformOptions terraform.TerraformOptions) terraform.TerraformOptions {
+	if !flgUpdateScenario {
+		return terraformOptions
+	}
+
+	terraformOptions.VarFiles = append(append([]string{}, terraformOptions.VarFiles...), getExistingAbsolutePaths(flgUpdateVarFiles, "Terraform Update Variable File")...)
+	return terraformOptions
+}
+
+func getExistingAbsolutePaths(paths []string, description string) []string {
+	absPaths := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			log.Fatalf("%s does not exist: %s\n", description, path)
+		}
+
+		absPath, err := getAbsolutePath(path)
+		if err != nil {
+			log.Fatalf("Error getting absolute path for %s: %v\n", path, err)
+		}
+		absPaths = append(absPaths, absPath)
+	}
+	return absPaths
+}
+
+// getRunContextVars returns the run context as -var assignments, for the variables declared by the module.
+// Variables given with --var are passed after the run context, and take precedence
+func getRunContextVars(workingDir string, mpfConfig domain.MPFConfig) []string {
+	declaredVariables, err := terraform.GetDeclaredVariables(workingDir)
+	if err != nil {
+		log.Fatalf("Error reading terraform variables of module: %v\n", err)
+	}
+
+	runContext := []struct {
+		name  string
+		value string
+	}{
+		{flgSubscriptionIDVar, mpfConfig.SubscriptionID},
+		{flgLocationVar, flgLocation},
+		{flgRandomSuffixVar, strings.ToLower(mpfSharedUtils.GenerateRandomString(7))},
+	}
+	if flgCreateResourceGroup {
+		runContext = append(runContext, struct {
+			name  string
+			value string
+		}{flgResourceGroupNameVar, mpfConfig.ResourceGroup.ResourceGroupName})
+	}
+
+	var vars []string
+	for _, v := range runContext {
+		if v.name == "" {
+			continue
+		}
+		if !declaredVariables[v.name] {
+			log.Infof("Terraform variable %s is not declared by the module, not passing it\n", v.name)
+			continue
+		}
+		log.Infof("Passing terraform variable %s=%s\n", v.name, v.value)
+		vars = append(vars, fmt.Sprintf("%s=%s", v.name, v.value))
+	}
+	return vars
+}
+
+// getPreviousRunPermissions returns the permissions of the saved result of a failed run, which are stored for the
+// resource group of the run, or for the empty scope when no resource group was created
+func getPreviousRunPermissions(prevResult *domain.MPFResult) []string {
+	if prevResult == nil {
+		return nil
+	}
+
+	var permissions []string
+	for scope, scopePermissions := range prevResult.RequiredPermissions {
+		if scope == "" || !strings.Contains(scope, "/providers/") {
+			permissions = append(permissions, scopePermissions...)
+		}
+	}
+	return permissions
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/manisbindra/az-mpf/pkg/domain"
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/ARMTemplateShared"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/ARMTemplateValidate"
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/ARMTemplateWhatIf"
 	mpfSharedUtils "github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
 	resourceGroupManager "github.com/manisbindra/az-mpf/pkg/infrastructure/resourceGroupManager"
@@ -239,3 +240,96 @@ func TestARMTemplatAksPrivateSubnetTemplate(t *testing.T) {
 	assert.NotEmpty(t, mpfResult.RequiredPermissions)
 	assert.Equal(t, 8, len(mpfResult.RequiredPermissions[mpfConfig.ResourceGroup.ResourceGroupResourceID]))
 }
+
+func TestARMTemplatAksPrivateSubnetTemplateHybridValidateWhatIf(t *testing.T) {
+
+	mpfArgs, err := getTestingMPFArgs()
+	if err != nil {
+		t.Skip("required environment variables not set, skipping end to end test")
+	}
+	mpfArgs.TemplateFilePath = "../samples/templates/aks-private-subnet.json"
+	mpfArgs.ParametersFilePath = "../samples/templates/aks-private-subnet-parameters.json"
+
+	ctx := context.Background()
+
+	mpfConfig := getMPFConfig(mpfArgs)
+
+	deploymentName := fmt.Sprintf("%s-%s", mpfArgs.DeploymentNamePfx, mpfSharedUtils.GenerateRandomString(7))
+	armConfig := &ARMTemplateShared.ArmTemplateAdditionalConfig{
+		TemplateFilePath:   mpfArgs.TemplateFilePath,
+		ParametersFilePath: mpfArgs.ParametersFilePath,
+		DeploymentName:     deploymentName,
+	}
+
+	var rgManager usecase.ResourceGroupManager
+	var spRoleAssignmentManager usecase.ServicePrincipalRolemAssignmentManager
+	rgManager = resourceGroupManager.NewResourceGroupManager(mpfArgs.SubscriptionID)
+	spRoleAssignmentManager = sproleassignmentmanager.NewSPRoleAssignmentManager(mpfArgs.SubscriptionID)
+
+	var deploymentAuthorizationCheckerCleaner usecase.DeploymentAuthorizationCheckerCleaner
+	var mpfService *usecase.MPFService
+
+	deploymentAuthorizationCheckerCleaner = usecase.NewStagedDeploymentAuthorizationChecker(
+		usecase.DeploymentAuthorizationCheckerStage{Name: "validate", Checker: ARMTemplateValidate.NewARMTemplateValidateAuthorizationChecker(mpfArgs.SubscriptionID, *armConfig)},
+		usecase.DeploymentAuthorizationCheckerStage{Name: "whatIf", Checker: ARMTemplateWhatIf.NewARMTemplateWhatIfAuthorizationChecker(mpfArgs.SubscriptionID, *armConfig)},
+	)
+	initialPermissionsToAdd := []string{"Microsoft.Resources/deployments/*", "Microsoft.Resources/subscriptions/operationresults/read"}
+	permissionsToAddToResult := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
+	mpfService = usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, deploymentAuthorizationCheckerCleaner, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, true, false, true)
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	if err != nil {
+		t.Error(err)
+	}
+
+	// The same 8 permissions as the What-If only run are expected, most of them found by the validate stage
+	assert.NotEmpty(t, mpfResult.RequiredPermissions)
+	assert.Equal(t, 8, len(mpfResult.RequiredPermissions[mpfConfig.ResourceGroup.ResourceGroupResourceID]))
+	assert.NotEmpty(t, mpfResult.RequiredPermissionsByStage["validate"])
+}
+
+func TestARMTemplatLinkedTemplateWithRelativePath(t *testing.T) {
+
+	mpfArgs, err := getTestingMPFArgs()
+	if err != nil {
+		t.Skip("required environment variables not set, skipping end to end test")
+	}
+	mpfArgs.TemplateFilePath = "../samples/templates/linked-template/main.json"
+	mpfArgs.ParametersFilePath = "../samples/templates/linked-template/main-parameters.json"
+
+	ctx := context.Background()
+
+	mpfConfig := getMPFConfig(mpfArgs)
+
+	deploymentName := fmt.Sprintf("%s-%s", mpfArgs.DeploymentNamePfx, mpfSharedUtils.GenerateRandomString(7))
+	armConfig := &ARMTemplateShared.ArmTemplateAdditionalConfig{
+		TemplateFilePath:   mpfArgs.TemplateFilePath,
+		ParametersFilePath: mpfArgs.ParametersFilePath,
+		DeploymentName:     deploymentName,
+	}
+
+	var rgManager usecase.ResourceGroupManager
+	var spRoleAssignmentManager usecase.ServicePrincipalRolemAssignmentManager
+	rgManager = resourceGroupManager.NewResourceGroupManager(mpfArgs.SubscriptionID)
+	spRoleAssignmentManager = sproleassignmentmanager.NewSPRoleAssignmentManager(mpfArgs.SubscriptionID)
+
+	var deploymentAuthorizationCheckerCleaner usecase.DeploymentAuthorizationCheckerCleaner
+	var mpfService *usecase.MPFService
+
+	deploymentAuthorizationCheckerCleaner = ARMTemplateWhatIf.NewARMTemplateWhatIfAuthorizationChecker(mpfArgs.SubscriptionID, *armConfig)
+	initialPermissionsToAdd := []string{"Microsoft.Resources/deployments/*", "Microsoft.Resources/subscriptions/operationresults/read"}
+	permissionsToAddToResult := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
+	mpfService = usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, deploymentAuthorizationCheckerCleaner, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, true, false, true)
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Microsoft.Network/virtualNetworks/read
+	// Microsoft.Network/virtualNetworks/write
+	// Microsoft.Resources/deployments/read
+	// Microsoft.Resources/deployments/write
+	assert.NotEmpty(t, mpfResult.RequiredPermissions)
+	assert.Equal(t, 4, len(mpfResult.RequiredPermissions[mpfConfig.ResourceGroup.ResourceGroupResourceID]))
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/ARMTemplateShared"
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/ARMTemplateWhatIf"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/bicepCompiler"
 	mpfSharedUtils "github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
 	resourceGroupManager "github.com/manisbindra/az-mpf/pkg/infrastructure/resourceGroupManager"
 	sproleassignmentmanager "github.com/manisbindra/az-mpf/pkg/infrastructure/spRoleAssignmentManager"
@@ -113,3 +114,33 @@ func TestBicepInvalidResourceFile(t *testing.T) {
 	// defer os.Remove(armTemplatePath)
 
 }
+
+func TestBicepInvalidBicepFileDiagnostics(t *testing.T) {
+
+	if checkBicepTestEnvVars() {
+		t.Skip("required environment variables not set, skipping end to end test")
+	}
+
+	bicepExecPath := os.Getenv("MPF_BICEPEXECPATH")
+	bicepFilePath := "../samples/bicep/invalid-bicep.bicep"
+
+	bicepFilePath, err := getAbsolutePath(bicepFilePath)
+	if err != nil {
+		t.Error(err)
+	}
+
+	compiler, err := bicepCompiler.NewBicepCompiler(bicepExecPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer compiler.Cleanup()
+
+	_, err = compiler.Build(bicepFilePath)
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, bicepCompiler.ErrBicepCompilation))
+
+	var compilationErr *bicepCompiler.BicepCompilationError
+	if assert.True(t, errors.As(err, &compilationErr)) {
+		assert.NotEmpty(t, compilationErr.Diagnostics)
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/ARMTemplateShared"
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/ARMTemplateWhatIf"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/bicepCompiler"
 	mpfSharedUtils "github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
 	resourceGroupManager "github.com/manisbindra/az-mpf/pkg/infrastructure/resourceGroupManager"
 	sproleassignmentmanager "github.com/manisbindra/az-mpf/pkg/infrastructure/spRoleAssignmentManager"
@@ -110,3 +111,73 @@ func getAbsolutePath(path string) (string, error) {
 	}
 	return absPath, nil
 }
+
+func TestBicepAksBicepparam(t *testing.T) {
+
+	mpfArgs, err := getTestingMPFArgs()
+	if err != nil {
+		t.Skip("required environment variables not set, skipping end to end test")
+	}
+
+	if checkBicepTestEnvVars() {
+		t.Skip("required environment variables not set, skipping end to end test")
+	}
+
+	bicepExecPath := os.Getenv("MPF_BICEPEXECPATH")
+	parametersFilePath := "../samples/bicep/aks-private-subnet.bicepparam"
+
+	parametersFilePath, _ = getAbsolutePath(parametersFilePath)
+
+	bicepFilePath, err := bicepCompiler.GetBicepFileFromBicepparam(parametersFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compiler, err := bicepCompiler.NewBicepCompiler(bicepExecPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer compiler.Cleanup()
+
+	armTemplatePath, err := compiler.Build(bicepFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	armParametersPath, err := compiler.BuildParams(parametersFilePath, bicepFilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	mpfConfig := getMPFConfig(mpfArgs)
+
+	deploymentName := fmt.Sprintf("%s-%s", mpfArgs.DeploymentNamePfx, mpfSharedUtils.GenerateRandomString(7))
+	armConfig := &ARMTemplateShared.ArmTemplateAdditionalConfig{
+		TemplateFilePath:   armTemplatePath,
+		ParametersFilePath: armParametersPath,
+		DeploymentName:     deploymentName,
+	}
+
+	var rgManager usecase.ResourceGroupManager
+	var spRoleAssignmentManager usecase.ServicePrincipalRolemAssignmentManager
+	rgManager = resourceGroupManager.NewResourceGroupManager(mpfArgs.SubscriptionID)
+	spRoleAssignmentManager = sproleassignmentmanager.NewSPRoleAssignmentManager(mpfArgs.SubscriptionID)
+
+	var deploymentAuthorizationCheckerCleaner usecase.DeploymentAuthorizationCheckerCleaner
+	var mpfService *usecase.MPFService
+
+	deploymentAuthorizationCheckerCleaner = ARMTemplateWhatIf.NewARMTemplateWhatIfAuthorizationChecker(mpfArgs.SubscriptionID, *armConfig)
+	initialPermissionsToAdd := []string{"Microsoft.Resources/deployments/*", "Microsoft.Resources/subscriptions/operationresults/read"}
+	permissionsToAddToResult := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
+	mpfService = usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, deploymentAuthorizationCheckerCleaner, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, true, false, true)
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	if err != nil {
+		t.Error(err)
+	}
+
+	assert.NotEmpty(t, mpfResult.RequiredPermissions)
+	assert.Equal(t, 8, len(mpfResult.RequiredPermissions[mpfConfig.ResourceGroup.ResourceGroupResourceID]))
+}
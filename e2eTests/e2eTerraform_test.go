@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/authorizationCheckers/terraform"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
 	resourceGroupManager "github.com/manisbindra/az-mpf/pkg/infrastructure/resourceGroupManager"
 	sproleassignmentmanager "github.com/manisbindra/az-mpf/pkg/infrastructure/spRoleAssignmentManager"
 	"github.com/manisbindra/az-mpf/pkg/usecase"
@@ -15,6 +16,15 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func getTerraformRunDir(t *testing.T, wrkDir string) *mpfSharedUtils.RunDir {
+	runDir, err := mpfSharedUtils.NewRunDir("terraform", wrkDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(runDir.Release)
+	return runDir
+}
+
 func TestTerraformACI(t *testing.T) {
 
 	mpfArgs, err := getTestingMPFArgs()
@@ -52,7 +62,7 @@ func TestTerraformACI(t *testing.T) {
 
 	initialPermissionsToAdd := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
 	permissionsToAddToResult := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
-	deploymentAuthorizationCheckerCleaner = terraform.NewTerraformAuthorizationChecker(wrkDir, tfpath, varsFile, true, "")
+	deploymentAuthorizationCheckerCleaner = terraform.NewTerraformAuthorizationChecker(wrkDir, getTerraformRunDir(t, wrkDir), tfpath, varsFile, true, "")
 	mpfService = usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, deploymentAuthorizationCheckerCleaner, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, false, true, false)
 
 	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
@@ -98,7 +108,7 @@ func TestTerraformACINoTfvarsFile(t *testing.T) {
 
 	initialPermissionsToAdd := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
 	permissionsToAddToResult := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
-	deploymentAuthorizationCheckerCleaner = terraform.NewTerraformAuthorizationChecker(wrkDir, tfpath, "", true, "")
+	deploymentAuthorizationCheckerCleaner = terraform.NewTerraformAuthorizationChecker(wrkDir, getTerraformRunDir(t, wrkDir), tfpath, "", true, "")
 	mpfService = usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, deploymentAuthorizationCheckerCleaner, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, false, true, false)
 
 	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
@@ -144,7 +154,7 @@ func TestTerraformModuleTest(t *testing.T) {
 
 	initialPermissionsToAdd := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
 	permissionsToAddToResult := []string{"Microsoft.Resources/deployments/read", "Microsoft.Resources/deployments/write"}
-	deploymentAuthorizationCheckerCleaner = terraform.NewTerraformAuthorizationChecker(wrkDir, tfpath, "", true, "")
+	deploymentAuthorizationCheckerCleaner = terraform.NewTerraformAuthorizationChecker(wrkDir, getTerraformRunDir(t, wrkDir), tfpath, "", true, "")
 	mpfService = usecase.NewMPFService(ctx, rgManager, spRoleAssignmentManager, deploymentAuthorizationCheckerCleaner, mpfConfig, initialPermissionsToAdd, permissionsToAddToResult, false, true, false)
 
 	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
@@ -10,34 +10,44 @@ import (
 )
 
 func GetScopePermissionsFromAuthError(authErrMesg string) (map[string][]string, error) {
-	if authErrMesg != "" && !strings.Contains(authErrMesg, "AuthorizationFailed") && !strings.Contains(authErrMesg, "Authorization failed") {
+	authErrMesg = normalizeAuthorizationErrorMessage(authErrMesg)
+
+	if authErrMesg != "" && !IsAzureAuthorizationError(authErrMesg) {
 		log.Infoln("Non Authorization Error when creating deployment:", authErrMesg)
 		return nil, errors.New("Could not parse deploment error, potentially due to a Non-Authorization error")
 	}
 
-	var resMap map[string][]string
+	// Messages harvested from several deployment operations can mix the different authorization error formats,
+	// so every matching parser is run and the results merged. Individual parser errors are only returned if nothing was found.
+	resMap := make(map[string][]string)
 	var err error
 
-	switch {
-	case strings.Count(authErrMesg, "LinkedAuthorizationFailed") >= 1:
-		resMap, err = parseLinkedAuthorizationFailedErrors(authErrMesg)
-	case strings.Count(authErrMesg, "AuthorizationFailed") >= 1:
-		resMap, err = parseMultiAuthorizationFailedErrors(authErrMesg)
-	case strings.Count(authErrMesg, "Authorization failed") >= 1:
-		resMap, err = parseMultiAuthorizationErrors(authErrMesg)
+	parsers := []struct {
+		marker string
+		parse  func(string) (map[string][]string, error)
+	}{
+		{"LinkedAuthorizationFailed", parseLinkedAuthorizationFailedErrors},
+		{"AuthorizationFailed", parseMultiAuthorizationFailedErrors},
+		{"Authorization failed", parseMultiAuthorizationErrors},
 	}
 
-	// if strings.Count(authErrMesg, "AuthorizationFailed") >= 1 {
-	// 	resMap, err = parseMultiAuthorizationFailedErrors(authErrMesg)
-
-	// }
-
-	// // If count of "Authorization failed" in error message is 1 or more than 1, then it is a multi authorization error
-	// if strings.Count(authErrMesg, "Authorization failed") >= 1 {
-	// 	resMap, err = parseMultiAuthorizationErrors(authErrMesg)
-	// }
+	for _, p := range parsers {
+		if !strings.Contains(authErrMesg, p.marker) {
+			continue
+		}
+		scpMp, parseErr := p.parse(authErrMesg)
+		if parseErr != nil {
+			if err == nil {
+				err = parseErr
+			}
+			continue
+		}
+		for scope, permissions := range scpMp {
+			resMap[scope] = append(resMap[scope], permissions...)
+		}
+	}
 
-	if err != nil {
+	if len(resMap) == 0 && err != nil {
 		return nil, err
 	}
 
@@ -60,6 +70,19 @@ func GetScopePermissionsFromAuthError(authErrMesg string) (map[string][]string,
 	return resMap, nil
 }
 
+// IsAzureAuthorizationError returns true if the error message contains an Azure Resource Manager authorization error
+func IsAzureAuthorizationError(authErrMesg string) bool {
+	return strings.Contains(authErrMesg, "AuthorizationFailed") || strings.Contains(authErrMesg, "Authorization failed")
+}
+
+// The response bodies of the Azure SDK errors, such as the 'RESPONSE 403' errors of the azapi provider, can contain the
+// messages with JSON escaped quotes, which are unescaped so that the messages can be parsed
+var authorizationErrorMessageReplacer = strings.NewReplacer(`\u0027`, "'", `\"`, `"`)
+
+func normalizeAuthorizationErrorMessage(authErrMesg string) string {
+	return authorizationErrorMessageReplacer.Replace(authErrMesg)
+}
+
 // For 'AuthorizationFailed' errors
 func parseMultiAuthorizationFailedErrors(authorizationFailedErrMsg string) (map[string][]string, error) {
 
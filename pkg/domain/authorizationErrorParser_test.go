@@ -76,3 +76,49 @@ func TestMultiAuthorizationSpaceFailedErrors(t *testing.T) {
 	assert.Equal(t, "Microsoft.KeyVault/vaults/write", lastMatch[0])
 
 }
+
+func TestMixedLinkedAndAuthorizationFailedErrors(t *testing.T) {
+	mixedErrors := "{\"status\":\"Failed\",\"error\":{\"code\":\"AuthorizationFailed\",\"message\":\"The client 'XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX' with object id 'XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX' does not have authorization to perform action 'Microsoft.Network/virtualNetworks/write' over scope '/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Network/virtualNetworks/vnet1' or the scope is invalid. If access was recently granted, please refresh your credentials.\"}}\n" +
+		"{\"status\":\"Failed\",\"error\":{\"code\":\"LinkedAuthorizationFailed\",\"message\":\"The client 'XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX' with object id 'XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX' has permission to perform action 'Microsoft.ContainerService/managedClusters/write' on scope '/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.ContainerService/managedClusters/aks1'; however, it does not have permission to perform action(s) 'Microsoft.Network/virtualNetworks/subnets/join/action' on the linked scope(s) '/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Network/virtualNetworks/vnet1/subnets/subnet1' (respectively) or the linked scope(s) are invalid.\"}}"
+	spm, err := GetScopePermissionsFromAuthError(mixedErrors)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(spm))
+
+	vnetMatch := spm["/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Network/virtualNetworks/vnet1"]
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/write"}, vnetMatch)
+
+	subnetMatch := spm["/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Network/virtualNetworks/vnet1/subnets/subnet1"]
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/subnets/join/action"}, subnetMatch)
+}
+
+func TestAzapiResponseAuthorizationFailedError(t *testing.T) {
+	azapiError := `Error: Failed to create/update resource
+
+creating/updating Resource: (ResourceId "/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.App/managedEnvironments/env1" / Api Version "2024-03-01"): PUT https://management.azure.com/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.App/managedEnvironments/env1
+--------------------------------------------------------------------------------
+RESPONSE 403: 403 Forbidden
+ERROR CODE: AuthorizationFailed
+--------------------------------------------------------------------------------
+{
+  "error": {
+    "code": "AuthorizationFailed",
+    "message": "The client 'XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX' with object id 'XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX' does not have authorization to perform action 'Microsoft.App/managedEnvironments/write' over scope '/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.App/managedEnvironments/env1' or the scope is invalid. If access was recently granted, please refresh your credentials."
+  }
+}
+--------------------------------------------------------------------------------
+
+  with azapi_resource.env,
+`
+	spm, err := GetScopePermissionsFromAuthError(azapiError)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.App/managedEnvironments/write"}, spm["/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.App/managedEnvironments/env1"])
+}
+
+func TestEscapedAuthorizationFailedError(t *testing.T) {
+	escapedError := `RESPONSE 403: 403 Forbidden
+ERROR CODE: AuthorizationFailed
+{"error":{"code":"AuthorizationFailed","message":"The client \u0027XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX\u0027 with object id \u0027XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX\u0027 does not have authorization to perform action \u0027Microsoft.Storage/storageAccounts/listKeys/action\u0027 over scope \u0027/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Storage/storageAccounts/sa1\u0027 or the scope is invalid. If access was recently granted, please refresh your credentials."}}`
+	spm, err := GetScopePermissionsFromAuthError(escapedError)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.Storage/storageAccounts/listKeys/action"}, spm["/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Storage/storageAccounts/sa1"])
+}
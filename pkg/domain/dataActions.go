@@ -0,0 +1,35 @@
+package domain
+
+import "strings"
+
+// The resource types whose operations are data actions, which are granted in the dataActions of a role instead of its actions
+var dataActionResourceTypes = []string{
+	"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/",
+	"Microsoft.Storage/storageAccounts/fileServices/fileshares/files/",
+	"Microsoft.Storage/storageAccounts/queueServices/queues/messages/",
+	"Microsoft.Storage/storageAccounts/tableServices/tables/entities/",
+}
+
+// IsDataAction returns true if the permission is a data action, such as reading or writing a storage blob
+func IsDataAction(permission string) bool {
+	for _, resourceType := range dataActionResourceTypes {
+		if strings.HasPrefix(strings.ToLower(permission), strings.ToLower(resourceType)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitActionsAndDataActions splits the permissions into the actions and data actions of a role
+func SplitActionsAndDataActions(permissions []string) ([]string, []string) {
+	actions := []string{}
+	dataActions := []string{}
+	for _, permission := range permissions {
+		if IsDataAction(permission) {
+			dataActions = append(dataActions, permission)
+			continue
+		}
+		actions = append(actions, permission)
+	}
+	return actions, dataActions
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitActionsAndDataActions(t *testing.T) {
+	permissions := []string{
+		"Microsoft.Storage/storageAccounts/read",
+		"Microsoft.Storage/storageAccounts/blobServices/containers/read",
+		"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read",
+		"microsoft.storage/storageaccounts/blobservices/containers/blobs/write",
+	}
+
+	actions, dataActions := SplitActionsAndDataActions(permissions)
+	assert.Equal(t, []string{"Microsoft.Storage/storageAccounts/read", "Microsoft.Storage/storageAccounts/blobServices/containers/read"}, actions)
+	assert.Equal(t, []string{"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read", "microsoft.storage/storageaccounts/blobservices/containers/blobs/write"}, dataActions)
+}
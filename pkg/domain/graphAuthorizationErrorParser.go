@@ -0,0 +1,128 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The error code of Microsoft Graph when the identity has no permission for the request
+const GraphAuthorizationRequestDeniedErr = "Authorization_RequestDenied"
+
+var (
+	graphResourceAddressRegex = regexp.MustCompile(`with (data\.)?(azuread_[a-z_]+)\.`)
+	graphResourceTypeRegex    = regexp.MustCompile(`Microsoft\.Graph/([A-Za-z0-9]+)`)
+)
+
+// The Microsoft Graph application permissions required to read and to manage the resources of each azuread resource type,
+// by resource type prefix. Microsoft Graph errors do not name the permission which is missing
+var graphResourceTypePermissions = []struct {
+	resourceTypePrefix string
+	readPermission     string
+	writePermission    string
+}{
+	{"azuread_application", "Application.Read.All", "Application.ReadWrite.All"},
+	{"azuread_service_principal_delegated_permission_grant", "DelegatedPermissionGrant.ReadWrite.All", "DelegatedPermissionGrant.ReadWrite.All"},
+	{"azuread_service_principal", "Application.Read.All", "Application.ReadWrite.All"},
+	{"azuread_app_role_assignment", "AppRoleAssignment.ReadWrite.All", "AppRoleAssignment.ReadWrite.All"},
+	{"azuread_group", "Group.Read.All", "Group.ReadWrite.All"},
+	{"azuread_user", "User.Read.All", "User.ReadWrite.All"},
+	{"azuread_invitation", "User.Invite.All", "User.Invite.All"},
+	{"azuread_directory_role", "RoleManagement.Read.Directory", "RoleManagement.ReadWrite.Directory"},
+	{"azuread_administrative_unit", "AdministrativeUnit.Read.All", "AdministrativeUnit.ReadWrite.All"},
+	{"azuread_conditional_access_policy", "Policy.Read.All", "Policy.ReadWrite.ConditionalAccess"},
+	{"azuread_named_location", "Policy.Read.All", "Policy.ReadWrite.ConditionalAccess"},
+	{"azuread_domains", "Domain.Read.All", "Domain.Read.All"},
+}
+
+// The Microsoft Graph application permissions required to manage the resources of the Microsoft Graph Bicep extension, by resource type
+var graphExtensionResourceTypePermissions = map[string]string{
+	"applications":                 "Application.ReadWrite.All",
+	"servicePrincipals":            "Application.ReadWrite.All",
+	"federatedIdentityCredentials": "Application.ReadWrite.All",
+	"groups":                       "Group.ReadWrite.All",
+	"oauth2PermissionGrants":       "DelegatedPermissionGrant.ReadWrite.All",
+	"appRoleAssignedTo":            "AppRoleAssignment.ReadWrite.All",
+	"users":                        "User.Read.All",
+}
+
+// IsGraphAuthorizationError returns true if the error message contains a Microsoft Graph authorization error
+func IsGraphAuthorizationError(authErrMesg string) bool {
+	return strings.Contains(authErrMesg, GraphAuthorizationRequestDeniedErr)
+}
+
+// GetGraphPermissionsFromAuthError returns the Microsoft Graph permissions required by the Microsoft Graph authorization errors
+// of the azuread provider, from the resource addresses of the errors, and of the Microsoft Graph Bicep extension, from the
+// resource types of the errors. Data sources require the read permission of the resource type
+func GetGraphPermissionsFromAuthError(authErrMesg string) ([]string, error) {
+	if !IsGraphAuthorizationError(authErrMesg) {
+		return nil, errors.New("No Microsoft Graph authorization error found")
+	}
+
+	permissions := make(map[string]bool)
+	for _, errMsg := range strings.Split(authErrMesg, "Error: ") {
+		if !IsGraphAuthorizationError(errMsg) {
+			continue
+		}
+
+		errPermissions, err := getGraphPermissionsOfError(errMsg)
+		if err != nil {
+			return nil, err
+		}
+		for _, permission := range errPermissions {
+			permissions[permission] = true
+		}
+	}
+
+	if len(permissions) == 0 {
+		return nil, errors.New("No Microsoft Graph permissions found in authorization error")
+	}
+
+	graphPermissions := make([]string, 0, len(permissions))
+	for permission := range permissions {
+		graphPermissions = append(graphPermissions, permission)
+	}
+	sort.Strings(graphPermissions)
+	return graphPermissions, nil
+}
+
+// getGraphPermissionsOfError returns the Microsoft Graph permissions of a single error, from the address of the azuread
+// resource, or from the Microsoft Graph resource types of the Bicep extension for deployment errors
+func getGraphPermissionsOfError(errMsg string) ([]string, error) {
+	if match := graphResourceAddressRegex.FindStringSubmatch(errMsg); match != nil {
+		permission, err := getGraphPermission(match[2], match[1] != "")
+		if err != nil {
+			return nil, err
+		}
+		return []string{permission}, nil
+	}
+
+	var permissions []string
+	for _, match := range graphResourceTypeRegex.FindAllStringSubmatch(errMsg, -1) {
+		permission, ok := graphExtensionResourceTypePermissions[match[1]]
+		if !ok {
+			return nil, fmt.Errorf("No Microsoft Graph permission known for resource type %s", match[0])
+		}
+		permissions = append(permissions, permission)
+	}
+
+	if len(permissions) == 0 {
+		return nil, fmt.Errorf("Could not find the resource of Microsoft Graph authorization error: %s", errMsg)
+	}
+	return permissions, nil
+}
+
+func getGraphPermission(resourceType string, dataSource bool) (string, error) {
+	for _, p := range graphResourceTypePermissions {
+		if !strings.HasPrefix(resourceType, p.resourceTypePrefix) {
+			continue
+		}
+		if dataSource {
+			return p.readPermission, nil
+		}
+		return p.writePermission, nil
+	}
+	return "", fmt.Errorf("No Microsoft Graph permission known for azuread resource type %s", resourceType)
+}
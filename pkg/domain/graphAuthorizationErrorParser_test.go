@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetGraphPermissionsFromAuthError(t *testing.T) {
+	graphError := "Error: Could not create application\n\nApplicationsClient.BaseClient.Post(): unexpected status 403 with OData error: Authorization_RequestDenied: Insufficient privileges to complete the operation.\n\n  with azuread_application.example,\n\n" +
+		"Error: Retrieving group\n\nunexpected status 403 (403 Forbidden) with error: Authorization_RequestDenied: Insufficient privileges to complete the operation.\n\n  with data.azuread_group.admins,\n\n"
+
+	assert.True(t, IsGraphAuthorizationError(graphError))
+
+	permissions, err := GetGraphPermissionsFromAuthError(graphError)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Application.ReadWrite.All", "Group.Read.All"}, permissions)
+}
+
+func TestGetGraphPermissionsFromBicepExtensionAuthError(t *testing.T) {
+	graphError := `{"status":"Failed","error":{"code":"DeploymentFailed","details":[{"code":"Forbidden","message":"{\"error\":{\"code\":\"Authorization_RequestDenied\",\"message\":\"Insufficient privileges to complete the operation.\",\"target\":\"Microsoft.Graph/applications@v1.0\"}}"}]}}`
+
+	permissions, err := GetGraphPermissionsFromAuthError(graphError)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Application.ReadWrite.All"}, permissions)
+
+	_, err = GetGraphPermissionsFromAuthError("Authorization_RequestDenied: Microsoft.Graph/unknownResources@v1.0")
+	assert.NotNil(t, err)
+}
+
+func TestGetGraphPermissionsFromAuthErrorWithoutResource(t *testing.T) {
+	_, err := GetGraphPermissionsFromAuthError("Error: Authorization_RequestDenied: Insufficient privileges to complete the operation.")
+	assert.NotNil(t, err)
+
+	_, err = GetGraphPermissionsFromAuthError("Error: Authorization_RequestDenied\n\n  with azuread_unknown_resource.example,\n\n")
+	assert.NotNil(t, err)
+
+	_, err = GetGraphPermissionsFromAuthError("AuthorizationFailed")
+	assert.NotNil(t, err)
+}
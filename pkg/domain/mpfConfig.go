@@ -30,6 +30,28 @@ type MPFConfig struct {
 type MPFResult struct {
 	// The map from which the minimum permissions will be calculated
 	RequiredPermissions map[string][]string
+	// The permissions found by each stage, for checkers that discover permissions in multiple stages
+	RequiredPermissionsByStage map[string][]string `json:",omitempty"`
+	// The permissions of a role which can run each stage, including the permissions found in the stages before it
+	RolePermissionsByStage map[string][]string `json:",omitempty"`
+	// The predicted permissions which were confirmed to be required, and which were pruned as not required
+	ConfirmedPredictedPermissions []string `json:",omitempty"`
+	PrunedPredictedPermissions    []string `json:",omitempty"`
+	// The permissions of the role marked as required or unnecessary by the ablation pass
+	VerifiedRequiredPermissions []string `json:",omitempty"`
+	UnnecessaryPermissions      []string `json:",omitempty"`
+	// The result of rerunning the checker with a fresh role containing exactly the required permissions
+	SufficiencyVerification *SufficiencyVerification `json:",omitempty"`
+	// The Microsoft Graph permissions required, which can not be granted by an Azure role
+	GraphPermissions []string `json:",omitempty"`
+}
+
+type SufficiencyVerification struct {
+	Passed bool
+	// The initial permissions MPF grants for the checks, which are in the verification role but not in the required permissions
+	InitialPermissions []string `json:",omitempty"`
+	// The authorization or other error received, when the verification failed
+	Details string `json:",omitempty"`
 }
 
 func GetMPFResult(requiredPermissions map[string][]string) MPFResult {
@@ -37,3 +59,11 @@ func GetMPFResult(requiredPermissions map[string][]string) MPFResult {
 		RequiredPermissions: getMapWithUniqueValues(requiredPermissions),
 	}
 }
+
+func GetMPFResultWithStages(requiredPermissions map[string][]string, requiredPermissionsByStage map[string][]string) MPFResult {
+	mpfResult := GetMPFResult(requiredPermissions)
+	if len(requiredPermissionsByStage) > 0 {
+		mpfResult.RequiredPermissionsByStage = getMapWithUniqueValues(requiredPermissionsByStage)
+	}
+	return mpfResult
+}
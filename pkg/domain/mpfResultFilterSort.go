@@ -1,6 +1,9 @@
 package domain
 
-import "sort"
+import (
+	"sort"
+	"strings"
+)
 
 func getMapWithUniqueValues(m map[string][]string) map[string][]string {
 	sm := make(map[string][]string)
@@ -23,3 +26,46 @@ func getUniqueSlice(s []string) []string {
 	}
 	return uniqueSlice
 }
+
+// GetUniqueSortedPermissions returns the permissions without duplicates, sorted
+func GetUniqueSortedPermissions(permissions []string) []string {
+	uniquePermissions := getUniqueSlice(permissions)
+	sort.Strings(uniquePermissions)
+	return uniquePermissions
+}
+
+// SplitPredictedPermissions splits the predicted permissions into the ones which are part of the required permissions, and the ones which are not
+func SplitPredictedPermissions(predictedPermissions []string, requiredPermissions []string) ([]string, []string) {
+	required := make(map[string]bool)
+	for _, permission := range requiredPermissions {
+		required[strings.ToLower(permission)] = true
+	}
+
+	var confirmed, pruned []string
+	for _, permission := range getUniqueSlice(predictedPermissions) {
+		if required[strings.ToLower(permission)] {
+			confirmed = append(confirmed, permission)
+		} else {
+			pruned = append(pruned, permission)
+		}
+	}
+	sort.Strings(confirmed)
+	sort.Strings(pruned)
+	return confirmed, pruned
+}
+
+// GetRolePermissionsByStage returns for each stage in which permissions were found, the permissions of a role which can run the stage,
+// that is the base permissions and the permissions found in the stage and in the stages before it
+func GetRolePermissionsByStage(stages []string, requiredPermissionsByStage map[string][]string, basePermissions []string) map[string][]string {
+	rolePermissionsByStage := make(map[string][]string)
+	rolePermissions := append([]string{}, basePermissions...)
+	for _, stage := range stages {
+		permissions, ok := requiredPermissionsByStage[stage]
+		if !ok {
+			continue
+		}
+		rolePermissions = append(rolePermissions, permissions...)
+		rolePermissionsByStage[stage] = append([]string{}, rolePermissions...)
+	}
+	return getMapWithUniqueValues(rolePermissionsByStage)
+}
@@ -97,3 +97,51 @@ func TestGetMapWithUniqueValues(t *testing.T) {
 	}
 
 }
+
+func TestSplitPredictedPermissions(t *testing.T) {
+	predictedPermissions := []string{
+		"Microsoft.Network/virtualNetworks/write",
+		"Microsoft.Network/virtualNetworks/read",
+		"Microsoft.Network/virtualNetworks/subnets/join/action",
+		"Microsoft.Network/virtualNetworks/write",
+	}
+	requiredPermissions := []string{
+		"Microsoft.Network/virtualnetworks/write",
+		"Microsoft.Network/virtualNetworks/read",
+		"Microsoft.ContainerService/managedClusters/write",
+	}
+
+	confirmed, pruned := SplitPredictedPermissions(predictedPermissions, requiredPermissions)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/read", "Microsoft.Network/virtualNetworks/write"}, confirmed)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/subnets/join/action"}, pruned)
+}
+
+func TestGetUniqueSortedPermissions(t *testing.T) {
+	permissions := []string{"User.Read.All", "Application.ReadWrite.All", "User.Read.All"}
+	assert.Equal(t, []string{"Application.ReadWrite.All", "User.Read.All"}, GetUniqueSortedPermissions(permissions))
+	assert.Empty(t, GetUniqueSortedPermissions(nil))
+}
+
+func TestGetRolePermissionsByStage(t *testing.T) {
+	requiredPermissionsByStage := map[string][]string{
+		"destroy": {"Microsoft.Network/virtualNetworks/delete"},
+		"apply":   {"Microsoft.Network/virtualNetworks/write", "Microsoft.Network/virtualNetworks/read"},
+		"other":   {"Microsoft.Storage/storageAccounts/write"},
+	}
+
+	rolePermissionsByStage := GetRolePermissionsByStage([]string{"init", "plan", "apply", "destroy"}, requiredPermissionsByStage, []string{"Microsoft.Resources/deployments/read"})
+
+	assert.Equal(t, map[string][]string{
+		"apply": {
+			"Microsoft.Network/virtualNetworks/read",
+			"Microsoft.Network/virtualNetworks/write",
+			"Microsoft.Resources/deployments/read",
+		},
+		"destroy": {
+			"Microsoft.Network/virtualNetworks/delete",
+			"Microsoft.Network/virtualNetworks/read",
+			"Microsoft.Network/virtualNetworks/write",
+			"Microsoft.Resources/deployments/read",
+		},
+	}, rolePermissionsByStage)
+}
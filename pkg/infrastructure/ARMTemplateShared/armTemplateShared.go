@@ -1,13 +1,57 @@
 package ARMTemplateShared
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
+)
 
 var ErrInvalidTemplate = errors.New("InvalidTemplate")
 
+const (
+	subscriptionDeploymentTemplateSchema    = "subscriptionDeploymentTemplate.json"
+	managementGroupDeploymentTemplateSchema = "managementGroupDeploymentTemplate.json"
+	tenantDeploymentTemplateSchema          = "tenantDeploymentTemplate.json"
+)
+
 type ArmTemplateAdditionalConfig struct {
 	TemplateFilePath   string
 	ParametersFilePath string
 	DeploymentName     string
+	// SubscriptionScope deploys the template to the subscription instead of the resource group, for templates
+	// with a subscription deployment schema, such as Bicep files with targetScope = 'subscription'
+	SubscriptionScope bool
+	// Location of the deployment metadata, required by deployments at subscription scope
+	Location string
+}
+
+// GetDeploymentResourceID returns the resource ID of the deployment, at subscription scope or in the resource group
+func GetDeploymentResourceID(armConfig ArmTemplateAdditionalConfig, subscriptionID string, resourceGroupName string, deploymentName string) string {
+	if armConfig.SubscriptionScope {
+		return fmt.Sprintf("/subscriptions/%s/providers/Microsoft.Resources/deployments/%s", subscriptionID, deploymentName)
+	}
+	return fmt.Sprintf("/subscriptions/%s/resourcegroups/%s/providers/Microsoft.Resources/deployments/%s", subscriptionID, resourceGroupName, deploymentName)
+}
+
+// IsSubscriptionDeploymentTemplate returns whether the $schema of the template is the subscription deployment schema.
+// Templates for management group and tenant deployments are not supported
+func IsSubscriptionDeploymentTemplate(templateFilePath string) (bool, error) {
+	template, err := mpfSharedUtils.ReadJson(templateFilePath)
+	if err != nil {
+		return false, err
+	}
+
+	schema, _ := template["$schema"].(string)
+	switch {
+	case strings.Contains(schema, subscriptionDeploymentTemplateSchema):
+		return true, nil
+	case strings.Contains(schema, managementGroupDeploymentTemplateSchema), strings.Contains(schema, tenantDeploymentTemplateSchema):
+		return false, fmt.Errorf("deployments of template %s with schema %s are not supported, only resource group and subscription deployments are", templateFilePath, schema)
+	}
+	return false, nil
 }
 
 // Get parameters in standard format that is without the schema, contentVersion and parameters fields
@@ -42,9 +86,52 @@ func GetParametersInStandardFormat(parameters map[string]interface{}) map[string
 	// 	  }
 	// 	}
 	if parameters["$schema"] != nil {
+		// a parameters file with a schema, but without parameters, has no parameters
+		standardParameters, ok := parameters["parameters"].(map[string]interface{})
+		if !ok {
+			return map[string]interface{}{}
+		}
+		return standardParameters
+	}
+	return parameters
+}
 
-		return parameters["parameters"].(map[string]interface{})
+// GetDeploymentRequestBody returns the incremental deployment request body, containing the template and parameters, as a JSON string.
+// The request body of a deployment at subscription scope also contains its location
+func GetDeploymentRequestBody(armConfig ArmTemplateAdditionalConfig) (string, error) {
+	// read template and parameters
+	template, err := LoadTemplate(armConfig.TemplateFilePath)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidTemplate, fmt.Errorf("error reading template file: %w", err))
+	}
 
+	parameters := map[string]interface{}{}
+	if armConfig.ParametersFilePath != "" {
+		parameters, err = mpfSharedUtils.ReadJson(armConfig.ParametersFilePath)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrInvalidTemplate, fmt.Errorf("error reading parameters file: %w", err))
+		}
 	}
-	return parameters
+
+	// convert parameters to standard format
+	parameters = GetParametersInStandardFormat(parameters)
+
+	fullTemplate := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"mode":       "Incremental",
+			"template":   template,
+			"parameters": parameters,
+		},
+	}
+	if armConfig.SubscriptionScope {
+		fullTemplate["location"] = armConfig.Location
+	}
+
+	// convert bodyJSON to string
+	fullTemplateJSONBytes, err := json.Marshal(fullTemplate)
+	if err != nil {
+		return "", fmt.Errorf("%w, %w", ErrInvalidTemplate, fmt.Errorf("error marshalling fullTemplateJSON: %w", err))
+	}
+
+	return string(fullTemplateJSONBytes), nil
 }
@@ -1,6 +1,8 @@
 package ARMTemplateShared
 
 import (
+	"encoding/json"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -67,3 +69,62 @@ func TestGetParametersInStandardFormatWithoutSchema(t *testing.T) {
 	result := GetParametersInStandardFormat(parameters)
 	assert.Equal(t, expected, result)
 }
+
+func TestGetParametersInStandardFormatWithSchemaWithoutParameters(t *testing.T) {
+	parameters := map[string]interface{}{
+		"$schema":        "https://schema.management.azure.com/schemas/2019-04-01/deploymentParameters.json#",
+		"contentVersion": "1.0.0.0",
+	}
+
+	result := GetParametersInStandardFormat(parameters)
+	assert.Equal(t, map[string]interface{}{}, result)
+}
+
+func TestGetDeploymentResourceID(t *testing.T) {
+	armConfig := ArmTemplateAdditionalConfig{}
+	assert.Equal(t, "/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourcegroups/rg-mpf/providers/Microsoft.Resources/deployments/deploy-mpf", GetDeploymentResourceID(armConfig, "SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS", "rg-mpf", "deploy-mpf"))
+
+	armConfig.SubscriptionScope = true
+	assert.Equal(t, "/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/providers/Microsoft.Resources/deployments/deploy-mpf", GetDeploymentResourceID(armConfig, "SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS", "rg-mpf", "deploy-mpf"))
+}
+
+func TestIsSubscriptionDeploymentTemplate(t *testing.T) {
+	dir := t.TempDir()
+
+	templateFilePath := filepath.Join(dir, "resourceGroup.json")
+	writeTestTemplate(t, templateFilePath, `{"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#", "resources": []}`)
+	isSubscriptionScope, err := IsSubscriptionDeploymentTemplate(templateFilePath)
+	assert.Nil(t, err)
+	assert.False(t, isSubscriptionScope)
+
+	templateFilePath = filepath.Join(dir, "subscription.json")
+	writeTestTemplate(t, templateFilePath, `{"$schema": "https://schema.management.azure.com/schemas/2018-05-01/subscriptionDeploymentTemplate.json#", "resources": []}`)
+	isSubscriptionScope, err = IsSubscriptionDeploymentTemplate(templateFilePath)
+	assert.Nil(t, err)
+	assert.True(t, isSubscriptionScope)
+
+	templateFilePath = filepath.Join(dir, "managementGroup.json")
+	writeTestTemplate(t, templateFilePath, `{"$schema": "https://schema.management.azure.com/schemas/2019-08-01/managementGroupDeploymentTemplate.json#", "resources": []}`)
+	_, err = IsSubscriptionDeploymentTemplate(templateFilePath)
+	assert.NotNil(t, err)
+}
+
+func TestGetDeploymentRequestBodyAtSubscriptionScope(t *testing.T) {
+	templateFilePath := filepath.Join(t.TempDir(), "main.json")
+	writeTestTemplate(t, templateFilePath, `{"$schema": "https://schema.management.azure.com/schemas/2018-05-01/subscriptionDeploymentTemplate.json#", "resources": []}`)
+
+	armConfig := ArmTemplateAdditionalConfig{TemplateFilePath: templateFilePath}
+	body, err := GetDeploymentRequestBody(armConfig)
+	assert.Nil(t, err)
+	var request map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(body), &request))
+	assert.NotContains(t, request, "location")
+
+	armConfig.SubscriptionScope = true
+	armConfig.Location = "eastus2"
+	body, err = GetDeploymentRequestBody(armConfig)
+	assert.Nil(t, err)
+	request = map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal([]byte(body), &request))
+	assert.Equal(t, "eastus2", request["location"])
+}
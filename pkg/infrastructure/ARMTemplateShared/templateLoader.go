@@ -0,0 +1,237 @@
+package ARMTemplateShared
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	deploymentsResourceType = "Microsoft.Resources/deployments"
+	maxTemplateLinkDepth    = 20
+)
+
+// A templateLinkResolver returns the template referenced by a relative template link,
+// and the resolver to be used for the relative links of that template
+type templateLinkResolver func(relativePath string) (map[string]interface{}, templateLinkResolver, error)
+
+// LoadTemplate reads the ARM template, and inlines the nested deployments which link to templates using
+// templateLink.relativePath (or a relative templateLink.uri) resolved from the local filesystem
+func LoadTemplate(templateFilePath string) (map[string]interface{}, error) {
+	template, err := mpfSharedUtils.ReadJson(templateFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = inlineRelativeTemplateLinks(template, newFileTemplateLinkResolver(filepath.Dir(templateFilePath)), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// GetTemplateSpecTemplate fetches the main template of a template spec version, with its linked templates inlined
+func GetTemplateSpecTemplate(templateSpecVersionID string, bearerToken string) (map[string]interface{}, error) {
+	if !strings.Contains(strings.ToLower(templateSpecVersionID), "/providers/microsoft.resources/templatespecs/") || !strings.Contains(strings.ToLower(templateSpecVersionID), "/versions/") {
+		return nil, fmt.Errorf("invalid template spec version ID, expected /subscriptions/<subscriptionID>/resourceGroups/<resourceGroup>/providers/Microsoft.Resources/templateSpecs/<name>/versions/<version>: %s", templateSpecVersionID)
+	}
+
+	url := fmt.Sprintf("https://management.azure.com%s?api-version=2022-02-01", templateSpecVersionID)
+
+	client := &http.Client{}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Go HTTP Client")
+
+	// add bearer token to header
+	req.Header.Add("Authorization", "Bearer "+bearerToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error getting template spec version. Status code: %d, %s", resp.StatusCode, string(body))
+	}
+
+	var templateSpecVersion struct {
+		Properties struct {
+			MainTemplate    map[string]interface{} `json:"mainTemplate"`
+			LinkedTemplates []struct {
+				Path     string                 `json:"path"`
+				Template map[string]interface{} `json:"template"`
+			} `json:"linkedTemplates"`
+		} `json:"properties"`
+	}
+
+	err = json.Unmarshal(body, &templateSpecVersion)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding template spec version: %w", err)
+	}
+
+	if templateSpecVersion.Properties.MainTemplate == nil {
+		return nil, fmt.Errorf("template spec version has no main template: %s", templateSpecVersionID)
+	}
+
+	linkedTemplates := make(map[string]map[string]interface{})
+	for _, linkedTemplate := range templateSpecVersion.Properties.LinkedTemplates {
+		linkedTemplates[path.Clean(filepath.ToSlash(linkedTemplate.Path))] = linkedTemplate.Template
+	}
+
+	mainTemplate := templateSpecVersion.Properties.MainTemplate
+	err = inlineRelativeTemplateLinks(mainTemplate, newTemplateSpecLinkResolver(linkedTemplates, "."), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return mainTemplate, nil
+}
+
+func newFileTemplateLinkResolver(baseDir string) templateLinkResolver {
+	return func(relativePath string) (map[string]interface{}, templateLinkResolver, error) {
+		linkedTemplatePath := filepath.Join(baseDir, filepath.FromSlash(relativePath))
+		log.Infof("Loading linked template: %s \n", linkedTemplatePath)
+
+		linkedTemplate, err := mpfSharedUtils.ReadJson(linkedTemplatePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading linked template %s: %w", linkedTemplatePath, err)
+		}
+		return linkedTemplate, newFileTemplateLinkResolver(filepath.Dir(linkedTemplatePath)), nil
+	}
+}
+
+// relative paths in template specs are relative to the template that references them, and are stored in linkedTemplates
+func newTemplateSpecLinkResolver(linkedTemplates map[string]map[string]interface{}, baseDir string) templateLinkResolver {
+	return func(relativePath string) (map[string]interface{}, templateLinkResolver, error) {
+		linkedTemplatePath := path.Clean(path.Join(baseDir, filepath.ToSlash(relativePath)))
+
+		linkedTemplate, ok := linkedTemplates[linkedTemplatePath]
+		if !ok {
+			return nil, nil, fmt.Errorf("linked template %s not found in template spec", linkedTemplatePath)
+		}
+		return linkedTemplate, newTemplateSpecLinkResolver(linkedTemplates, path.Dir(linkedTemplatePath)), nil
+	}
+}
+
+func inlineRelativeTemplateLinks(template map[string]interface{}, resolve templateLinkResolver, depth int) error {
+	if depth > maxTemplateLinkDepth {
+		return errors.New("maximum depth of linked templates exceeded, the templates potentially link to each other")
+	}
+
+	for _, resource := range GetTemplateResources(template) {
+		resourceType, _ := resource["type"].(string)
+		if !strings.EqualFold(resourceType, deploymentsResourceType) {
+			continue
+		}
+
+		properties, ok := resource["properties"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		// inline nested templates can also contain relative links
+		if nestedTemplate, ok := properties["template"].(map[string]interface{}); ok {
+			err := inlineRelativeTemplateLinks(nestedTemplate, resolve, depth+1)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		templateLink, ok := properties["templateLink"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		relativePath := getRelativeTemplateLinkPath(templateLink)
+		if relativePath == "" {
+			// template spec IDs and absolute URIs are resolved by ARM at deployment time
+			continue
+		}
+
+		if strings.HasPrefix(relativePath, "[") {
+			log.Warnf("Template link path is an expression and can not be resolved locally: %s \n", relativePath)
+			continue
+		}
+
+		linkedTemplate, linkedTemplateResolver, err := resolve(relativePath)
+		if err != nil {
+			return err
+		}
+
+		err = inlineRelativeTemplateLinks(linkedTemplate, linkedTemplateResolver, depth+1)
+		if err != nil {
+			return err
+		}
+
+		// Linked templates have their own scope for parameters and variables, which for inline templates requires the inner scope
+		delete(properties, "templateLink")
+		properties["template"] = linkedTemplate
+		properties["expressionEvaluationOptions"] = map[string]interface{}{
+			"scope": "inner",
+		}
+	}
+
+	return nil
+}
+
+func getRelativeTemplateLinkPath(templateLink map[string]interface{}) string {
+	if relativePath, ok := templateLink["relativePath"].(string); ok && relativePath != "" {
+		return relativePath
+	}
+
+	if uri, ok := templateLink["uri"].(string); ok && uri != "" {
+		lowerURI := strings.ToLower(uri)
+		if strings.HasPrefix(lowerURI, "https://") || strings.HasPrefix(lowerURI, "http://") || strings.HasPrefix(uri, "[") {
+			return ""
+		}
+		return uri
+	}
+
+	return ""
+}
+
+// GetTemplateResources returns the resources of the template. Resources are an array,
+// except for templates with languageVersion 2.0 where they are an object keyed by symbolic name
+func GetTemplateResources(template map[string]interface{}) []map[string]interface{} {
+	var resources []map[string]interface{}
+
+	switch res := template["resources"].(type) {
+	case []interface{}:
+		for _, r := range res {
+			if resource, ok := r.(map[string]interface{}); ok {
+				resources = append(resources, resource)
+			}
+		}
+	case map[string]interface{}:
+		for _, r := range res {
+			if resource, ok := r.(map[string]interface{}); ok {
+				resources = append(resources, resource)
+			}
+		}
+	}
+
+	return resources
+}
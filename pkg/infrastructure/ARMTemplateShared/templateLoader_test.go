@@ -0,0 +1,189 @@
+package ARMTemplateShared
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mainTemplateWithRelativeLink = `{
+	"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#",
+	"contentVersion": "1.0.0.0",
+	"resources": [
+		{
+			"type": "Microsoft.Resources/deployments",
+			"apiVersion": "2022-09-01",
+			"name": "storage",
+			"properties": {
+				"mode": "Incremental",
+				"templateLink": {
+					"relativePath": "modules/storage.json"
+				}
+			}
+		},
+		{
+			"type": "Microsoft.Resources/deployments",
+			"apiVersion": "2022-09-01",
+			"name": "remote",
+			"properties": {
+				"mode": "Incremental",
+				"templateLink": {
+					"uri": "https://raw.githubusercontent.com/Azure/azure-quickstart-templates/master/quickstarts/microsoft.storage/storage-account-create/azuredeploy.json"
+				}
+			}
+		}
+	]
+}`
+
+const storageTemplateWithRelativeLink = `{
+	"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#",
+	"contentVersion": "1.0.0.0",
+	"resources": [
+		{
+			"type": "Microsoft.Storage/storageAccounts",
+			"apiVersion": "2022-09-01",
+			"name": "mpfstorage",
+			"location": "eastus",
+			"kind": "StorageV2",
+			"sku": { "name": "Standard_LRS" }
+		},
+		{
+			"type": "Microsoft.Resources/deployments",
+			"apiVersion": "2022-09-01",
+			"name": "vnet",
+			"properties": {
+				"mode": "Incremental",
+				"templateLink": {
+					"relativePath": "../shared/vnet.json"
+				}
+			}
+		}
+	]
+}`
+
+const vnetTemplate = `{
+	"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#",
+	"contentVersion": "1.0.0.0",
+	"resources": [
+		{
+			"type": "Microsoft.Network/virtualNetworks",
+			"apiVersion": "2022-09-01",
+			"name": "mpfvnet",
+			"location": "eastus"
+		}
+	]
+}`
+
+func writeTestTemplate(t *testing.T, path string, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	assert.Nil(t, err)
+	err = os.WriteFile(path, []byte(content), 0644)
+	assert.Nil(t, err)
+}
+
+func getNestedTemplate(t *testing.T, resource map[string]interface{}) map[string]interface{} {
+	properties := resource["properties"].(map[string]interface{})
+	assert.Nil(t, properties["templateLink"])
+	assert.Equal(t, map[string]interface{}{"scope": "inner"}, properties["expressionEvaluationOptions"])
+	return properties["template"].(map[string]interface{})
+}
+
+func TestLoadTemplateWithRelativeLinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	mainTemplatePath := filepath.Join(tmpDir, "main.json")
+	writeTestTemplate(t, mainTemplatePath, mainTemplateWithRelativeLink)
+	writeTestTemplate(t, filepath.Join(tmpDir, "modules", "storage.json"), storageTemplateWithRelativeLink)
+	writeTestTemplate(t, filepath.Join(tmpDir, "shared", "vnet.json"), vnetTemplate)
+
+	template, err := LoadTemplate(mainTemplatePath)
+	assert.Nil(t, err)
+
+	resources := GetTemplateResources(template)
+	assert.Equal(t, 2, len(resources))
+
+	storageTemplate := getNestedTemplate(t, resources[0])
+	storageResources := GetTemplateResources(storageTemplate)
+	assert.Equal(t, "Microsoft.Storage/storageAccounts", storageResources[0]["type"])
+
+	vnetNestedTemplate := getNestedTemplate(t, storageResources[1])
+	vnetResources := GetTemplateResources(vnetNestedTemplate)
+	assert.Equal(t, "Microsoft.Network/virtualNetworks", vnetResources[0]["type"])
+
+	// absolute URIs are left for ARM to resolve
+	remoteProperties := resources[1]["properties"].(map[string]interface{})
+	assert.NotNil(t, remoteProperties["templateLink"])
+	assert.Nil(t, remoteProperties["template"])
+}
+
+func TestLoadTemplateWithMissingLinkedTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	mainTemplatePath := filepath.Join(tmpDir, "main.json")
+	writeTestTemplate(t, mainTemplatePath, mainTemplateWithRelativeLink)
+
+	_, err := LoadTemplate(mainTemplatePath)
+	assert.NotNil(t, err)
+}
+
+func TestInlineTemplateSpecLinkedTemplates(t *testing.T) {
+	mainTemplate := map[string]interface{}{
+		"resources": []interface{}{
+			map[string]interface{}{
+				"type": "Microsoft.Resources/deployments",
+				"name": "storage",
+				"properties": map[string]interface{}{
+					"templateLink": map[string]interface{}{
+						"relativePath": "modules/storage.json",
+					},
+				},
+			},
+		},
+	}
+
+	linkedTemplates := map[string]map[string]interface{}{
+		"modules/storage.json": {
+			"resources": []interface{}{
+				map[string]interface{}{
+					"type": "Microsoft.Resources/deployments",
+					"name": "vnet",
+					"properties": map[string]interface{}{
+						"templateLink": map[string]interface{}{
+							"relativePath": "../shared/vnet.json",
+						},
+					},
+				},
+			},
+		},
+		"shared/vnet.json": {
+			"resources": []interface{}{
+				map[string]interface{}{
+					"type": "Microsoft.Network/virtualNetworks",
+					"name": "mpfvnet",
+				},
+			},
+		},
+	}
+
+	err := inlineRelativeTemplateLinks(mainTemplate, newTemplateSpecLinkResolver(linkedTemplates, "."), 0)
+	assert.Nil(t, err)
+
+	storageTemplate := getNestedTemplate(t, GetTemplateResources(mainTemplate)[0])
+	vnetNestedTemplate := getNestedTemplate(t, GetTemplateResources(storageTemplate)[0])
+	assert.Equal(t, "Microsoft.Network/virtualNetworks", GetTemplateResources(vnetNestedTemplate)[0]["type"])
+}
+
+func TestGetTemplateResourcesWithSymbolicNames(t *testing.T) {
+	template := map[string]interface{}{
+		"languageVersion": "2.0",
+		"resources": map[string]interface{}{
+			"vnet": map[string]interface{}{
+				"type": "Microsoft.Network/virtualNetworks",
+			},
+		},
+	}
+
+	resources := GetTemplateResources(template)
+	assert.Equal(t, 1, len(resources))
+	assert.Equal(t, "Microsoft.Network/virtualNetworks", resources[0]["type"])
+}
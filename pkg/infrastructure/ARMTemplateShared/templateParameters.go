@@ -0,0 +1,244 @@
+package ARMTemplateShared
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	deploymentParametersSchema = "https://schema.management.azure.com/schemas/2019-04-01/deploymentParameters.json#"
+
+	generatedNamePrefix          = "mpf"
+	defaultGeneratedNameLength   = 12
+	defaultGeneratedSecretLength = 20
+)
+
+// ParseParameterOverrides parses parameter overrides in the key=value format
+func ParseParameterOverrides(overrides []string) (map[string]string, error) {
+	parameterOverrides := make(map[string]string)
+	for _, override := range overrides {
+		key, value, found := strings.Cut(override, "=")
+		if !found || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid parameter override %q, expected key=value", override)
+		}
+		parameterOverrides[strings.TrimSpace(key)] = value
+	}
+	return parameterOverrides, nil
+}
+
+// GetDeploymentParameters returns the parameters for the deployment of the template in the deploymentParameters format.
+// Parameters of the parameters file (optional) are merged with the overrides, and values are generated for the required
+// parameters of the template which are still missing
+func GetDeploymentParameters(templateFilePath string, parametersFilePath string, parameterOverrides map[string]string) (map[string]interface{}, error) {
+	template, err := mpfSharedUtils.ReadJson(templateFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading template file: %w", err)
+	}
+
+	parameters := make(map[string]interface{})
+	if parametersFilePath != "" {
+		fileParameters, err := mpfSharedUtils.ReadJson(parametersFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading parameters file: %w", err)
+		}
+		parameters = GetParametersInStandardFormat(fileParameters)
+	}
+
+	templateParameters, _ := template["parameters"].(map[string]interface{})
+
+	for name, value := range parameterOverrides {
+		templateParameterName, templateParameter := getTemplateParameter(templateParameters, name)
+		if templateParameter == nil {
+			return nil, fmt.Errorf("parameter override %s is not a parameter of the template", name)
+		}
+
+		typedValue, err := getTypedParameterValue(templateParameter, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for parameter override %s: %w", name, err)
+		}
+		parameters[templateParameterName] = map[string]interface{}{
+			"value": typedValue,
+		}
+	}
+
+	// sorted, so that the generated values are logged in a predictable order
+	names := make([]string, 0, len(templateParameters))
+	for name := range templateParameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		templateParameter, ok := templateParameters[name].(map[string]interface{})
+		if !ok || !isRequiredParameter(templateParameter) {
+			continue
+		}
+		if _, ok := parameters[name]; ok {
+			continue
+		}
+
+		value, err := generateParameterValue(templateParameter)
+		if err != nil {
+			return nil, fmt.Errorf("error generating value for parameter %s: %w", name, err)
+		}
+
+		if isSecureParameter(templateParameter) {
+			log.Infof("Generated value for secure parameter %s\n", name)
+		} else {
+			log.Infof("Generated value for parameter %s: %v\n", name, value)
+		}
+		parameters[name] = map[string]interface{}{
+			"value": value,
+		}
+	}
+
+	return map[string]interface{}{
+		"$schema":        deploymentParametersSchema,
+		"contentVersion": "1.0.0.0",
+		"parameters":     parameters,
+	}, nil
+}
+
+// GetUpdateDeploymentParameters returns the parameters for the update deployment of an update scenario, the parameters of the
+// update parameters file merged into the parameters of the initial deployment. Values generated for the initial deployment are
+// kept, so that the update deployment updates the resources of the initial deployment
+func GetUpdateDeploymentParameters(deploymentParametersFilePath string, updateParametersFilePath string) (map[string]interface{}, error) {
+	deploymentParameters, err := mpfSharedUtils.ReadJson(deploymentParametersFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading deployment parameters file: %w", err)
+	}
+
+	updateParameters, err := mpfSharedUtils.ReadJson(updateParametersFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading update parameters file: %w", err)
+	}
+
+	parameters := GetParametersInStandardFormat(deploymentParameters)
+	for name, value := range GetParametersInStandardFormat(updateParameters) {
+		parameters[name] = value
+	}
+
+	return map[string]interface{}{
+		"$schema":        deploymentParametersSchema,
+		"contentVersion": "1.0.0.0",
+		"parameters":     parameters,
+	}, nil
+}
+
+// parameter names are case insensitive in ARM templates
+func getTemplateParameter(templateParameters map[string]interface{}, name string) (string, map[string]interface{}) {
+	for templateParameterName, templateParameter := range templateParameters {
+		if strings.EqualFold(templateParameterName, name) {
+			parameter, _ := templateParameter.(map[string]interface{})
+			return templateParameterName, parameter
+		}
+	}
+	return "", nil
+}
+
+func isRequiredParameter(templateParameter map[string]interface{}) bool {
+	if _, ok := templateParameter["defaultValue"]; ok {
+		return false
+	}
+	nullable, _ := templateParameter["nullable"].(bool)
+	return !nullable
+}
+
+func getParameterType(templateParameter map[string]interface{}) string {
+	parameterType, _ := templateParameter["type"].(string)
+	return strings.ToLower(parameterType)
+}
+
+func isSecureParameter(templateParameter map[string]interface{}) bool {
+	parameterType := getParameterType(templateParameter)
+	return parameterType == "securestring" || parameterType == "secureobject"
+}
+
+func getTypedParameterValue(templateParameter map[string]interface{}, value string) (interface{}, error) {
+	switch getParameterType(templateParameter) {
+	case "int":
+		return strconv.Atoi(value)
+	case "bool":
+		return strconv.ParseBool(value)
+	case "object", "secureobject", "array":
+		var typedValue interface{}
+		err := json.Unmarshal([]byte(value), &typedValue)
+		return typedValue, err
+	default:
+		return value, nil
+	}
+}
+
+func generateParameterValue(templateParameter map[string]interface{}) (interface{}, error) {
+	if allowedValues, ok := templateParameter["allowedValues"].([]interface{}); ok && len(allowedValues) > 0 {
+		return allowedValues[0], nil
+	}
+
+	minLength, hasMinLength := getIntProperty(templateParameter, "minLength")
+	maxLength, hasMaxLength := getIntProperty(templateParameter, "maxLength")
+
+	switch getParameterType(templateParameter) {
+	case "string":
+		return generateUniqueName(getGeneratedLength(defaultGeneratedNameLength, minLength, hasMinLength, maxLength, hasMaxLength)), nil
+	case "securestring":
+		return generateSecret(getGeneratedLength(defaultGeneratedSecretLength, minLength, hasMinLength, maxLength, hasMaxLength)), nil
+	case "int":
+		if minValue, ok := getIntProperty(templateParameter, "minValue"); ok {
+			return minValue, nil
+		}
+		if maxValue, ok := getIntProperty(templateParameter, "maxValue"); ok && maxValue < 1 {
+			return maxValue, nil
+		}
+		return 1, nil
+	case "bool":
+		return false, nil
+	case "array":
+		return []interface{}{}, nil
+	case "object", "secureobject":
+		return map[string]interface{}{}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported parameter type: %v", templateParameter["type"])
+}
+
+func getGeneratedLength(defaultLength int, minLength int, hasMinLength bool, maxLength int, hasMaxLength bool) int {
+	length := defaultLength
+	if hasMinLength && length < minLength {
+		length = minLength
+	}
+	if hasMaxLength && length > maxLength {
+		length = maxLength
+	}
+	return length
+}
+
+// names are lowercase alphanumeric starting with a letter, which is accepted by most resource types, including storage accounts
+func generateUniqueName(length int) string {
+	if length <= len(generatedNamePrefix) {
+		return strings.ToLower(generatedNamePrefix + mpfSharedUtils.GenerateRandomString(length))[:max(length, 0)]
+	}
+	return generatedNamePrefix + strings.ToLower(mpfSharedUtils.GenerateRandomString(length-len(generatedNamePrefix)))
+}
+
+// secrets contain upper and lower case letters, digits and a special character to meet common complexity requirements
+func generateSecret(length int) string {
+	const complexitySuffix = "aA1!"
+	if length <= len(complexitySuffix) {
+		return complexitySuffix[:max(length, 0)]
+	}
+	return mpfSharedUtils.GenerateRandomString(length-len(complexitySuffix)) + complexitySuffix
+}
+
+func getIntProperty(templateParameter map[string]interface{}, property string) (int, bool) {
+	value, ok := templateParameter[property].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int(value), true
+}
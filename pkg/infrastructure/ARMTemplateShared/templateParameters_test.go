@@ -0,0 +1,141 @@
+package ARMTemplateShared
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+const templateWithParameters = `{
+	"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#",
+	"contentVersion": "1.0.0.0",
+	"parameters": {
+		"location": {
+			"type": "string",
+			"defaultValue": "[resourceGroup().location]"
+		},
+		"storageAccountName": {
+			"type": "string",
+			"minLength": 3,
+			"maxLength": 8
+		},
+		"skuName": {
+			"type": "string",
+			"allowedValues": ["Standard_LRS", "Standard_GRS"]
+		},
+		"adminPassword": {
+			"type": "securestring"
+		},
+		"nodeCount": {
+			"type": "int",
+			"minValue": 2
+		},
+		"enableRbac": {
+			"type": "bool"
+		},
+		"tags": {
+			"type": "object"
+		},
+		"clusterName": {
+			"type": "string"
+		}
+	},
+	"resources": []
+}`
+
+const parametersForTemplateWithParameters = `{
+	"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentParameters.json#",
+	"contentVersion": "1.0.0.0",
+	"parameters": {
+		"clusterName": {
+			"value": "myAKSCluster"
+		},
+		"nodeCount": {
+			"value": 3
+		}
+	}
+}`
+
+func writeTemplateParametersTestFiles(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	templateFilePath := filepath.Join(dir, "main.json")
+	parametersFilePath := filepath.Join(dir, "main.parameters.json")
+	assert.NoError(t, os.WriteFile(templateFilePath, []byte(templateWithParameters), 0600))
+	assert.NoError(t, os.WriteFile(parametersFilePath, []byte(parametersForTemplateWithParameters), 0600))
+	return templateFilePath, parametersFilePath
+}
+
+func TestGetDeploymentParametersWithoutParametersFile(t *testing.T) {
+	templateFilePath, _ := writeTemplateParametersTestFiles(t)
+
+	deploymentParameters, err := GetDeploymentParameters(templateFilePath, "", nil)
+	assert.NoError(t, err)
+
+	parameters := GetParametersInStandardFormat(deploymentParameters)
+	assert.NotContains(t, parameters, "location")
+
+	storageAccountName := parameters["storageAccountName"].(map[string]interface{})["value"].(string)
+	assert.Len(t, storageAccountName, 8)
+	assert.Regexp(t, regexp.MustCompile(`^mpf[a-z0-9]{5}$`), storageAccountName)
+
+	assert.Equal(t, "Standard_LRS", parameters["skuName"].(map[string]interface{})["value"])
+	assert.Len(t, parameters["adminPassword"].(map[string]interface{})["value"], defaultGeneratedSecretLength)
+	assert.Equal(t, 2, parameters["nodeCount"].(map[string]interface{})["value"])
+	assert.Equal(t, false, parameters["enableRbac"].(map[string]interface{})["value"])
+	assert.Equal(t, map[string]interface{}{}, parameters["tags"].(map[string]interface{})["value"])
+	assert.Len(t, parameters["clusterName"].(map[string]interface{})["value"], defaultGeneratedNameLength)
+}
+
+func TestGetDeploymentParametersMergesFileAndOverrides(t *testing.T) {
+	templateFilePath, parametersFilePath := writeTemplateParametersTestFiles(t)
+
+	overrides, err := ParseParameterOverrides([]string{"NodeCount=5", "skuName=Standard_GRS", "tags={\"env\":\"dev\"}"})
+	assert.NoError(t, err)
+
+	deploymentParameters, err := GetDeploymentParameters(templateFilePath, parametersFilePath, overrides)
+	assert.NoError(t, err)
+
+	parameters := GetParametersInStandardFormat(deploymentParameters)
+	assert.Equal(t, "myAKSCluster", parameters["clusterName"].(map[string]interface{})["value"])
+	assert.Equal(t, 5, parameters["nodeCount"].(map[string]interface{})["value"])
+	assert.Equal(t, "Standard_GRS", parameters["skuName"].(map[string]interface{})["value"])
+	assert.Equal(t, map[string]interface{}{"env": "dev"}, parameters["tags"].(map[string]interface{})["value"])
+}
+
+func TestGetDeploymentParametersInvalidOverrides(t *testing.T) {
+	templateFilePath, _ := writeTemplateParametersTestFiles(t)
+
+	_, err := GetDeploymentParameters(templateFilePath, "", map[string]string{"unknown": "value"})
+	assert.Error(t, err)
+
+	_, err = GetDeploymentParameters(templateFilePath, "", map[string]string{"nodeCount": "many"})
+	assert.Error(t, err)
+
+	_, err = ParseParameterOverrides([]string{"nodeCount"})
+	assert.Error(t, err)
+}
+
+func TestGetUpdateDeploymentParameters(t *testing.T) {
+	templateFilePath, parametersFilePath := writeTemplateParametersTestFiles(t)
+
+	deploymentParameters, err := GetDeploymentParameters(templateFilePath, parametersFilePath, nil)
+	assert.NoError(t, err)
+	deploymentParametersFilePath := filepath.Join(t.TempDir(), "parameters.json")
+	assert.NoError(t, mpfSharedUtils.WriteJson(deploymentParametersFilePath, deploymentParameters))
+
+	updateParametersFilePath := filepath.Join(t.TempDir(), "update.parameters.json")
+	assert.NoError(t, os.WriteFile(updateParametersFilePath, []byte(`{"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentParameters.json#", "parameters": {"nodeCount": {"value": 5}}}`), 0600))
+
+	updateDeploymentParameters, err := GetUpdateDeploymentParameters(deploymentParametersFilePath, updateParametersFilePath)
+	assert.NoError(t, err)
+
+	parameters := GetParametersInStandardFormat(deploymentParameters)
+	updateParameters := GetParametersInStandardFormat(updateDeploymentParameters)
+	assert.Equal(t, float64(5), updateParameters["nodeCount"].(map[string]interface{})["value"])
+	assert.Equal(t, "myAKSCluster", updateParameters["clusterName"].(map[string]interface{})["value"])
+	assert.Equal(t, parameters["storageAccountName"], updateParameters["storageAccountName"])
+}
@@ -3,7 +3,6 @@ package ARMTemplateDeployment
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -15,29 +14,43 @@ import (
 
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/ARMTemplateShared"
 	"github.com/manisbindra/az-mpf/pkg/infrastructure/azureAPI"
-	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
 	"github.com/manisbindra/az-mpf/pkg/domain"
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	DefaultDeploymentTimeout = 10 * time.Minute
+	deploymentPollInterval   = 5 * time.Second
+)
+
+var errDeploymentNotCompleted = errors.New("DeploymentNotCompleted")
+
 type armDeploymentConfig struct {
-	ctx         context.Context
-	armConfig   ARMTemplateShared.ArmTemplateAdditionalConfig
-	azAPIClient *azureAPI.AzureAPIClients
+	ctx               context.Context
+	armConfig         ARMTemplateShared.ArmTemplateAdditionalConfig
+	azAPIClient       *azureAPI.AzureAPIClients
+	deploymentTimeout time.Duration
 }
 
 func NewARMTemplateDeploymentAuthorizationChecker(subscriptionID string, armConfig ARMTemplateShared.ArmTemplateAdditionalConfig) *armDeploymentConfig {
 	azAPIClient := azureAPI.NewAzureAPIClients(subscriptionID)
 	return &armDeploymentConfig{
-		azAPIClient: azAPIClient,
-		armConfig:   armConfig,
-		ctx:         context.Background(),
+		azAPIClient:       azAPIClient,
+		armConfig:         armConfig,
+		ctx:               context.Background(),
+		deploymentTimeout: DefaultDeploymentTimeout,
 	}
 
 }
 
+// SetDeploymentTimeout sets how long to wait for the deployment to complete, before the authorization errors of the
+// operations which failed so far are returned
+func (a *armDeploymentConfig) SetDeploymentTimeout(deploymentTimeout time.Duration) {
+	a.deploymentTimeout = deploymentTimeout
+}
+
 func (a *armDeploymentConfig) GetDeploymentAuthorizationErrors(mpfConfig domain.MPFConfig) (string, error) {
 	return a.deployARMTemplate(a.armConfig.DeploymentName, mpfConfig)
 }
@@ -65,36 +78,11 @@ func (a *armDeploymentConfig) deployARMTemplate(deploymentName string, mpfConfig
 		return "", err
 	}
 
-	// read template and parameters
-	template, err := mpfSharedUtils.ReadJson(a.armConfig.TemplateFilePath)
-	if err != nil {
-		return "", err
-	}
-
-	parameters, err := mpfSharedUtils.ReadJson(a.armConfig.ParametersFilePath)
-	if err != nil {
-		return "", err
-	}
-
-	// convert parameters to standard format
-	parameters = ARMTemplateShared.GetParametersInStandardFormat(parameters)
-
-	fullTemplate := map[string]interface{}{
-		"properties": map[string]interface{}{
-			"mode":       "Incremental",
-			"template":   template,
-			"parameters": parameters,
-		},
-	}
-
-	// convert bodyJSON to string
-	fullTemplateJSONBytes, err := json.Marshal(fullTemplate)
+	fullTemplateJSONString, err := ARMTemplateShared.GetDeploymentRequestBody(a.armConfig)
 	if err != nil {
 		return "", err
 	}
 
-	fullTemplateJSONString := string(fullTemplateJSONBytes)
-
 	log.Debugln()
 	log.Debugln(fullTemplateJSONString)
 	log.Debugln()
@@ -103,7 +91,8 @@ func (a *armDeploymentConfig) deployARMTemplate(deploymentName string, mpfConfig
 	client := &http.Client{}
 
 	log.Info("MPF mode is fullDeployment, Proceeding to create resources....")
-	url := fmt.Sprintf("https://management.azure.com/subscriptions/%s/resourcegroups/%s/providers/Microsoft.Resources/deployments/%s?api-version=2020-10-01", mpfConfig.SubscriptionID, mpfConfig.ResourceGroup.ResourceGroupName, deploymentName)
+	deploymentID := ARMTemplateShared.GetDeploymentResourceID(a.armConfig, mpfConfig.SubscriptionID, mpfConfig.ResourceGroup.ResourceGroupName, deploymentName)
+	url := fmt.Sprintf("https://management.azure.com%s?api-version=2020-10-01", deploymentID)
 	reqMethod := "PUT"
 
 	req, err := http.NewRequest(reqMethod, url, bytes.NewBufferString(fullTemplateJSONString))
@@ -147,12 +136,94 @@ func (a *armDeploymentConfig) deployARMTemplate(deploymentName string, mpfConfig
 		// Sample error [{\"code\":\"PodIdentityAddonFeatureFlagNotEnabled\",\"message\":\"Provisioning of resource(s) for container service aks-24xalwx7i2ueg in resource group testdeployrg-Y2jsRAG failed. Message: PodIdentity addon is not allowed since feature 'Microsoft.ContainerService/EnablePodIdentityPreview' is not enabled.
 		// Hence ok to proceed, and not return error in this condition
 		log.Warnf("Non Authorizaton error occured: %s", respBody)
+		return "", nil
 	}
 
-	return "", nil
+	// Failures inside nested or linked deployments only surface in the deployment operations,
+	// so wait for the deployment to complete and harvest the authorization errors from the operations tree
+	return a.getDeploymentOperationsAuthorizationErrors(deploymentName, mpfConfig)
 
 }
 
+func (a *armDeploymentConfig) getDeploymentOperationsAuthorizationErrors(deploymentName string, mpfConfig domain.MPFConfig) (string, error) {
+
+	deployment, err := a.waitForDeploymentCompletion(deploymentName, mpfConfig)
+	if errors.Is(err, errDeploymentNotCompleted) && deployment.ID != nil {
+		// Long running deployments, for example of AKS clusters, may already have failed operations
+		log.Warnf("Deployment %s did not complete in %s, checking the operations which failed so far for authorization errors \n", deploymentName, a.deploymentTimeout)
+		return a.getCompletedOperationsAuthorizationErrors(deploymentName, *deployment.ID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if deployment.Properties == nil || deployment.Properties.ProvisioningState == nil || *deployment.Properties.ProvisioningState != armresources.ProvisioningStateFailed {
+		return "", nil
+	}
+
+	log.Infof("Deployment %s failed, checking deployment operations for authorization errors \n", deploymentName)
+
+	authErrors, err := a.getNestedDeploymentsAuthorizationErrors(*deployment.ID)
+	if err != nil {
+		return "", fmt.Errorf("error getting deployment operations: %w", err)
+	}
+
+	if len(authErrors) == 0 {
+		log.Warnf("Deployment %s failed with non authorization errors \n", deploymentName)
+		return "", nil
+	}
+
+	log.Infof("Found %d authorization errors in deployment operations \n", len(authErrors))
+	return strings.Join(authErrors, "\n"), nil
+}
+
+// getCompletedOperationsAuthorizationErrors returns the authorization errors of the operations of a deployment which did not
+// complete. If none of its operations failed with authorization errors so far, no authorization errors are returned, as
+// for a deployment which succeeded, so that slow deployments do not fail the run
+func (a *armDeploymentConfig) getCompletedOperationsAuthorizationErrors(deploymentName string, deploymentID string) (string, error) {
+	authErrors, err := a.getNestedDeploymentsAuthorizationErrors(deploymentID)
+	if err != nil {
+		return "", fmt.Errorf("error getting deployment operations: %w", err)
+	}
+
+	if len(authErrors) == 0 {
+		log.Warnf("Deployment %s did not complete in %s, and no operations failed with authorization errors so far. Increase the deployment timeout to discover the permissions of the remaining operations \n", deploymentName, a.deploymentTimeout)
+		return "", nil
+	}
+
+	log.Infof("Found %d authorization errors in deployment operations \n", len(authErrors))
+	return strings.Join(authErrors, "\n"), nil
+}
+
+// waitForDeploymentCompletion polls the deployment until it completes. If it does not complete within the deployment timeout,
+// the deployment is returned with errDeploymentNotCompleted
+func (a *armDeploymentConfig) waitForDeploymentCompletion(deploymentName string, mpfConfig domain.MPFConfig) (armresources.DeploymentExtended, error) {
+
+	deadline := time.Now().Add(a.deploymentTimeout)
+	for {
+		deployment, err := a.getDeployment(a.ctx, deploymentName, mpfConfig)
+		if err != nil {
+			return armresources.DeploymentExtended{}, fmt.Errorf("error getting deployment %s: %w", deploymentName, err)
+		}
+
+		if deployment.Properties != nil && deployment.Properties.ProvisioningState != nil {
+			switch *deployment.Properties.ProvisioningState {
+			case armresources.ProvisioningStateSucceeded, armresources.ProvisioningStateFailed, armresources.ProvisioningStateCanceled:
+				log.Infof("Deployment %s completed with provisioning state: %s \n", deploymentName, *deployment.Properties.ProvisioningState)
+				return deployment, nil
+			}
+			log.Debugf("Deployment %s provisioning state: %s \n", deploymentName, *deployment.Properties.ProvisioningState)
+		}
+
+		if time.Now().After(deadline) {
+			return deployment, fmt.Errorf("%w: deployment %s did not complete in %s", errDeploymentNotCompleted, deploymentName, a.deploymentTimeout)
+		}
+
+		log.Infoln("Deployment still in progress, checking again in a bit...")
+		time.Sleep(deploymentPollInterval)
+	}
+}
+
 // func (a *armDeploymentConfig) getARMDeployment(deploymentName string) error {
 
 // 	bearerToken, err := a.azAPIClient.GetSPBearerToken(a.mpfCfg.TenantID, a.mpfCfg.SPClientID, a.mpfCfg.SPClientSecret)
@@ -205,7 +276,7 @@ func (a *armDeploymentConfig) deployARMTemplate(deploymentName string, mpfConfig
 func (a *armDeploymentConfig) cancelDeployment(ctx context.Context, deploymentName string, mpfConfig domain.MPFConfig) error {
 
 	// Get deployments status. If status is "Running", cancel deployment, then delete deployment
-	getResp, err := a.azAPIClient.DeploymentsClient.Get(ctx, mpfConfig.ResourceGroup.ResourceGroupName, deploymentName, nil)
+	deployment, err := a.getDeployment(ctx, deploymentName, mpfConfig)
 	if err != nil {
 		// Error indicates deployment does not exist, so cancelling deployment not needed
 		if strings.Contains(err.Error(), "DeploymentNotFound") {
@@ -214,12 +285,12 @@ func (a *armDeploymentConfig) cancelDeployment(ctx context.Context, deploymentNa
 		}
 	}
 
-	log.Infof("Deployment status: %s\n", *getResp.DeploymentExtended.Properties.ProvisioningState)
+	log.Infof("Deployment status: %s\n", *deployment.Properties.ProvisioningState)
 
-	if *getResp.DeploymentExtended.Properties.ProvisioningState == armresources.ProvisioningStateRunning {
+	if *deployment.Properties.ProvisioningState == armresources.ProvisioningStateRunning {
 
 		retryCount := 0
-		for _, err := a.azAPIClient.DeploymentsClient.Cancel(ctx, mpfConfig.ResourceGroup.ResourceGroupName, deploymentName, nil); err != nil; {
+		for err := a.cancelRunningDeployment(ctx, deploymentName, mpfConfig); err != nil; {
 			// cancel deployment
 			if err != nil {
 				// return err
@@ -240,3 +311,23 @@ func (a *armDeploymentConfig) cancelDeployment(ctx context.Context, deploymentNa
 
 	return nil
 }
+
+// getDeployment gets the deployment, at subscription scope or in the resource group
+func (a *armDeploymentConfig) getDeployment(ctx context.Context, deploymentName string, mpfConfig domain.MPFConfig) (armresources.DeploymentExtended, error) {
+	if a.armConfig.SubscriptionScope {
+		getResp, err := a.azAPIClient.DeploymentsClient.GetAtSubscriptionScope(ctx, deploymentName, nil)
+		return getResp.DeploymentExtended, err
+	}
+	getResp, err := a.azAPIClient.DeploymentsClient.Get(ctx, mpfConfig.ResourceGroup.ResourceGroupName, deploymentName, nil)
+	return getResp.DeploymentExtended, err
+}
+
+// cancelRunningDeployment cancels the deployment, at subscription scope or in the resource group
+func (a *armDeploymentConfig) cancelRunningDeployment(ctx context.Context, deploymentName string, mpfConfig domain.MPFConfig) error {
+	if a.armConfig.SubscriptionScope {
+		_, err := a.azAPIClient.DeploymentsClient.CancelAtSubscriptionScope(ctx, deploymentName, nil)
+		return err
+	}
+	_, err := a.azAPIClient.DeploymentsClient.Cancel(ctx, mpfConfig.ResourceGroup.ResourceGroupName, deploymentName, nil)
+	return err
+}
@@ -0,0 +1,172 @@
+package ARMTemplateDeployment
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+const nestedDeploymentResourceType = "Microsoft.Resources/deployments"
+
+type deploymentOperationTargetResource struct {
+	ID           string `json:"id"`
+	ResourceType string `json:"resourceType"`
+	ResourceName string `json:"resourceName"`
+}
+
+type deploymentOperationProperties struct {
+	ProvisioningState string                             `json:"provisioningState"`
+	StatusCode        string                             `json:"statusCode"`
+	StatusMessage     interface{}                        `json:"statusMessage"`
+	TargetResource    *deploymentOperationTargetResource `json:"targetResource"`
+}
+
+type deploymentOperation struct {
+	ID          string                        `json:"id"`
+	OperationID string                        `json:"operationId"`
+	Properties  deploymentOperationProperties `json:"properties"`
+}
+
+type deploymentOperationsList struct {
+	Value    []deploymentOperation `json:"value"`
+	NextLink string                `json:"nextLink"`
+}
+
+// getNestedDeploymentsAuthorizationErrors walks the operations of the deployment, recursively following nested deployments,
+// and returns the authorization error status messages of all failed operations
+func (a *armDeploymentConfig) getNestedDeploymentsAuthorizationErrors(deploymentID string) ([]string, error) {
+	bearerToken, err := a.azAPIClient.GetDefaultAPIBearerToken()
+	if err != nil {
+		return nil, err
+	}
+
+	visited := make(map[string]bool)
+	return a.walkDeploymentOperations(deploymentID, bearerToken, visited)
+}
+
+func (a *armDeploymentConfig) walkDeploymentOperations(deploymentID string, bearerToken string, visited map[string]bool) ([]string, error) {
+	if visited[strings.ToLower(deploymentID)] {
+		return nil, nil
+	}
+	visited[strings.ToLower(deploymentID)] = true
+
+	operations, err := a.listDeploymentOperations(deploymentID, bearerToken)
+	if err != nil {
+		return nil, err
+	}
+
+	authErrors := getAuthorizationErrorsFromOperations(operations)
+
+	for _, nestedDeploymentID := range getNestedDeploymentIDs(operations) {
+		log.Debugf("Walking operations of nested deployment: %s \n", nestedDeploymentID)
+		nestedAuthErrors, err := a.walkDeploymentOperations(nestedDeploymentID, bearerToken, visited)
+		if err != nil {
+			// A nested deployment in a scope that can not be read should not hide the errors found so far
+			log.Warnf("Could not get operations of nested deployment %s: %v \n", nestedDeploymentID, err)
+			continue
+		}
+		authErrors = append(authErrors, nestedAuthErrors...)
+	}
+
+	return authErrors, nil
+}
+
+func (a *armDeploymentConfig) listDeploymentOperations(deploymentID string, bearerToken string) ([]deploymentOperation, error) {
+	client := &http.Client{}
+
+	var operations []deploymentOperation
+	url := fmt.Sprintf("https://management.azure.com%s/operations?api-version=2021-04-01", deploymentID)
+
+	for url != "" {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", "Go HTTP Client")
+
+		// add bearer token to header
+		req.Header.Add("Authorization", "Bearer "+bearerToken)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("error listing deployment operations. Status code: %d, %s", resp.StatusCode, string(body))
+		}
+
+		var operationsList deploymentOperationsList
+		err = json.Unmarshal(body, &operationsList)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding deployment operations: %w", err)
+		}
+
+		operations = append(operations, operationsList.Value...)
+		url = operationsList.NextLink
+	}
+
+	log.Debugf("Deployment %s has %d operations \n", deploymentID, len(operations))
+	return operations, nil
+}
+
+// getAuthorizationErrorsFromOperations returns the status messages of operations which the authorization error parser can handle
+func getAuthorizationErrorsFromOperations(operations []deploymentOperation) []string {
+	var authErrors []string
+	for _, op := range operations {
+		statusMessage := getStatusMessageString(op.Properties.StatusMessage)
+		if statusMessage == "" || !strings.Contains(statusMessage, "Authorization") {
+			continue
+		}
+
+		if _, err := domain.GetScopePermissionsFromAuthError(statusMessage); err != nil {
+			continue
+		}
+		authErrors = append(authErrors, statusMessage)
+	}
+	return authErrors
+}
+
+func getNestedDeploymentIDs(operations []deploymentOperation) []string {
+	var nestedDeploymentIDs []string
+	for _, op := range operations {
+		target := op.Properties.TargetResource
+		if target == nil || target.ID == "" {
+			continue
+		}
+		if strings.EqualFold(target.ResourceType, nestedDeploymentResourceType) {
+			nestedDeploymentIDs = append(nestedDeploymentIDs, target.ID)
+		}
+	}
+	return nestedDeploymentIDs
+}
+
+// the statusMessage of an operation can either be a plain string or a JSON object
+func getStatusMessageString(statusMessage interface{}) string {
+	switch msg := statusMessage.(type) {
+	case nil:
+		return ""
+	case string:
+		return msg
+	default:
+		msgBytes, err := json.Marshal(msg)
+		if err != nil {
+			return ""
+		}
+		return string(msgBytes)
+	}
+}
@@ -0,0 +1,86 @@
+package ARMTemplateDeployment
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleDeploymentOperations = `{
+	"value": [
+		{
+			"id": "/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Resources/deployments/testDeploy/operations/1",
+			"operationId": "1",
+			"properties": {
+				"provisioningState": "Failed",
+				"statusCode": "Forbidden",
+				"statusMessage": {
+					"status": "Failed",
+					"error": {
+						"code": "AuthorizationFailed",
+						"message": "The client 'XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX' with object id 'XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX' does not have authorization to perform action 'Microsoft.Storage/storageAccounts/write' over scope '/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Storage/storageAccounts/sa1' or the scope is invalid. If access was recently granted, please refresh your credentials."
+					}
+				},
+				"targetResource": {
+					"id": "/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Storage/storageAccounts/sa1",
+					"resourceType": "Microsoft.Storage/storageAccounts",
+					"resourceName": "sa1"
+				}
+			}
+		},
+		{
+			"id": "/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Resources/deployments/testDeploy/operations/2",
+			"operationId": "2",
+			"properties": {
+				"provisioningState": "Failed",
+				"statusCode": "Conflict",
+				"statusMessage": {
+					"status": "Failed",
+					"error": {
+						"code": "DeploymentFailed",
+						"message": "At least one resource deployment operation failed."
+					}
+				},
+				"targetResource": {
+					"id": "/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Resources/deployments/nestedModule",
+					"resourceType": "Microsoft.Resources/deployments",
+					"resourceName": "nestedModule"
+				}
+			}
+		},
+		{
+			"id": "/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Resources/deployments/testDeploy/operations/3",
+			"operationId": "3",
+			"properties": {
+				"provisioningState": "Succeeded",
+				"statusCode": "OK",
+				"statusMessage": "OK",
+				"targetResource": {
+					"id": "/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Network/virtualNetworks/vnet1",
+					"resourceType": "Microsoft.Network/virtualNetworks",
+					"resourceName": "vnet1"
+				}
+			}
+		}
+	]
+}`
+
+func TestGetAuthorizationErrorsFromOperations(t *testing.T) {
+	var operationsList deploymentOperationsList
+	err := json.Unmarshal([]byte(sampleDeploymentOperations), &operationsList)
+	assert.Nil(t, err)
+
+	authErrors := getAuthorizationErrorsFromOperations(operationsList.Value)
+	assert.Equal(t, 1, len(authErrors))
+	assert.Contains(t, authErrors[0], "Microsoft.Storage/storageAccounts/write")
+}
+
+func TestGetNestedDeploymentIDs(t *testing.T) {
+	var operationsList deploymentOperationsList
+	err := json.Unmarshal([]byte(sampleDeploymentOperations), &operationsList)
+	assert.Nil(t, err)
+
+	nestedDeploymentIDs := getNestedDeploymentIDs(operationsList.Value)
+	assert.Equal(t, []string{"/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Resources/deployments/nestedModule"}, nestedDeploymentIDs)
+}
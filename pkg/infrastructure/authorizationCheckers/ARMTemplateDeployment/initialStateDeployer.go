@@ -0,0 +1,80 @@
+package ARMTemplateDeployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resources/armresources"
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/ARMTemplateShared"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/azureAPI"
+	log "github.com/sirupsen/logrus"
+)
+
+const initialStateDeploymentNameSuffix = "initial"
+
+type armInitialStateDeployer struct {
+	ctx         context.Context
+	armConfig   ARMTemplateShared.ArmTemplateAdditionalConfig
+	azAPIClient *azureAPI.AzureAPIClients
+}
+
+// NewARMTemplateInitialStateDeployer returns the deployer of the initial state of an update scenario, which deploys the
+// template with the default credentials MPF runs with
+func NewARMTemplateInitialStateDeployer(subscriptionID string, armConfig ARMTemplateShared.ArmTemplateAdditionalConfig) *armInitialStateDeployer {
+	azAPIClient := azureAPI.NewAzureAPIClients(subscriptionID)
+	return &armInitialStateDeployer{
+		azAPIClient: azAPIClient,
+		armConfig:   armConfig,
+		ctx:         context.Background(),
+	}
+}
+
+// DeployInitialState deploys the template to the resource group, or the subscription, and waits for the deployment to complete
+func (d *armInitialStateDeployer) DeployInitialState(mpfConfig domain.MPFConfig) error {
+	deploymentName := fmt.Sprintf("%s-%s", d.armConfig.DeploymentName, initialStateDeploymentNameSuffix)
+
+	fullTemplateJSONString, err := ARMTemplateShared.GetDeploymentRequestBody(d.armConfig)
+	if err != nil {
+		return err
+	}
+
+	var deployment armresources.Deployment
+	err = json.Unmarshal([]byte(fullTemplateJSONString), &deployment)
+	if err != nil {
+		return fmt.Errorf("error creating deployment request: %w", err)
+	}
+
+	log.Infof("Creating initial state deployment %s \n", deploymentName)
+	resp, err := d.createDeployment(deploymentName, deployment, mpfConfig)
+	if err != nil {
+		return err
+	}
+
+	if resp.Properties == nil || resp.Properties.ProvisioningState == nil || *resp.Properties.ProvisioningState != armresources.ProvisioningStateSucceeded {
+		return fmt.Errorf("initial state deployment %s did not succeed", deploymentName)
+	}
+
+	log.Infof("Initial state deployment %s succeeded \n", deploymentName)
+	return nil
+}
+
+// createDeployment creates the deployment, at subscription scope or in the resource group, and waits for it to complete
+func (d *armInitialStateDeployer) createDeployment(deploymentName string, deployment armresources.Deployment, mpfConfig domain.MPFConfig) (armresources.DeploymentExtended, error) {
+	if d.armConfig.SubscriptionScope {
+		poller, err := d.azAPIClient.DeploymentsClient.BeginCreateOrUpdateAtSubscriptionScope(d.ctx, deploymentName, deployment, nil)
+		if err != nil {
+			return armresources.DeploymentExtended{}, err
+		}
+		resp, err := poller.PollUntilDone(d.ctx, nil)
+		return resp.DeploymentExtended, err
+	}
+
+	poller, err := d.azAPIClient.DeploymentsClient.BeginCreateOrUpdate(d.ctx, mpfConfig.ResourceGroup.ResourceGroupName, deploymentName, deployment, nil)
+	if err != nil {
+		return armresources.DeploymentExtended{}, err
+	}
+	resp, err := poller.PollUntilDone(d.ctx, nil)
+	return resp.DeploymentExtended, err
+}
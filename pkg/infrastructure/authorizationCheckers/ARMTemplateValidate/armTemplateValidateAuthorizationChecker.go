@@ -0,0 +1,170 @@
+package ARMTemplateValidate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	URL "net/url"
+	"strings"
+	"time"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/ARMTemplateShared"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/azureAPI"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type armValidateConfig struct {
+	armConfig   ARMTemplateShared.ArmTemplateAdditionalConfig
+	azAPIClient *azureAPI.AzureAPIClients
+}
+
+// NewARMTemplateValidateAuthorizationChecker returns a checker which uses the deployment validate endpoint.
+// Validate runs the preflight checks, including RBAC checks, without creating resources and is faster than What-If
+func NewARMTemplateValidateAuthorizationChecker(subscriptionID string, armConfig ARMTemplateShared.ArmTemplateAdditionalConfig) *armValidateConfig {
+	azAPIClient := azureAPI.NewAzureAPIClients(subscriptionID)
+	return &armValidateConfig{
+		azAPIClient: azAPIClient,
+		armConfig:   armConfig,
+	}
+}
+
+func (a *armValidateConfig) GetDeploymentAuthorizationErrors(mpfConfig domain.MPFConfig) (string, error) {
+	return a.GetARMValidateAuthorizationErrors(a.armConfig.DeploymentName, mpfConfig)
+}
+
+func (a *armValidateConfig) CleanDeployment(mpfConfig domain.MPFConfig) error {
+	log.Infoln("No additional cleanup needed in Validate mode")
+	log.Infoln("*************************")
+
+	return nil
+}
+
+func (a *armValidateConfig) GetARMValidateAuthorizationErrors(deploymentName string, mpfConfig domain.MPFConfig) (string, error) {
+
+	bearerToken, err := a.azAPIClient.GetSPBearerToken(mpfConfig.TenantID, mpfConfig.SP.SPClientID, mpfConfig.SP.SPClientSecret)
+	if err != nil {
+		return "", fmt.Errorf("error getting bearer token: %w", err)
+	}
+
+	fullTemplateJSONString, err := ARMTemplateShared.GetDeploymentRequestBody(a.armConfig)
+	if err != nil {
+		return "", err
+	}
+
+	log.Debugln()
+	log.Debugln(fullTemplateJSONString)
+	log.Debugln()
+
+	client := &http.Client{}
+
+	deploymentID := ARMTemplateShared.GetDeploymentResourceID(a.armConfig, mpfConfig.SubscriptionID, mpfConfig.ResourceGroup.ResourceGroupName, deploymentName)
+	url := fmt.Sprintf("https://management.azure.com%s/validate?api-version=2021-04-01", deploymentID)
+
+	req, err := http.NewRequest("POST", url, bytes.NewBufferString(fullTemplateJSONString))
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Go HTTP Client")
+
+	// add bearer token to header
+	req.Header.Add("Authorization", "Bearer "+bearerToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	respBody := string(body)
+
+	// Large templates are validated asynchronously
+	if resp.StatusCode == http.StatusAccepted {
+		validateRespLoc := resp.Header.Get("Location")
+		log.Debugf("Validate response Location: %s \n", validateRespLoc)
+
+		_, err = URL.ParseRequestURI(validateRespLoc)
+		if err != nil {
+			return "", fmt.Errorf("Error parsing validate response location: %w", err)
+		}
+
+		respBody, err = a.GetValidateResp(validateRespLoc, bearerToken)
+		if err != nil {
+			return "", fmt.Errorf("Could not fetch validate response: %w", err)
+		}
+	}
+
+	log.Debugln(respBody)
+
+	return getAuthorizationErrorsFromValidateResponse(respBody)
+}
+
+func getAuthorizationErrorsFromValidateResponse(respBody string) (string, error) {
+	switch {
+	case strings.Contains(respBody, "InvalidTemplate") && !strings.Contains(respBody, "InvalidTemplateDeployment"):
+		// This indicates the ARM Template or Bicep File has issues.
+		return "", fmt.Errorf("%w: please check the template and parameters file: %s", ARMTemplateShared.ErrInvalidTemplate, respBody)
+	case strings.Contains(respBody, "Authorization"):
+		// This indicates Authorization errors occured
+		return respBody, nil
+	case strings.Contains(respBody, "InvalidTemplateDeployment"):
+		// This indicates all Authorization errors are fixed, and other preflight errors occured
+		// Hence ok to proceed, and not return error in this condition
+		log.Warnf("Post Authorizaton preflight error occured: %s", respBody)
+	}
+
+	return "", nil
+}
+
+func (a *armValidateConfig) GetValidateResp(validateRespLoc string, bearerToken string) (string, error) {
+
+	client := &http.Client{}
+
+	maxRetries := 50
+	for retryCount := 0; ; retryCount++ {
+		req, err := http.NewRequest("GET", validateRespLoc, nil)
+		if err != nil {
+			return "", err
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", "Go HTTP Client")
+
+		// add bearer token to header
+		req.Header.Add("Authorization", "Bearer "+bearerToken)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", err
+		}
+
+		// 202 indicates validation is still in progress
+		if resp.StatusCode != http.StatusAccepted {
+			log.Infoln("Validate Results Response Received..")
+			return string(body), nil
+		}
+
+		if retryCount == maxRetries {
+			return "", fmt.Errorf("Validate Results not available after %d retries", maxRetries)
+		}
+
+		log.Infoln("Validate still in progress, retrying in a bit...")
+		time.Sleep(500 * time.Millisecond)
+	}
+}
@@ -0,0 +1,53 @@
+package ARMTemplateValidate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/ARMTemplateShared"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAuthorizationErrorsFromValidateResponse(t *testing.T) {
+	tests := []struct {
+		name            string
+		respBody        string
+		wantAuthErrMesg bool
+		wantErr         error
+	}{
+		{
+			name:            "authorization error in preflight",
+			respBody:        `{"error":{"code":"InvalidTemplateDeployment","message":"The template deployment 'testDeploy' is not valid according to the validation procedure. See inner errors for details.","details":[{"code":"AuthorizationFailed","message":"The client 'XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX' with object id 'XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX' does not have authorization to perform action 'Microsoft.Network/virtualNetworks/write' over scope '/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Network/virtualNetworks/vnet1' or the scope is invalid."}]}}`,
+			wantAuthErrMesg: true,
+		},
+		{
+			name:     "invalid template",
+			respBody: `{"error":{"code":"InvalidTemplate","message":"Deployment template validation failed: 'The template parameters 'aksClusterName' in the parameters file are not valid'."}}`,
+			wantErr:  ARMTemplateShared.ErrInvalidTemplate,
+		},
+		{
+			name:     "non authorization preflight error",
+			respBody: `{"error":{"code":"InvalidTemplateDeployment","message":"The template deployment 'testDeploy' is not valid according to the validation procedure.","details":[{"code":"SkuNotAvailable","message":"The requested size is not available."}]}}`,
+		},
+		{
+			name:     "successful validation",
+			respBody: `{"id":"/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg/providers/Microsoft.Resources/deployments/testDeploy","properties":{"provisioningState":"Succeeded"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authErrMesg, err := getAuthorizationErrorsFromValidateResponse(tt.respBody)
+			if tt.wantErr != nil {
+				assert.True(t, errors.Is(err, tt.wantErr))
+				return
+			}
+			assert.Nil(t, err)
+			if tt.wantAuthErrMesg {
+				assert.Equal(t, tt.respBody, authErrMesg)
+			} else {
+				assert.Equal(t, "", authErrMesg)
+			}
+		})
+	}
+}
@@ -109,37 +109,11 @@ func (a *armWhatIfConfig) GetARMWhatIfAuthorizationErrors(deploymentName string,
 		return "", fmt.Errorf("error getting bearer token: %w", err)
 	}
 
-	// read template and parameters
-	template, err := mpfSharedUtils.ReadJson(a.armConfig.TemplateFilePath)
+	fullTemplateJSONString, err := ARMTemplateShared.GetDeploymentRequestBody(a.armConfig)
 	if err != nil {
-		// log.Errorf("Error reading template file: %v", err)
-		return "", fmt.Errorf("%w: %w", ARMTemplateShared.ErrInvalidTemplate, fmt.Errorf("error reading template file: %w", err))
-	}
-
-	parameters, err := mpfSharedUtils.ReadJson(a.armConfig.ParametersFilePath)
-	if err != nil {
-		return "", fmt.Errorf("%w: %w", ARMTemplateShared.ErrInvalidTemplate, fmt.Errorf("error reading parameters file: %w", err))
-	}
-
-	// convert parameters to standard format
-	parameters = ARMTemplateShared.GetParametersInStandardFormat(parameters)
-
-	fullTemplate := map[string]interface{}{
-		"properties": map[string]interface{}{
-			"mode":       "Incremental",
-			"template":   template,
-			"parameters": parameters,
-		},
-	}
-
-	// convert bodyJSON to string
-	fullTemplateJSONBytes, err := json.Marshal(fullTemplate)
-	if err != nil {
-		return "", fmt.Errorf("%w, %w", ARMTemplateShared.ErrInvalidTemplate, fmt.Errorf("error marshalling fullTemplateJSON: %w", err))
+		return "", err
 	}
 
-	fullTemplateJSONString := string(fullTemplateJSONBytes)
-
 	log.Debugln()
 	log.Debugln(fullTemplateJSONString)
 	log.Debugln()
@@ -147,7 +121,8 @@ func (a *armWhatIfConfig) GetARMWhatIfAuthorizationErrors(deploymentName string,
 
 	client := &http.Client{}
 
-	url := fmt.Sprintf("https://management.azure.com/subscriptions/%s/resourcegroups/%s/providers/Microsoft.Resources/deployments/%s/whatIf?api-version=2021-04-01", mpfConfig.SubscriptionID, mpfConfig.ResourceGroup.ResourceGroupName, deploymentName)
+	deploymentID := ARMTemplateShared.GetDeploymentResourceID(a.armConfig, mpfConfig.SubscriptionID, mpfConfig.ResourceGroup.ResourceGroupName, deploymentName)
+	url := fmt.Sprintf("https://management.azure.com%s/whatIf?api-version=2021-04-01", deploymentID)
 	reqMethod := "POST"
 
 	req, err := http.NewRequest(reqMethod, url, bytes.NewBufferString(fullTemplateJSONString))
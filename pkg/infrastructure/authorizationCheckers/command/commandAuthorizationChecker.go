@@ -0,0 +1,191 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+// The file of the Azure CLI configuration directory the client secret of the service principal is read from by az login
+const azLoginSecretFileName = "mpf-login-secret"
+
+type commandDeploymentConfig struct {
+	ctx             context.Context
+	command         []string
+	cleanupCommand  string
+	workingDir      string
+	azPath          string
+	azureConfigDir  string
+	loggedIn        bool
+	subscriptionSet bool
+}
+
+// NewCommandAuthorizationChecker returns the checker which runs the command, for example an Azure CLI or PowerShell script,
+// with the credentials of the service principal, and scans its output for authorization errors. The optional cleanup command
+// runs with the credentials MPF runs with, to delete the resources the command created
+func NewCommandAuthorizationChecker(command []string, cleanupCommand string, workingDir string, azPath string) *commandDeploymentConfig {
+	return &commandDeploymentConfig{
+		ctx:            context.Background(),
+		command:        command,
+		cleanupCommand: cleanupCommand,
+		workingDir:     workingDir,
+		azPath:         azPath,
+	}
+}
+
+func (a *commandDeploymentConfig) GetDeploymentAuthorizationErrors(mpfConfig domain.MPFConfig) (string, error) {
+	err := a.loginAzureCLI(mpfConfig)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.CommandContext(a.ctx, a.command[0], a.command[1:]...)
+	cmd.Env = a.getSPEnv(mpfConfig)
+	output, err := a.run(cmd)
+
+	if domain.IsAzureAuthorizationError(output) {
+		_, parseErr := domain.GetScopePermissionsFromAuthError(output)
+		if parseErr == nil {
+			log.Infoln("authorization error found in command output")
+			return output, nil
+		}
+		log.Warnf("could not parse authorization error of command output: %s", parseErr)
+	}
+
+	if err != nil {
+		log.Warnf("command: non authorizaton error occured: %s", output)
+		return output, err
+	}
+	return "", nil
+}
+
+// CleanDeployment runs the cleanup command, with the credentials MPF runs with
+func (a *commandDeploymentConfig) CleanDeployment(mpfConfig domain.MPFConfig) error {
+	if a.cleanupCommand == "" {
+		return nil
+	}
+
+	log.Infof("running cleanup command: %s", a.cleanupCommand)
+	cmd := exec.CommandContext(a.ctx, shell(), shellCommandFlag(), a.cleanupCommand)
+	cmd.Env = os.Environ()
+	output, err := a.run(cmd)
+	if err != nil {
+		log.Warnf("error running cleanup command: %s", output)
+		return err
+	}
+	return nil
+}
+
+// RemoveAzureConfigDir removes the Azure CLI configuration directory of the service principal, with the tokens of its login
+func (a *commandDeploymentConfig) RemoveAzureConfigDir() {
+	if a.azureConfigDir == "" {
+		return
+	}
+
+	err := os.RemoveAll(a.azureConfigDir)
+	if err != nil {
+		log.Warnf("error removing Azure CLI configuration directory %s: %s", a.azureConfigDir, err)
+	}
+	a.azureConfigDir = ""
+	a.loggedIn = false
+	a.subscriptionSet = false
+}
+
+// loginAzureCLI logs the service principal in to the Azure CLI once, in an Azure CLI configuration directory of its own,
+// so that the login of the Azure CLI MPF runs with is not used or changed. Without the Azure CLI the login is skipped
+func (a *commandDeploymentConfig) loginAzureCLI(mpfConfig domain.MPFConfig) error {
+	if a.loggedIn {
+		return a.setAzureCLISubscription(mpfConfig)
+	}
+
+	azPath, err := exec.LookPath(a.azPath)
+	if err != nil {
+		log.Warnf("Azure CLI %s not found, the command runs without an Azure CLI login: %s", a.azPath, err)
+		a.loggedIn = true
+		a.subscriptionSet = true
+		return nil
+	}
+	a.azPath = azPath
+
+	a.azureConfigDir, err = os.MkdirTemp("", "az-mpf-azure-config-")
+	if err != nil {
+		return err
+	}
+
+	// the secret is read by the Azure CLI from a file of the configuration directory, so that it is not on the command line
+	// of the login, which other processes can read
+	secretFilePath := filepath.Join(a.azureConfigDir, azLoginSecretFileName)
+	err = os.WriteFile(secretFilePath, []byte(mpfConfig.SP.SPClientSecret), 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(secretFilePath)
+
+	env := a.getSPEnv(mpfConfig)
+	login := exec.CommandContext(a.ctx, azPath, "login", "--service-principal", "--username", mpfConfig.SP.SPClientID, "--password", "@"+secretFilePath, "--tenant", mpfConfig.TenantID, "--allow-no-subscriptions", "--output", "none")
+	login.Env = env
+	output, err := a.run(login)
+	if err != nil {
+		return fmt.Errorf("error logging in the service principal to the Azure CLI: %w: %s", err, output)
+	}
+
+	log.Infoln("service principal logged in to the Azure CLI")
+	a.loggedIn = true
+	return a.setAzureCLISubscription(mpfConfig)
+}
+
+// setAzureCLISubscription sets the subscription of the Azure CLI login of the service principal. The service principal may have
+// no role assignment for the subscription yet, so the subscription is set again on the next check until it succeeds
+func (a *commandDeploymentConfig) setAzureCLISubscription(mpfConfig domain.MPFConfig) error {
+	if a.subscriptionSet {
+		return nil
+	}
+
+	setSubscription := exec.CommandContext(a.ctx, a.azPath, "account", "set", "--subscription", mpfConfig.SubscriptionID)
+	setSubscription.Env = a.getSPEnv(mpfConfig)
+	output, err := a.run(setSubscription)
+	if err != nil {
+		log.Warnf("could not set the subscription of the Azure CLI login, retrying on the next check: %s", output)
+		return nil
+	}
+	a.subscriptionSet = true
+	return nil
+}
+
+// run runs the command in the working directory, and returns its combined standard output and error
+func (a *commandDeploymentConfig) run(cmd *exec.Cmd) (string, error) {
+	cmd.Dir = a.workingDir
+
+	output := &bytes.Buffer{}
+	var w io.Writer = output
+	if log.IsLevelEnabled(log.InfoLevel) {
+		w = io.MultiWriter(output, os.Stderr)
+	}
+	cmd.Stdout = w
+	cmd.Stderr = w
+
+	err := cmd.Run()
+	return output.String(), err
+}
+
+func shell() string {
+	if runtime.GOOS == "windows" {
+		return "cmd"
+	}
+	return "sh"
+}
+
+func shellCommandFlag() string {
+	if runtime.GOOS == "windows" {
+		return "/C"
+	}
+	return "-c"
+}
@@ -0,0 +1,159 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+const azCLIAuthorizationError = `ERROR: (AuthorizationFailed) The client 'ddfcf162-2cf2-40cf-bd4a-49a63e248436' with object id 'ddfcf162-2cf2-40cf-bd4a-49a63e248436' does not have authorization to perform action 'Microsoft.Network/virtualNetworks/write' over scope '/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/rg-mpf/providers/Microsoft.Network/virtualNetworks/vnet-mpf' or the scope is invalid. If access was recently granted, please refresh your credentials.
+Code: AuthorizationFailed`
+
+var testMPFConfig = domain.MPFConfig{
+	SubscriptionID: "SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS",
+	TenantID:       "TTTTTTTT-TTTT-TTTT-TTTT-TTTTTTTTTTTT",
+	SP:             domain.ServicePrincipal{SPClientID: "sp-client-id", SPClientSecret: "sp-client-secret"},
+}
+
+func getTestChecker(t *testing.T, script string, cleanupCommand string) *commandDeploymentConfig {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test commands are shell scripts")
+	}
+
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "deploy.sh")
+	assert.Nil(t, os.WriteFile(scriptPath, []byte(script), 0700))
+	errorPath := filepath.Join(dir, "error.txt")
+	assert.Nil(t, os.WriteFile(errorPath, []byte(azCLIAuthorizationError), 0600))
+
+	// the Azure CLI login is skipped when the Azure CLI is not found
+	return NewCommandAuthorizationChecker([]string{scriptPath, errorPath}, cleanupCommand, dir, filepath.Join(dir, "az"))
+}
+
+func TestGetDeploymentAuthorizationErrors(t *testing.T) {
+	checker := getTestChecker(t, "#!/bin/sh\ncat \"$1\" >&2\nexit 1\n", "")
+
+	authErrMesg, err := checker.GetDeploymentAuthorizationErrors(testMPFConfig)
+	assert.Nil(t, err)
+	scopePermissions, err := domain.GetScopePermissionsFromAuthError(authErrMesg)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/write"}, scopePermissions["/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/rg-mpf/providers/Microsoft.Network/virtualNetworks/vnet-mpf"])
+}
+
+func TestGetDeploymentAuthorizationErrorsOfSucceededCommand(t *testing.T) {
+	// scripts which continue on errors succeed with authorization errors in their output
+	checker := getTestChecker(t, "#!/bin/sh\ncat \"$1\"\nexit 0\n", "")
+	authErrMesg, err := checker.GetDeploymentAuthorizationErrors(testMPFConfig)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(authErrMesg, "AuthorizationFailed"))
+
+	checker = getTestChecker(t, "#!/bin/sh\necho \"$ARM_CLIENT_ID $AZURE_CLIENT_ID\"\nexit 0\n", "")
+	authErrMesg, err = checker.GetDeploymentAuthorizationErrors(testMPFConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, "", authErrMesg)
+}
+
+func TestGetDeploymentAuthorizationErrorsOfFailedCommand(t *testing.T) {
+	checker := getTestChecker(t, "#!/bin/sh\necho \"ERROR: (ResourceGroupNotFound) Resource group 'rg-mpf' could not be found.\" >&2\nexit 3\n", "")
+
+	authErrMesg, err := checker.GetDeploymentAuthorizationErrors(testMPFConfig)
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(authErrMesg, "ResourceGroupNotFound"))
+}
+
+func TestCleanDeployment(t *testing.T) {
+	checker := getTestChecker(t, "#!/bin/sh\nexit 0\n", "touch cleaned")
+	assert.Nil(t, checker.CleanDeployment(testMPFConfig))
+	_, err := os.Stat(filepath.Join(checker.workingDir, "cleaned"))
+	assert.Nil(t, err)
+
+	checker = getTestChecker(t, "#!/bin/sh\nexit 0\n", "exit 1")
+	assert.NotNil(t, checker.CleanDeployment(testMPFConfig))
+}
+
+// fakeAzScript records the arguments of az login, and the secret of the file of its password argument. az account set
+// fails while the file fail-account-set exists
+const fakeAzScript = `#!/bin/sh
+if [ "$1" = "account" ] && [ -f "$FAKE_AZ_DIR/fail-account-set" ]; then
+  exit 1
+fi
+if [ "$1" = "login" ]; then
+  echo "$@" >> "$FAKE_AZ_DIR/logins.txt"
+  echo "$@" > "$FAKE_AZ_DIR/args.txt"
+  while [ $# -gt 0 ]; do
+    if [ "$1" = "--password" ]; then
+      cat "${2#@}" > "$FAKE_AZ_DIR/secret.txt"
+    fi
+    shift
+  done
+fi
+exit 0
+`
+
+func TestLoginAzureCLIWithoutSecretOnCommandLine(t *testing.T) {
+	checker := getTestChecker(t, "#!/bin/sh\nexit 0\n", "")
+	azDir := t.TempDir()
+	checker.azPath = filepath.Join(azDir, "az")
+	assert.Nil(t, os.WriteFile(checker.azPath, []byte(fakeAzScript), 0700))
+	t.Setenv("FAKE_AZ_DIR", azDir)
+
+	err := checker.loginAzureCLI(testMPFConfig)
+	assert.Nil(t, err)
+	defer checker.RemoveAzureConfigDir()
+
+	args, err := os.ReadFile(filepath.Join(azDir, "args.txt"))
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(string(args), "sp-client-secret"))
+	secret, err := os.ReadFile(filepath.Join(azDir, "secret.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "sp-client-secret", string(secret))
+
+	// the secret file is removed once the service principal is logged in
+	assert.NoFileExists(t, filepath.Join(checker.azureConfigDir, azLoginSecretFileName))
+}
+
+func TestLoginAzureCLIRetriesFailedSubscriptionSet(t *testing.T) {
+	checker := getTestChecker(t, "#!/bin/sh\nexit 0\n", "")
+	azDir := t.TempDir()
+	checker.azPath = filepath.Join(azDir, "az")
+	assert.Nil(t, os.WriteFile(checker.azPath, []byte(fakeAzScript), 0700))
+	assert.Nil(t, os.WriteFile(filepath.Join(azDir, "fail-account-set"), nil, 0600))
+	t.Setenv("FAKE_AZ_DIR", azDir)
+
+	err := checker.loginAzureCLI(testMPFConfig)
+	assert.Nil(t, err)
+	defer checker.RemoveAzureConfigDir()
+	assert.True(t, checker.loggedIn)
+	assert.False(t, checker.subscriptionSet)
+
+	// the next check sets the subscription again, without logging in again
+	assert.Nil(t, os.Remove(filepath.Join(azDir, "fail-account-set")))
+	err = checker.loginAzureCLI(testMPFConfig)
+	assert.Nil(t, err)
+	assert.True(t, checker.subscriptionSet)
+
+	logins, err := os.ReadFile(filepath.Join(azDir, "logins.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(string(logins), "\n"))
+}
+
+func TestGetSPEnv(t *testing.T) {
+	t.Setenv("ARM_USE_MSI", "true")
+	t.Setenv("AZURE_CONFIG_DIR", "/home/mpf/.azure")
+
+	checker := &commandDeploymentConfig{azureConfigDir: "/tmp/az-mpf-azure-config"}
+	env := checker.getSPEnv(testMPFConfig)
+
+	assert.Contains(t, env, "ARM_CLIENT_ID=sp-client-id")
+	assert.Contains(t, env, "AZURE_CLIENT_SECRET=sp-client-secret")
+	assert.Contains(t, env, "AZURE_SUBSCRIPTION_ID=SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS")
+	assert.Contains(t, env, "AZURE_TENANT_ID=TTTTTTTT-TTTT-TTTT-TTTT-TTTTTTTTTTTT")
+	assert.Contains(t, env, "AZURE_CONFIG_DIR=/tmp/az-mpf-azure-config")
+	assert.NotContains(t, env, "AZURE_CONFIG_DIR=/home/mpf/.azure")
+	assert.NotContains(t, env, "ARM_USE_MSI=true")
+}
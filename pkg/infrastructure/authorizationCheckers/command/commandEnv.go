@@ -0,0 +1,48 @@
+package command
+
+import (
+	"os"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+)
+
+// The prefixes of the environment variables of Azure credentials, which are not passed to the command, so that the tools it runs
+// can only authenticate as the service principal
+var azureCredentialEnvPrefixes = []string{"ARM_", "AZURE_"}
+
+// getSPEnv returns the environment MPF runs in without Azure credentials, with the credentials of the service principal in the
+// ARM_* environment variables of Terraform and Pulumi, the AZURE_* environment variables of the Azure SDKs and Azure PowerShell,
+// and the Azure CLI configuration directory the service principal is logged in to
+func (a *commandDeploymentConfig) getSPEnv(mpfConfig domain.MPFConfig) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if !hasAzureCredentialEnvPrefix(kv) {
+			env = append(env, kv)
+		}
+	}
+
+	env = append(env,
+		"ARM_CLIENT_ID="+mpfConfig.SP.SPClientID,
+		"ARM_CLIENT_SECRET="+mpfConfig.SP.SPClientSecret,
+		"ARM_SUBSCRIPTION_ID="+mpfConfig.SubscriptionID,
+		"ARM_TENANT_ID="+mpfConfig.TenantID,
+		"AZURE_CLIENT_ID="+mpfConfig.SP.SPClientID,
+		"AZURE_CLIENT_SECRET="+mpfConfig.SP.SPClientSecret,
+		"AZURE_SUBSCRIPTION_ID="+mpfConfig.SubscriptionID,
+		"AZURE_TENANT_ID="+mpfConfig.TenantID,
+	)
+	if a.azureConfigDir != "" {
+		env = append(env, "AZURE_CONFIG_DIR="+a.azureConfigDir)
+	}
+	return env
+}
+
+func hasAzureCredentialEnvPrefix(kv string) bool {
+	for _, prefix := range azureCredentialEnvPrefixes {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+	return false
+}
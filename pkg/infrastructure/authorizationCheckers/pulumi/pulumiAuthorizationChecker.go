@@ -0,0 +1,266 @@
+package pulumi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
+	log "github.com/sirupsen/logrus"
+)
+
+// The pulumi phases in which permissions are discovered. The up phase is required, the destroy phase can be selected
+const (
+	PulumiPhaseUp      = "up"
+	PulumiPhaseDestroy = "destroy"
+	PulumiPhaseDone    = "done"
+)
+
+const (
+	PulumiEventLogFileName = "pulumiEvents.json"
+	pulumiBackendDirName   = "backend"
+)
+
+type pulumiDeploymentConfig struct {
+	ctx          context.Context
+	workingDir   string
+	execPath     string
+	stackName    string
+	config       []string
+	destroyPhase bool
+	phase        string
+	runDir       *mpfSharedUtils.RunDir
+	backendDir   string
+}
+
+// NewPulumiAuthorizationChecker returns the checker which runs pulumi up and destroy for the stack of the project in the working
+// directory, with the credentials of the service principal. The stack is kept in a local file backend in the run directory of MPF,
+// so that the backend of the project is not used. The stack configuration file, which pulumi writes into the project directory,
+// is removed with the stack, so the stack must not have a configuration file in the project
+func NewPulumiAuthorizationChecker(workDir string, runDir *mpfSharedUtils.RunDir, execPath string, stackName string) *pulumiDeploymentConfig {
+	if configFilePath := GetStackConfigFilePath(workDir, stackName); configFilePath != "" {
+		log.Fatalf("the configuration file %s of pulumi stack %s exists in the project directory, and would be overwritten and removed by MPF. Use another stack name, or remove the file if it was left by an interrupted MPF run", configFilePath, stackName)
+	}
+
+	backendDir := filepath.Join(runDir.Path, pulumiBackendDirName)
+	err := os.MkdirAll(backendDir, 0700)
+	if err != nil {
+		log.Fatalf("error creating pulumi local backend directory: %s", err)
+	}
+
+	return &pulumiDeploymentConfig{
+		ctx:          context.Background(),
+		workingDir:   workDir,
+		execPath:     execPath,
+		stackName:    stackName,
+		destroyPhase: true,
+		phase:        PulumiPhaseUp,
+		runDir:       runDir,
+		backendDir:   backendDir,
+	}
+}
+
+// GetStackConfigFilePath returns the path of the stack configuration file of the stack in the project directory,
+// Pulumi.<stack>.yaml, or an empty string if the project has no configuration file for the stack
+func GetStackConfigFilePath(workingDir string, stackName string) string {
+	// the configuration file of a fully qualified stack name, org/project/stack, is named after the stack
+	stackName = stackName[strings.LastIndex(stackName, "/")+1:]
+	for _, ext := range []string{".yaml", ".yml"} {
+		configFilePath := filepath.Join(workingDir, "Pulumi."+stackName+ext)
+		if _, err := os.Stat(configFilePath); err == nil {
+			return configFilePath
+		}
+	}
+	return ""
+}
+
+// SetConfig sets the configuration of the stack as key=value, which is passed to pulumi up with --config
+func (a *pulumiDeploymentConfig) SetConfig(config []string) error {
+	for _, kv := range config {
+		key, _, found := strings.Cut(kv, "=")
+		if !found || key == "" {
+			return fmt.Errorf("invalid pulumi config %s, the format is key=value", kv)
+		}
+	}
+	a.config = config
+	return nil
+}
+
+// SetPhases selects the phases in which permissions are discovered, up and optionally destroy.
+// When the destroy phase is not selected, the resources are only destroyed when the deployment is cleaned up
+func (a *pulumiDeploymentConfig) SetPhases(phases []string) error {
+	var upPhase bool
+	a.destroyPhase = false
+	for _, phase := range phases {
+		switch strings.ToLower(phase) {
+		case PulumiPhaseUp:
+			upPhase = true
+		case PulumiPhaseDestroy:
+			a.destroyPhase = true
+		default:
+			return fmt.Errorf("invalid pulumi phase: %s, valid phases are %s and %s", phase, PulumiPhaseUp, PulumiPhaseDestroy)
+		}
+	}
+	if !upPhase {
+		return fmt.Errorf("pulumi phase %s is required", PulumiPhaseUp)
+	}
+	return nil
+}
+
+// GetCurrentStage returns the pulumi phase of the last authorization error
+func (a *pulumiDeploymentConfig) GetCurrentStage() string {
+	return a.phase
+}
+
+// GetStages returns the pulumi phases in the order they run
+func (a *pulumiDeploymentConfig) GetStages() []string {
+	if a.destroyPhase {
+		return []string{PulumiPhaseUp, PulumiPhaseDestroy}
+	}
+	return []string{PulumiPhaseUp}
+}
+
+func (a *pulumiDeploymentConfig) GetDeploymentAuthorizationErrors(mpfConfig domain.MPFConfig) (string, error) {
+	env := a.getSPEnv(mpfConfig)
+
+	_, err := a.runPulumi(env, "stack", "select", a.stackName, "--create", "--secrets-provider", "passphrase")
+	if err != nil {
+		return "", fmt.Errorf("error selecting pulumi stack %s: %w", a.stackName, err)
+	}
+
+	if a.phase == PulumiPhaseUp {
+		log.Infoln("in up phase")
+		msg, err := a.runPulumiWithEventLog(env, a.upArgs()...)
+		if err != nil || msg != "" {
+			return msg, err
+		}
+
+		if !a.destroyPhase {
+			log.Infoln("destroy phase is not selected, skipping pulumi destroy")
+			a.phase = PulumiPhaseDone
+			return "", nil
+		}
+		a.phase = PulumiPhaseDestroy
+	}
+
+	if a.phase == PulumiPhaseDestroy {
+		log.Infoln("in destroy phase")
+		msg, err := a.runPulumiWithEventLog(env, a.destroyArgs()...)
+		if err != nil || msg != "" {
+			return msg, err
+		}
+		a.phase = PulumiPhaseDone
+	}
+	return "", nil
+}
+
+// CleanDeployment destroys the resources of the stack with the credentials MPF runs with, and removes the stack from the
+// local file backend, so that the next check starts with a fresh stack
+func (a *pulumiDeploymentConfig) CleanDeployment(mpfConfig domain.MPFConfig) error {
+	a.phase = PulumiPhaseUp
+	env := a.getAdminEnv()
+
+	_, err := a.runPulumi(env, "stack", "select", a.stackName)
+	if err != nil {
+		log.Debugf("pulumi stack %s does not exist, nothing to clean up: %s", a.stackName, err)
+		return nil
+	}
+
+	_, err = a.runPulumi(env, a.destroyArgs()...)
+	if err != nil {
+		log.Warnf("error running pulumi destroy: %s", err)
+		return err
+	}
+
+	_, err = a.runPulumi(env, "stack", "rm", a.stackName, "--yes", "--force")
+	if err != nil {
+		log.Warnf("error removing pulumi stack: %s", err)
+	}
+	return err
+}
+
+func (a *pulumiDeploymentConfig) upArgs() []string {
+	args := []string{"up", "--yes", "--skip-preview"}
+	for _, kv := range a.config {
+		args = append(args, "--config", kv)
+	}
+	return args
+}
+
+func (a *pulumiDeploymentConfig) destroyArgs() []string {
+	return []string{"destroy", "--yes", "--skip-preview"}
+}
+
+// runPulumiWithEventLog runs the pulumi command with an engine event log, and returns the authorization errors of the
+// error diagnostics of the events
+func (a *pulumiDeploymentConfig) runPulumiWithEventLog(env []string, args ...string) (string, error) {
+	eventLogPath := filepath.Join(a.runDir.Path, PulumiEventLogFileName)
+	defer os.Remove(eventLogPath)
+
+	output, err := a.runPulumi(env, append(args, "--event-log", eventLogPath)...)
+	if err == nil {
+		return "", nil
+	}
+
+	diagnostics, parseErr := a.getDiagnostics(eventLogPath)
+	if parseErr != nil || len(diagnostics) == 0 {
+		log.Warnf("pulumi %s: no error diagnostics found in engine events: %v", args[0], parseErr)
+		return output, err
+	}
+
+	errorMsg := formatDiagnostics(diagnostics)
+	log.Debugf("pulumi %s error: %s", args[0], errorMsg)
+
+	if authDiagnostics := filterDiagnostics(diagnostics, "Authorization"); len(authDiagnostics) > 0 {
+		return formatDiagnostics(authDiagnostics), nil
+	}
+
+	log.Warnf("pulumi %s: non authorizaton error occured: %s", args[0], errorMsg)
+	return errorMsg, err
+}
+
+func (a *pulumiDeploymentConfig) getDiagnostics(eventLogPath string) ([]PulumiDiagnostic, error) {
+	eventLog, err := os.Open(eventLogPath)
+	if err != nil {
+		return nil, err
+	}
+	defer eventLog.Close()
+
+	return ParsePulumiEngineEvents(eventLog)
+}
+
+// runPulumi runs the pulumi command for the stack in the working directory, and returns its error output
+func (a *pulumiDeploymentConfig) runPulumi(env []string, args ...string) (string, error) {
+	args = append(args, "--non-interactive")
+	if args[0] != "stack" {
+		args = append(args, "--stack", a.stackName)
+	}
+	log.Debugf("running pulumi %s", strings.Join(args, " "))
+
+	cmd := exec.CommandContext(a.ctx, a.execPath, args...)
+	cmd.Dir = a.workingDir
+	cmd.Env = env
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	cmd.Stdout = io.Discard
+	if log.IsLevelEnabled(log.InfoLevel) {
+		cmd.Stderr = io.MultiWriter(stderr, os.Stderr)
+	}
+	// the output of pulumi is only shown at debug level, and on stderr, so that it is not mixed with the output of MPF
+	if log.IsLevelEnabled(log.DebugLevel) {
+		cmd.Stdout = os.Stderr
+	}
+
+	err := cmd.Run()
+	if err != nil {
+		return stderr.String(), fmt.Errorf("pulumi %s: %w: %s", args[0], err, stderr.String())
+	}
+	return stderr.String(), nil
+}
@@ -0,0 +1,132 @@
+package pulumi
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetPhases(t *testing.T) {
+	checker := &pulumiDeploymentConfig{destroyPhase: true}
+
+	err := checker.SetPhases([]string{"up"})
+	assert.Nil(t, err)
+	assert.False(t, checker.destroyPhase)
+	assert.Equal(t, []string{PulumiPhaseUp}, checker.GetStages())
+
+	err = checker.SetPhases([]string{"Up", "destroy"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{PulumiPhaseUp, PulumiPhaseDestroy}, checker.GetStages())
+
+	assert.NotNil(t, checker.SetPhases([]string{"destroy"}))
+	assert.NotNil(t, checker.SetPhases([]string{"up", "refresh"}))
+}
+
+func TestSetConfig(t *testing.T) {
+	checker := &pulumiDeploymentConfig{}
+
+	assert.Nil(t, checker.SetConfig([]string{"azure-native:location=eastus2", "vnet:addressSpace=10.0.0.0/16"}))
+	assert.Equal(t, []string{"up", "--yes", "--skip-preview", "--config", "azure-native:location=eastus2", "--config", "vnet:addressSpace=10.0.0.0/16"}, checker.upArgs())
+
+	assert.NotNil(t, checker.SetConfig([]string{"location"}))
+	assert.NotNil(t, checker.SetConfig([]string{"=eastus2"}))
+}
+
+func TestGetSPEnv(t *testing.T) {
+	t.Setenv("ARM_USE_MSI", "true")
+	t.Setenv("AZURE_CLIENT_ID", "mpf-admin")
+	t.Setenv(pulumiBackendURLEnvVar, "https://api.pulumi.com")
+	t.Setenv(pulumiConfigPassphraseEnvVar, "")
+	os.Unsetenv(pulumiConfigPassphraseEnvVar)
+
+	checker := &pulumiDeploymentConfig{backendDir: "/tmp/mpf/backend"}
+	env := checker.getSPEnv(domain.MPFConfig{
+		SubscriptionID: "SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS",
+		TenantID:       "TTTTTTTT-TTTT-TTTT-TTTT-TTTTTTTTTTTT",
+		SP:             domain.ServicePrincipal{SPClientID: "sp-client-id", SPClientSecret: "sp-client-secret"},
+	})
+
+	assert.Contains(t, env, "ARM_CLIENT_ID=sp-client-id")
+	assert.Contains(t, env, "ARM_CLIENT_SECRET=sp-client-secret")
+	assert.Contains(t, env, "ARM_SUBSCRIPTION_ID=SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS")
+	assert.Contains(t, env, "ARM_TENANT_ID=TTTTTTTT-TTTT-TTTT-TTTT-TTTTTTTTTTTT")
+	assert.NotContains(t, env, "ARM_USE_MSI=true")
+	assert.NotContains(t, env, "AZURE_CLIENT_ID=mpf-admin")
+	assert.Contains(t, env, "PULUMI_BACKEND_URL=file:///tmp/mpf/backend")
+	assert.NotContains(t, env, "PULUMI_BACKEND_URL=https://api.pulumi.com")
+	assert.Contains(t, env, "PULUMI_CONFIG_PASSPHRASE=")
+}
+
+// fakePulumiScript writes the engine events of a failed update to the event log for pulumi up, and succeeds for other commands
+const fakePulumiScript = `#!/bin/sh
+if [ "$1" != "up" ]; then
+  exit 0
+fi
+while [ "$#" -gt 0 ]; do
+  if [ "$1" = "--event-log" ]; then
+    cp "$FAKE_PULUMI_EVENTS" "$2"
+  fi
+  shift
+done
+echo "error: update failed" >&2
+exit 255
+`
+
+func TestGetDeploymentAuthorizationErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake pulumi executable is a shell script")
+	}
+
+	dir := t.TempDir()
+	execPath := filepath.Join(dir, "pulumi")
+	assert.Nil(t, os.WriteFile(execPath, []byte(fakePulumiScript), 0700))
+	eventsPath := filepath.Join(dir, "events.json")
+	assert.Nil(t, os.WriteFile(eventsPath, []byte(pulumiUpEventLog), 0600))
+	t.Setenv("FAKE_PULUMI_EVENTS", eventsPath)
+
+	checker := &pulumiDeploymentConfig{
+		ctx:          context.Background(),
+		workingDir:   dir,
+		execPath:     execPath,
+		stackName:    "mpf",
+		destroyPhase: true,
+		phase:        PulumiPhaseUp,
+		runDir:       &mpfSharedUtils.RunDir{Path: dir},
+		backendDir:   filepath.Join(dir, pulumiBackendDirName),
+	}
+
+	authErrMesg, err := checker.GetDeploymentAuthorizationErrors(domain.MPFConfig{})
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(authErrMesg, "Microsoft.Network/virtualNetworks/write"))
+	assert.Equal(t, PulumiPhaseUp, checker.GetCurrentStage())
+
+	// once pulumi up succeeds, the destroy phase runs
+	assert.Nil(t, os.WriteFile(eventsPath, []byte{}, 0600))
+	assert.Nil(t, os.WriteFile(execPath, []byte("#!/bin/sh\nexit 0\n"), 0700))
+	authErrMesg, err = checker.GetDeploymentAuthorizationErrors(domain.MPFConfig{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", authErrMesg)
+	assert.Equal(t, PulumiPhaseDone, checker.GetCurrentStage())
+
+	assert.Nil(t, checker.CleanDeployment(domain.MPFConfig{}))
+	assert.Equal(t, PulumiPhaseUp, checker.GetCurrentStage())
+}
+
+func TestGetStackConfigFilePath(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, "", GetStackConfigFilePath(dir, "dev"))
+
+	// the configuration file of the stack of the project is not overwritten and removed by MPF
+	configFilePath := filepath.Join(dir, "Pulumi.dev.yaml")
+	assert.Nil(t, os.WriteFile(configFilePath, []byte("config:\n  azure-native:location: westeurope\n"), 0600))
+	assert.Equal(t, configFilePath, GetStackConfigFilePath(dir, "dev"))
+	assert.Equal(t, configFilePath, GetStackConfigFilePath(dir, "org/project/dev"))
+	assert.Equal(t, "", GetStackConfigFilePath(dir, "mpf"))
+}
@@ -0,0 +1,78 @@
+package pulumi
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// PulumiDiagnostic is an error diagnostic of the engine events of pulumi, with the URN of the resource it is reported for
+type PulumiDiagnostic struct {
+	URN      string `json:"urn"`
+	Message  string `json:"message"`
+	Severity string `json:"severity"`
+}
+
+type pulumiEngineEvent struct {
+	Sequence        int               `json:"sequence"`
+	DiagnosticEvent *PulumiDiagnostic `json:"diagnosticEvent"`
+}
+
+// The color directives pulumi embeds in the messages of the engine events
+var pulumiColorDirectiveRegex = regexp.MustCompile(`<\{%[^%]*%\}>`)
+
+// ParsePulumiEngineEvents returns the error diagnostics of the engine events JSON lines pulumi writes with --event-log,
+// lines which are not engine events are skipped
+func ParsePulumiEngineEvents(r io.Reader) ([]PulumiDiagnostic, error) {
+	var diagnostics []PulumiDiagnostic
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var event pulumiEngineEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			log.Debugf("skipping pulumi event log line which is not an engine event: %s\n", scanner.Text())
+			continue
+		}
+
+		if event.DiagnosticEvent != nil && event.DiagnosticEvent.Severity == "error" {
+			diagnostic := *event.DiagnosticEvent
+			diagnostic.Message = strings.TrimSpace(pulumiColorDirectiveRegex.ReplaceAllString(diagnostic.Message, ""))
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	return diagnostics, scanner.Err()
+}
+
+// String formats the diagnostic like the terraform diagnostics, so that it can be parsed by the authorization error parsers
+func (d PulumiDiagnostic) String() string {
+	var sb strings.Builder
+	sb.WriteString("Error: " + d.Message + "\n\n")
+	if d.URN != "" {
+		sb.WriteString("  with " + d.URN + ",\n\n")
+	}
+	return sb.String()
+}
+
+// filterDiagnostics returns the diagnostics which contain the text in the message
+func filterDiagnostics(diagnostics []PulumiDiagnostic, substr string) []PulumiDiagnostic {
+	var filtered []PulumiDiagnostic
+	for _, diagnostic := range diagnostics {
+		if strings.Contains(diagnostic.Message, substr) {
+			filtered = append(filtered, diagnostic)
+		}
+	}
+	return filtered
+}
+
+func formatDiagnostics(diagnostics []PulumiDiagnostic) string {
+	var sb strings.Builder
+	for _, diagnostic := range diagnostics {
+		sb.WriteString(diagnostic.String())
+	}
+	return sb.String()
+}
@@ -0,0 +1,32 @@
+package pulumi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+const pulumiUpEventLog = `{"sequence":0,"timestamp":1729339200,"cancelEvent":null,"stdoutEvent":null,"preludeEvent":{"config":{}}}
+{"sequence":1,"timestamp":1729339201,"resourcePreEvent":{"metadata":{"op":"create","urn":"urn:pulumi:mpf::vnet::azure-native:network:VirtualNetwork::vnet","type":"azure-native:network:VirtualNetwork"}}}
+{"sequence":2,"timestamp":1729339205,"diagnosticEvent":{"urn":"urn:pulumi:mpf::vnet::azure-native:network:VirtualNetwork::vnet","prefix":"<{%fg 1%}>error: <{%reset%}>","message":"<{%reset%}>Status=403 Code=\"AuthorizationFailed\" Message=\"The client 'ddfcf162-2cf2-40cf-bd4a-49a63e248436' with object id 'ddfcf162-2cf2-40cf-bd4a-49a63e248436' does not have authorization to perform action 'Microsoft.Network/virtualNetworks/write' over scope '/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/rg-mpf/providers/Microsoft.Network/virtualNetworks/vnet-mpf' or the scope is invalid. If access was recently granted, please refresh your credentials.\"<{%reset%}>\n","color":"raw","severity":"error"}}
+{"sequence":3,"timestamp":1729339205,"diagnosticEvent":{"urn":"urn:pulumi:mpf::vnet::pulumi:pulumi:Stack::vnet-mpf","message":"update failed","color":"raw","severity":"error"}}
+{"sequence":4,"timestamp":1729339205,"diagnosticEvent":{"urn":"urn:pulumi:mpf::vnet::pulumi:pulumi:Stack::vnet-mpf","message":"deprecated property","color":"raw","severity":"warning"}}
+not an engine event
+`
+
+func TestParsePulumiEngineEvents(t *testing.T) {
+	diagnostics, err := ParsePulumiEngineEvents(strings.NewReader(pulumiUpEventLog))
+	assert.Nil(t, err)
+	assert.Len(t, diagnostics, 2)
+	assert.Equal(t, "urn:pulumi:mpf::vnet::azure-native:network:VirtualNetwork::vnet", diagnostics[0].URN)
+	assert.False(t, strings.Contains(diagnostics[0].Message, "<{%"))
+
+	authDiagnostics := filterDiagnostics(diagnostics, "Authorization")
+	assert.Len(t, authDiagnostics, 1)
+
+	scopePermissions, err := domain.GetScopePermissionsFromAuthError(formatDiagnostics(authDiagnostics))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/write"}, scopePermissions["/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/rg-mpf/providers/Microsoft.Network/virtualNetworks/vnet-mpf"])
+}
@@ -0,0 +1,82 @@
+package pulumi
+
+import (
+	"os"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+)
+
+const (
+	pulumiBackendURLEnvVar           = "PULUMI_BACKEND_URL"
+	pulumiConfigPassphraseEnvVar     = "PULUMI_CONFIG_PASSPHRASE"
+	pulumiConfigPassphraseFileEnvVar = "PULUMI_CONFIG_PASSPHRASE_FILE"
+	pulumiSkipUpdateCheckEnvVar      = "PULUMI_SKIP_UPDATE_CHECK"
+)
+
+// The prefixes of the environment variables of Azure credentials, which are not passed to pulumi when it runs with the
+// credentials of the service principal, so that the azure-native provider can only authenticate as the service principal
+var azureCredentialEnvPrefixes = []string{"ARM_", "AZURE_"}
+
+// getAdminEnv returns the environment MPF runs in, with the local file backend of the run, for the pulumi commands
+// run with the credentials MPF runs with
+func (a *pulumiDeploymentConfig) getAdminEnv() []string {
+	return a.withBackendEnv(os.Environ())
+}
+
+// getSPEnv returns the environment MPF runs in without Azure credentials, with the credentials of the service principal
+// in the ARM_* environment variables the azure-native provider reads, and the local file backend of the run
+func (a *pulumiDeploymentConfig) getSPEnv(mpfConfig domain.MPFConfig) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if !hasAzureCredentialEnvPrefix(kv) {
+			env = append(env, kv)
+		}
+	}
+
+	env = append(env,
+		"ARM_CLIENT_ID="+mpfConfig.SP.SPClientID,
+		"ARM_CLIENT_SECRET="+mpfConfig.SP.SPClientSecret,
+		"ARM_SUBSCRIPTION_ID="+mpfConfig.SubscriptionID,
+		"ARM_TENANT_ID="+mpfConfig.TenantID,
+	)
+	return a.withBackendEnv(env)
+}
+
+// withBackendEnv adds the local file backend of the run to the environment, with an empty passphrase for the secrets of the
+// stack when no passphrase is set, as the stack only exists for the run
+func (a *pulumiDeploymentConfig) withBackendEnv(env []string) []string {
+	env = append(removeEnv(env, pulumiBackendURLEnvVar), pulumiBackendURLEnvVar+"=file://"+a.backendDir, pulumiSkipUpdateCheckEnvVar+"=true")
+	if !hasEnv(env, pulumiConfigPassphraseEnvVar) && !hasEnv(env, pulumiConfigPassphraseFileEnvVar) {
+		env = append(env, pulumiConfigPassphraseEnvVar+"=")
+	}
+	return env
+}
+
+func hasAzureCredentialEnvPrefix(kv string) bool {
+	for _, prefix := range azureCredentialEnvPrefixes {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasEnv(env []string, name string) bool {
+	for _, kv := range env {
+		if strings.HasPrefix(kv, name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+func removeEnv(env []string, name string) []string {
+	var filtered []string
+	for _, kv := range env {
+		if !strings.HasPrefix(kv, name+"=") {
+			filtered = append(filtered, kv)
+		}
+	}
+	return filtered
+}
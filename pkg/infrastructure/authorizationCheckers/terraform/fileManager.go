@@ -54,18 +54,6 @@ func DeleteTFFile(workingDir string, fileName string) error {
 	return nil
 }
 
-func doesEnteredDestroyPhaseStateFileExist(workingDir string, fileName string) bool {
-	return DoesTFFileExist(workingDir, fileName)
-}
-
-func createEnteredDestroyPhaseStateFile(workingDir string, fileName string) error {
-	return CreateTFFile(workingDir, fileName)
-}
-
-func deleteEnteredDestroyPhaseStateFile(workingDir string, fileName string) error {
-	return DeleteTFFile(workingDir, fileName)
-}
-
 func saveResultAsJSON(rw io.ReadWriter, mpfResult domain.MPFResult) error {
 	// serialize mpfREsult to json
 	return json.NewEncoder(rw).Encode(mpfResult)
@@ -0,0 +1,127 @@
+package terraform
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const TFBackendOverrideFileName = "mpf_backend_override.tf"
+
+// The backend override forces a local backend, so that the state of the isolated workspace is never written to the remote backend of the module
+const tfLocalBackendOverride = `terraform {
+  backend "local" {}
+}
+`
+
+var tfParentModuleSourceRegex = regexp.MustCompile(`source\s*=\s*"\.\./`)
+
+// CreateIsolatedWorkspace copies the terraform module in the working directory to a new temporary directory, without the .terraform
+// directory and the state files, and adds a backend override file, so that terraform runs with a local backend and a fresh state.
+// The returned workspace should be removed with os.RemoveAll once MPF has cleaned up the deployment
+func CreateIsolatedWorkspace(workingDir string) (string, error) {
+	workspaceDir, err := os.MkdirTemp("", "az-mpf-tf-workspace-")
+	if err != nil {
+		return "", err
+	}
+
+	err = filepath.WalkDir(workingDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(workingDir, path)
+		if err != nil {
+			return err
+		}
+
+		if isExcludedFromIsolatedWorkspace(relPath, d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		targetPath := filepath.Join(workspaceDir, relPath)
+		if d.IsDir() {
+			return os.MkdirAll(targetPath, 0755)
+		}
+
+		if d.Type()&fs.ModeSymlink != 0 {
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(linkTarget, targetPath)
+		}
+
+		if strings.HasSuffix(d.Name(), ".tf") {
+			warnIfParentModuleSource(path)
+		}
+		return copyFile(path, targetPath)
+	})
+	if err != nil {
+		os.RemoveAll(workspaceDir)
+		return "", err
+	}
+
+	err = os.WriteFile(filepath.Join(workspaceDir, TFBackendOverrideFileName), []byte(tfLocalBackendOverride), 0644)
+	if err != nil {
+		os.RemoveAll(workspaceDir)
+		return "", err
+	}
+
+	log.Infof("Created isolated terraform workspace %s from %s\n", workspaceDir, workingDir)
+	return workspaceDir, nil
+}
+
+func isExcludedFromIsolatedWorkspace(relPath string, d fs.DirEntry) bool {
+	if relPath == "." {
+		return false
+	}
+
+	name := d.Name()
+	if d.IsDir() {
+		return name == ".terraform" || name == ".git"
+	}
+
+	return strings.HasPrefix(name, "terraform.tfstate") || name == "terraform.log"
+}
+
+// module sources in parent directories are not part of the isolated workspace, and can not be resolved from it
+func warnIfParentModuleSource(path string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	if tfParentModuleSourceRegex.Match(content) {
+		log.Warnf("%s references a module in a parent directory, which is not copied to the isolated workspace\n", path)
+	}
+}
+
+func copyFile(sourcePath string, targetPath string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
+	target, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	_, err = io.Copy(target, source)
+	return err
+}
@@ -0,0 +1,73 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateIsolatedWorkspace(t *testing.T) {
+	workingDir := t.TempDir()
+	files := map[string]string{
+		"main.tf":                      `terraform { backend "azurerm" {} }`,
+		"modules/network/main.tf":      `resource "azurerm_virtual_network" "vnet" {}`,
+		".terraform.lock.hcl":          "lock",
+		"terraform.tfstate":            "{}",
+		"terraform.tfstate.backup":     "{}",
+		".terraform/terraform.tfstate": "{}",
+		".terraform/providers/azurerm": "provider",
+	}
+	for name, content := range files {
+		path := filepath.Join(workingDir, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	workspaceDir, err := CreateIsolatedWorkspace(workingDir)
+	assert.Nil(t, err)
+	defer os.RemoveAll(workspaceDir)
+
+	assert.FileExists(t, filepath.Join(workspaceDir, "main.tf"))
+	assert.FileExists(t, filepath.Join(workspaceDir, "modules/network/main.tf"))
+	assert.FileExists(t, filepath.Join(workspaceDir, ".terraform.lock.hcl"))
+	assert.NoFileExists(t, filepath.Join(workspaceDir, "terraform.tfstate"))
+	assert.NoFileExists(t, filepath.Join(workspaceDir, "terraform.tfstate.backup"))
+	assert.NoDirExists(t, filepath.Join(workspaceDir, ".terraform"))
+
+	override, err := os.ReadFile(filepath.Join(workspaceDir, TFBackendOverrideFileName))
+	assert.Nil(t, err)
+	assert.Contains(t, string(override), `backend "local"`)
+}
+
+func TestIsolatedWorkspaceHasNoAzurermBackend(t *testing.T) {
+	workingDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(workingDir, "backend.tf"), []byte(`terraform {
+  backend "azurerm" {
+    resource_group_name  = "tfstate-rg"
+    storage_account_name = "tfstate"
+    container_name       = "tfstate"
+    key                  = "mpf.tfstate"
+  }
+}
+`), 0644)
+	assert.Nil(t, err)
+
+	state, err := loadTerraformRunState(newTestRunDir(t))
+	assert.Nil(t, err)
+	checker := &terraformDeploymentConfig{workingDir: workingDir, state: state}
+	checker.detectStateBackend()
+	assert.NotNil(t, checker.backend)
+
+	// the copied backend block is overridden by the local backend of the isolated workspace
+	workspaceDir, err := CreateIsolatedWorkspace(workingDir)
+	assert.Nil(t, err)
+	defer os.RemoveAll(workspaceDir)
+
+	checker.workingDir = workspaceDir
+	err = checker.SetIsolated(true)
+	assert.Nil(t, err)
+	checker.detectStateBackend()
+	assert.Nil(t, checker.backend)
+}
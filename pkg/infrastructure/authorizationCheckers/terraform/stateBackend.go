@@ -0,0 +1,153 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+// TFStateBackendStage is the stage of the permissions required to access the azurerm state backend. They are required
+// by every terraform phase, and are reported as a stage of their own
+const TFStateBackendStage = "stateBackend"
+
+// The error code of the storage data plane when the identity has no role with the data action of the request
+const storagePermissionMismatchErr = "AuthorizationPermissionMismatch"
+
+var (
+	tfAzurermBackendBlockRegex = regexp.MustCompile(`backend\s+"azurerm"\s*\{([^}]*)\}`)
+	tfAttributeRegex           = regexp.MustCompile(`(?m)^\s*([A-Za-z_]+)\s*=\s*"([^"]*)"`)
+	storageClientOperationRgx  = regexp.MustCompile(`(blobs|containers)\.Client\.([A-Za-z]+)`)
+)
+
+// The data actions required by the operations of the storage clients the azurerm backend uses for the state blob
+var storageOperationDataActions = map[string]string{
+	"containers.ListBlobs":     "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read",
+	"blobs.Get":                "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read",
+	"blobs.GetProperties":      "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read",
+	"blobs.PutBlockBlob":       "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/write",
+	"blobs.SetMetaData":        "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/write",
+	"blobs.AcquireLease":       "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/write",
+	"blobs.ReleaseLease":       "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/write",
+	"blobs.BreakLease":         "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/write",
+	"blobs.Delete":             "Microsoft.Storage/storageAccounts/blobServices/containers/blobs/delete",
+	"containers.GetProperties": "Microsoft.Storage/storageAccounts/blobServices/containers/read",
+}
+
+// azurermBackendConfig is the location of the state of the azurerm backend, from the backend block of the module
+// and the backend configuration files
+type azurermBackendConfig struct {
+	SubscriptionID     string
+	ResourceGroupName  string
+	StorageAccountName string
+	ContainerName      string
+}
+
+// getAzurermBackendConfig returns the azurerm backend configuration of the root module in the directory, or nil when the
+// module has no azurerm backend. Attributes of the backend configuration files take precedence over the backend block
+func getAzurermBackendConfig(moduleDir string, backendConfigFiles []string) (*azurermBackendConfig, error) {
+	entries, err := os.ReadDir(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var attributes []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tf") {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(moduleDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range tfAzurermBackendBlockRegex.FindAllStringSubmatch(string(content), -1) {
+			attributes = append(attributes, match[1])
+		}
+	}
+
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+
+	for _, backendConfigFile := range backendConfigFiles {
+		content, err := os.ReadFile(backendConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, string(content))
+	}
+
+	config := &azurermBackendConfig{}
+	for _, attributeBlock := range attributes {
+		for _, match := range tfAttributeRegex.FindAllStringSubmatch(attributeBlock, -1) {
+			switch match[1] {
+			case "subscription_id":
+				config.SubscriptionID = match[2]
+			case "resource_group_name":
+				config.ResourceGroupName = match[2]
+			case "storage_account_name":
+				config.StorageAccountName = match[2]
+			case "container_name":
+				config.ContainerName = match[2]
+			}
+		}
+	}
+	return config, nil
+}
+
+// containerScope returns the resource ID of the state container, or of the closest parent known from the configuration
+func (c *azurermBackendConfig) containerScope(subscriptionID string) string {
+	if c.SubscriptionID != "" {
+		subscriptionID = c.SubscriptionID
+	}
+
+	scope := fmt.Sprintf("/subscriptions/%s", subscriptionID)
+	if c.ResourceGroupName == "" {
+		return scope
+	}
+	scope = fmt.Sprintf("%s/resourceGroups/%s", scope, c.ResourceGroupName)
+	if c.StorageAccountName == "" {
+		return scope
+	}
+	scope = fmt.Sprintf("%s/providers/Microsoft.Storage/storageAccounts/%s", scope, c.StorageAccountName)
+	if c.ContainerName == "" {
+		return scope
+	}
+	return fmt.Sprintf("%s/blobServices/default/containers/%s", scope, c.ContainerName)
+}
+
+// getStateBackendAuthorizationError returns the storage data plane authorization failures of the azurerm backend in the
+// terraform error as authorization errors, which name the data action the storage operation requires.
+// The storage data plane only reports that the permission does not match, without naming the data action
+func (c *azurermBackendConfig) getStateBackendAuthorizationError(errMsg string, mpfConfig domain.MPFConfig) (string, error) {
+	if !strings.Contains(errMsg, storagePermissionMismatchErr) {
+		return "", fmt.Errorf("no state backend authorization error found")
+	}
+
+	matches := storageClientOperationRgx.FindAllStringSubmatch(errMsg, -1)
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no storage operation found in state backend authorization error")
+	}
+
+	scope := c.containerScope(mpfConfig.SubscriptionID)
+	var sb strings.Builder
+	for _, match := range matches {
+		dataAction, ok := storageOperationDataActions[match[1]+"."+match[2]]
+		if !ok {
+			log.Warnf("unknown storage operation %s in state backend authorization error\n", match[0])
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("AuthorizationFailed: The client '%s' with object id '%s' does not have authorization to perform action '%s' over scope '%s' or the scope is invalid.\n", mpfConfig.SP.SPClientID, mpfConfig.SP.SPObjectID, dataAction, scope))
+	}
+
+	if sb.Len() == 0 {
+		return "", fmt.Errorf("no known storage operation found in state backend authorization error")
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,71 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAzurermBackendConfig(t *testing.T) {
+	moduleDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(moduleDir, "backend.tf"), []byte(`terraform {
+  backend "azurerm" {
+    resource_group_name  = "tfstate-rg"
+    storage_account_name = "tfstate"
+    container_name       = "tfstate"
+    key                  = "mpf.tfstate"
+    use_azuread_auth     = true
+  }
+}
+`), 0644)
+	assert.Nil(t, err)
+
+	backendConfigFile := filepath.Join(t.TempDir(), "backend.hcl")
+	err = os.WriteFile(backendConfigFile, []byte("storage_account_name = \"tfstateprod\"\n"), 0644)
+	assert.Nil(t, err)
+
+	config, err := getAzurermBackendConfig(moduleDir, []string{backendConfigFile})
+	assert.Nil(t, err)
+	assert.Equal(t, "tfstate-rg", config.ResourceGroupName)
+	assert.Equal(t, "tfstateprod", config.StorageAccountName)
+	assert.Equal(t, "/subscriptions/SSSSSSSS/resourceGroups/tfstate-rg/providers/Microsoft.Storage/storageAccounts/tfstateprod/blobServices/default/containers/tfstate", config.containerScope("SSSSSSSS"))
+}
+
+func TestGetAzurermBackendConfigWithoutBackend(t *testing.T) {
+	moduleDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(moduleDir, "main.tf"), []byte("terraform {\n  backend \"local\" {}\n}\n"), 0644)
+	assert.Nil(t, err)
+
+	config, err := getAzurermBackendConfig(moduleDir, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, config)
+}
+
+func TestGetStateBackendAuthorizationError(t *testing.T) {
+	config := &azurermBackendConfig{
+		ResourceGroupName:  "tfstate-rg",
+		StorageAccountName: "tfstate",
+		ContainerName:      "tfstate",
+	}
+	mpfConfig := domain.MPFConfig{
+		SubscriptionID: "SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS",
+		SP: domain.ServicePrincipal{
+			SPClientID: "XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX",
+			SPObjectID: "YYYYYYYY-YYYY-YYYY-YYYY-YYYYYYYYYYYY",
+		},
+	}
+	initErr := `Error: Failed to get existing workspaces: containers.Client.ListBlobs: executing request: unexpected status 403 (403 This request is not authorized to perform this operation using this permission.) with AuthorizationPermissionMismatch: This request is not authorized to perform this operation using this permission.`
+
+	msg, err := config.getStateBackendAuthorizationError(initErr, mpfConfig)
+	assert.Nil(t, err)
+
+	scpMp, err := domain.GetScopePermissionsFromAuthError(msg)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.Storage/storageAccounts/blobServices/containers/blobs/read"}, scpMp[config.containerScope(mpfConfig.SubscriptionID)])
+
+	_, err = config.getStateBackendAuthorizationError("Error: Invalid resource type", mpfConfig)
+	assert.NotNil(t, err)
+}
@@ -1,12 +1,17 @@
 package terraform
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/terraform-exec/tfexec"
 	"github.com/manisbindra/az-mpf/pkg/domain"
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -14,35 +19,169 @@ type terraformDeploymentConfig struct {
 	ctx                            context.Context
 	workingDir                     string
 	execPath                       string
-	varFilePath                    string
 	importExistingResourcesToState bool
-	targetModule                   string
+	options                        TerraformOptions
+	initialStateOptions            TerraformOptions
+	destroyPhase                   bool
+	planOnly                       bool
+	runDir                         *mpfSharedUtils.RunDir
+	state                          *terraformRunState
+	backend                        *azurermBackendConfig
+	isolated                       bool
+	stateBackendError              bool
+	initialized                    bool
+	pluginCacheDir                 string
+	envPassthrough                 []string
 }
 
-var inDestroyPhase bool
-
-// This file is created once the destroy phase is entered
 const (
-	TFDestroyStateEnteredFileName = ".azmpfEnteredDestroyPhase.txt"
-	TFExistingResourceErrorMsg    = "to be managed via Terraform this resource needs to be imported into the State"
-	BillingFeaturesPayloadError   = "CurrentBillingFeatures is required in payload"
+	TFExistingResourceErrorMsg  = "to be managed via Terraform this resource needs to be imported into the State"
+	BillingFeaturesPayloadError = "CurrentBillingFeatures is required in payload"
 	// AuthorizationPermissionMismatchErr = "AuthorizationPermissionMismatch"
+	TFPlanFileName = "mpf.tfplan"
+)
+
+// The terraform phases in which permissions are discovered. The apply and destroy phases can be selected,
+// the init, plan and import phases run as part of the apply phase
+const (
+	TFPhaseInit    = "init"
+	TFPhasePlan    = "plan"
+	TFPhaseImport  = "import"
+	TFPhaseApply   = "apply"
+	TFPhaseDestroy = "destroy"
+	TFPhaseDone    = "done"
 )
 
-func NewTerraformAuthorizationChecker(workDir string, execPath string, varFilePath string, importExistingResources bool, targetModule string) *terraformDeploymentConfig {
-	err := deleteEnteredDestroyPhaseStateFile(workDir, TFDestroyStateEnteredFileName)
+// NewTerraformAuthorizationChecker returns the checker for the module in the working directory, which keeps its files
+// in the run directory of MPF
+func NewTerraformAuthorizationChecker(workDir string, runDir *mpfSharedUtils.RunDir, execPath string, varFilePath string, importExistingResources bool, targetModule string) *terraformDeploymentConfig {
+	state, err := loadTerraformRunState(runDir)
 	if err != nil {
-		log.Warnf("error deleting enteredDestroyPhaseStateFile: %s", err)
+		log.Fatalf("error loading terraform run state: %s", err)
+	}
+
+	pluginCacheDir, err := GetPluginCacheDir()
+	if err != nil {
+		log.Warnf("error creating terraform plugin cache directory, providers are not cached: %s", err)
+	}
+
+	var options TerraformOptions
+	if varFilePath != "" {
+		options.VarFiles = []string{varFilePath}
+	}
+	if targetModule != "" {
+		options.Targets = []string{targetModule}
 	}
 
 	return &terraformDeploymentConfig{
 		workingDir:                     workDir,
 		execPath:                       execPath,
 		ctx:                            context.Background(),
-		varFilePath:                    varFilePath,
 		importExistingResourcesToState: importExistingResources,
-		targetModule:                   targetModule,
+		options:                        options,
+		destroyPhase:                   true,
+		runDir:                         runDir,
+		state:                          state,
+		pluginCacheDir:                 pluginCacheDir,
+	}
+}
+
+// SetOptions sets the terraform CLI options, in place of the var file and target module of the checker
+func (a *terraformDeploymentConfig) SetOptions(options TerraformOptions) error {
+	err := options.Validate()
+	if err != nil {
+		return err
+	}
+	a.options = options
+	return nil
+}
+
+// SetInitialStateOptions sets the terraform CLI options of the initial state of the update scenario, which is applied
+// before permissions are discovered for the options of the checker
+func (a *terraformDeploymentConfig) SetInitialStateOptions(options TerraformOptions) error {
+	err := options.Validate()
+	if err != nil {
+		return err
+	}
+	a.initialStateOptions = options
+	return nil
+}
+
+// DeployInitialState applies the module with the initial state options, with the credentials of the environment MPF runs in
+func (a *terraformDeploymentConfig) DeployInitialState(mpfConfig domain.MPFConfig) error {
+	tf, err := a.newAdminTerraform()
+	if err != nil {
+		return err
+	}
+
+	err = a.initialStateOptions.terraformInit(a.ctx, tf)
+	if err != nil {
+		return err
+	}
+
+	log.Infoln("applying initial state of the update scenario")
+	return tf.Apply(a.ctx, a.initialStateOptions.applyWithoutPlanOptions()...)
+}
+
+// SetPhases selects the phases in which permissions are discovered, apply and optionally destroy.
+// When the destroy phase is not selected, the resources are only destroyed when the deployment is cleaned up
+func (a *terraformDeploymentConfig) SetPhases(phases []string) error {
+	var applyPhase bool
+	a.destroyPhase = false
+	for _, phase := range phases {
+		switch strings.ToLower(phase) {
+		case TFPhaseApply:
+			applyPhase = true
+		case TFPhaseDestroy:
+			a.destroyPhase = true
+		default:
+			return fmt.Errorf("invalid terraform phase: %s, valid phases are %s and %s", phase, TFPhaseApply, TFPhaseDestroy)
+		}
+	}
+	if !applyPhase {
+		return fmt.Errorf("terraform phase %s is required", TFPhaseApply)
+	}
+	return nil
+}
+
+// SetPlanOnly selects the plan only mode, in which terraform plan runs against the existing infrastructure of the state,
+// without apply and destroy, so that the permissions to plan the module are discovered. Nothing is destroyed in this mode
+func (a *terraformDeploymentConfig) SetPlanOnly(planOnly bool) {
+	a.planOnly = planOnly
+}
+
+// SetIsolated marks the working directory as an isolated workspace, which is removed at the end of the run together with its
+// local state. An interrupted run in an isolated workspace is therefore not resumable, and a run interrupted in the destroy
+// phase without isolation can not be resumed in an isolated workspace, as its state is not part of the workspace. The backend
+// override of the isolated workspace forces a local backend, so the azurerm backend of the copied module is not used
+func (a *terraformDeploymentConfig) SetIsolated(isolated bool) error {
+	if isolated && a.state.Phase == TFPhaseDestroy {
+		return fmt.Errorf("the interrupted run of the module in the %s phase can not be resumed in an isolated workspace, run without isolation to destroy its resources", TFPhaseDestroy)
+	}
+	a.isolated = isolated
+	a.state.resumable = !isolated
+	return nil
+}
+
+// GetCurrentStage returns the terraform phase of the last authorization error, or the state backend stage when the
+// authorization error was returned by the state backend
+func (a *terraformDeploymentConfig) GetCurrentStage() string {
+	if a.stateBackendError {
+		return TFStateBackendStage
+	}
+	return a.state.Phase
+}
+
+// GetStages returns the terraform phases in the order they run, after the state backend stage which all phases require
+func (a *terraformDeploymentConfig) GetStages() []string {
+	if a.planOnly {
+		return []string{TFStateBackendStage, TFPhaseInit, TFPhasePlan}
+	}
+	stages := []string{TFStateBackendStage, TFPhaseInit, TFPhasePlan, TFPhaseImport, TFPhaseApply}
+	if a.destroyPhase {
+		stages = append(stages, TFPhaseDestroy)
 	}
+	return stages
 }
 
 func (a *terraformDeploymentConfig) GetDeploymentAuthorizationErrors(mpfConfig domain.MPFConfig) (string, error) {
@@ -51,32 +190,31 @@ func (a *terraformDeploymentConfig) GetDeploymentAuthorizationErrors(mpfConfig d
 
 func (a *terraformDeploymentConfig) CleanDeployment(mpfConfig domain.MPFConfig) error {
 
-	err := deleteEnteredDestroyPhaseStateFile(a.workingDir, TFDestroyStateEnteredFileName)
+	err := a.state.reset()
 	if err != nil {
-		log.Warnf("error deleting enteredDestroyPhaseStateFile: %s", err)
+		log.Warnf("error resetting terraform run state: %s", err)
 	}
 
-	tf, err := tfexec.NewTerraform(a.workingDir, a.execPath)
+	// the next check starts from a clean deployment, and runs init with the credentials of the service principal again
+	a.initialized = false
+
+	// in plan only mode the infrastructure of the state existed before the run, and is not destroyed
+	if a.planOnly {
+		return nil
+	}
+
+	tf, err := a.newAdminTerraform()
 	if err != nil {
 		log.Fatalf("error running NewTerraform: %s", err)
 	}
 
-	err = tf.Init(context.Background())
+	err = a.options.terraformInit(a.ctx, tf)
 	if err != nil {
 		log.Warnf("error running Init: %s", err)
 		return err
 	}
 
-	switch {
-	case a.varFilePath == "" && a.targetModule == "":
-		err = tf.Destroy(a.ctx)
-	case a.varFilePath != "" && a.targetModule == "":
-		err = tf.Destroy(a.ctx, tfexec.VarFile(a.varFilePath))
-	case a.varFilePath == "" && a.targetModule != "":
-		err = tf.Destroy(a.ctx, tfexec.Target(a.targetModule))
-	case a.varFilePath != "" && a.targetModule != "":
-		err = tf.Destroy(a.ctx, tfexec.VarFile(a.varFilePath), tfexec.Target(a.targetModule))
-	}
+	err = tf.Destroy(a.ctx, a.options.destroyOptions()...)
 	if err != nil {
 		log.Warnf("error running terraform destroy: %s", err)
 	}
@@ -85,7 +223,7 @@ func (a *terraformDeploymentConfig) CleanDeployment(mpfConfig domain.MPFConfig)
 
 func (a *terraformDeploymentConfig) setTFConfig(mpfConfig domain.MPFConfig) (*tfexec.Terraform, error) {
 	log.Infof("workingDir: %s", a.workingDir)
-	log.Infof("varfilePath: %s", a.varFilePath)
+	log.Infof("varFiles: %v", a.options.VarFiles)
 	log.Infof("execPath: %s", a.execPath)
 
 	tf, err := tfexec.NewTerraform(a.workingDir, a.execPath)
@@ -98,7 +236,7 @@ func (a *terraformDeploymentConfig) setTFConfig(mpfConfig domain.MPFConfig) (*tf
 
 	tfLogPathEnvVal := os.Getenv("TF_LOG_PATH")
 	if tfLogPathEnvVal == "" {
-		tfLogPathEnvVal = a.workingDir + "/terraform.log"
+		tfLogPathEnvVal = filepath.Join(a.runDir.Path, "terraform.log")
 	}
 
 	tfReattachProviders := os.Getenv("TF_REATTACH_PROVIDERS")
@@ -120,17 +258,19 @@ func (a *terraformDeploymentConfig) setTFConfig(mpfConfig domain.MPFConfig) (*tf
 		tf.SetLog(tfLogLevel)
 		tf.SetLogPath(tfLogPathEnvVal)
 		tf.SetStderr(os.Stderr)
-		tf.SetStdout(os.Stdout)
 	}
-
-	envVars := map[string]string{
-		"ARM_CLIENT_ID":       mpfConfig.SP.SPClientID,
-		"ARM_CLIENT_SECRET":   mpfConfig.SP.SPClientSecret,
-		"ARM_SUBSCRIPTION_ID": mpfConfig.SubscriptionID,
-		"ARM_TENANT_ID":       mpfConfig.TenantID,
-		"PATH":                pathEnvVal,
+	// the output of terraform is only shown at debug level, and on stderr, so that it is not mixed with the output of MPF
+	if log.IsLevelEnabled(log.DebugLevel) {
+		tf.SetStdout(os.Stderr)
 	}
 
+	envVars := a.getPassthroughEnv()
+	envVars["ARM_CLIENT_ID"] = mpfConfig.SP.SPClientID
+	envVars["ARM_CLIENT_SECRET"] = mpfConfig.SP.SPClientSecret
+	envVars["ARM_SUBSCRIPTION_ID"] = mpfConfig.SubscriptionID
+	envVars["ARM_TENANT_ID"] = mpfConfig.TenantID
+	envVars["PATH"] = pathEnvVal
+
 	if tfReattachProviders != "" {
 		envVars["TF_REATTACH_PROVIDERS"] = tfReattachProviders
 	}
@@ -145,66 +285,123 @@ func (a *terraformDeploymentConfig) deployTerraform(mpfConfig domain.MPFConfig)
 		log.Fatalf("error setting Terraform start config: %s", err)
 	}
 
-	inDestroyPhase = doesEnteredDestroyPhaseStateFileExist(a.workingDir, TFDestroyStateEnteredFileName)
-	if !inDestroyPhase {
-		log.Infof("destroy phase file does not exist, in apply phase")
+	a.stateBackendError = false
+	a.detectStateBackend()
+
+	if a.state.Phase != TFPhaseDestroy {
 		msg, err := a.terraformApply(mpfConfig, tf)
 		if err != nil || msg != "" {
 			return msg, err
 		}
+
+		if a.planOnly {
+			log.Infoln("plan only mode, skipping terraform apply and destroy")
+			return "", a.state.transition(TFPhaseDone)
+		}
+
+		if !a.destroyPhase {
+			log.Infoln("destroy phase is not selected, skipping terraform destroy")
+			return "", a.state.transition(TFPhaseDone)
+		}
 	}
 
 	return a.terraformDestroy(mpfConfig, tf)
 
 }
 
+// detectStateBackend reads the azurerm backend of the module, whose authorization errors are reported in the state backend stage.
+// An isolated workspace always has a local backend
+func (a *terraformDeploymentConfig) detectStateBackend() {
+	a.backend = nil
+	if a.isolated {
+		return
+	}
+
+	backend, err := getAzurermBackendConfig(a.workingDir, a.options.BackendConfigFiles)
+	if err != nil {
+		log.Warnf("error reading azurerm backend configuration: %s", err)
+	}
+	a.backend = backend
+}
+
 func (a *terraformDeploymentConfig) terraformApply(mpfConfig domain.MPFConfig, tf *tfexec.Terraform) (string, error) {
 
-	err := tf.Init(context.Background())
+	err := a.state.transition(TFPhaseInit)
 	if err != nil {
+		return "", err
+	}
+
+	err = a.terraformInit(tf)
+	if err != nil {
+		// the state backend is the only one accessed by init, so its ARM authorization errors, such as listing the keys
+		// of the storage account, are state backend errors as well
+		if a.backend != nil && strings.Contains(err.Error(), "AuthorizationFailed") {
+			a.stateBackendError = true
+			return err.Error(), nil
+		}
+		if msg := a.stateBackendAuthorizationError(err.Error(), mpfConfig); msg != "" {
+			return msg, nil
+		}
 		log.Warnf("error running Init: %s", err)
 		return "", err
 	}
 
-	log.Infoln("in apply phase")
+	// plan to a file and apply the saved plan, so that errors of the plan and the apply are reported in their own phase
+	log.Infoln("in plan phase")
+	err = a.state.transition(TFPhasePlan)
+	if err != nil {
+		return "", err
+	}
+	planFilePath := filepath.Join(a.runDir.Path, TFPlanFileName)
+	defer os.Remove(planFilePath)
 
-	switch {
-	case a.varFilePath == "" && a.targetModule == "":
-		err = tf.Apply(a.ctx)
-	case a.varFilePath != "" && a.targetModule == "":
-		err = tf.Apply(a.ctx, tfexec.VarFile(a.varFilePath))
-	case a.varFilePath == "" && a.targetModule != "":
-		err = tf.Apply(a.ctx, tfexec.Target(a.targetModule))
-	case a.varFilePath != "" && a.targetModule != "":
-		err = tf.Apply(a.ctx, tfexec.VarFile(a.varFilePath), tfexec.Target(a.targetModule))
+	output, err := a.runWithJSONOutput(tf, func(w io.Writer) error {
+		_, err := tf.PlanJSON(a.ctx, w, a.options.planOptions(planFilePath)...)
+		return err
+	})
+	if err == nil && !a.planOnly {
+		log.Infoln("in apply phase")
+		err = a.state.transition(TFPhaseApply)
+		if err != nil {
+			return "", err
+		}
+		output, err = a.runWithJSONOutput(tf, func(w io.Writer) error {
+			return tf.ApplyJSON(a.ctx, w, a.options.applyOptions(planFilePath)...)
+		})
 	}
 
 	if err == nil {
 		return "", nil
 	}
 
-	errorMsg := err.Error()
+	diagnostics := getDiagnosticsOrError(output, err)
+	errorMsg := formatDiagnostics(diagnostics)
 	log.Debugln("terraform apply error: ", errorMsg)
 
 	// if strings.Contains(errorMsg, AuthorizationPermissionMismatchErr) {
 	// 	return errorMsg, nil
 	// }
 
-	if strings.Contains(errorMsg, "Authorization") {
-		return errorMsg, nil
+	// plan and apply read, lock and write the state
+	if msg := a.stateBackendAuthorizationError(errorMsg, mpfConfig); msg != "" {
+		return msg, nil
+	}
+
+	if authDiagnostics := filterDiagnostics(diagnostics, "Authorization"); len(authDiagnostics) > 0 {
+		return formatDiagnostics(authDiagnostics), nil
 	}
 
 	// Temporary fix to workaround issue https://github.com/hashicorp/terraform-provider-azurerm/issues/27961
 	// It is observed only once, so retrying works
-	if strings.Contains(errorMsg, BillingFeaturesPayloadError) {
+	if len(filterDiagnostics(diagnostics, BillingFeaturesPayloadError)) > 0 {
 		return errorMsg, nil
 	}
 
 	// import errors can occur for some resources, when identity does not have all required permissions,
 	// as described in https://github.com/hashicorp/terraform-provider-azurerm/issues/27961#issuecomment-2467392936
-	if a.importExistingResourcesToState && strings.Contains(errorMsg, TFExistingResourceErrorMsg) {
+	if a.importExistingResourcesToState && len(filterDiagnostics(diagnostics, TFExistingResourceErrorMsg)) > 0 {
 
-		msg, err := a.terraformImport(tf, errorMsg)
+		msg, err := a.terraformImport(tf, diagnostics)
 		if err != nil || msg != "" {
 			if strings.Contains(msg, "Authorization") {
 				return msg, nil
@@ -218,11 +415,22 @@ func (a *terraformDeploymentConfig) terraformApply(mpfConfig domain.MPFConfig, t
 	return errorMsg, err
 }
 
-func (a *terraformDeploymentConfig) terraformImport(tf *tfexec.Terraform, existingResErrMesg string) (string, error) {
+// terraformImport imports the existing resources of the diagnostics. terraform import has no machine readable output,
+// so errors of the import are returned as terraform reports them
+func (a *terraformDeploymentConfig) terraformImport(tf *tfexec.Terraform, diagnostics []TerraformDiagnostic) (string, error) {
+	existingResErrMesg := formatDiagnostics(filterDiagnostics(diagnostics, TFExistingResourceErrorMsg))
 	log.Warnf("terraform apply: existing resource error occured:|| %s ||\n\n", existingResErrMesg)
 	log.Warn("importing existing resources to state")
+	err := a.state.transition(TFPhaseImport)
+	if err != nil {
+		return "", err
+	}
 
-	exstResAddrAndResIDs, err := GetAddressAndResourceIDFromExistingResourceError(existingResErrMesg)
+	exstResAddrAndResIDs, err := GetAddressAndResourceIDFromExistingResourceDiagnostics(diagnostics)
+	if err != nil {
+		// diagnostics of older terraform versions have no resource address, which is part of the formatted message
+		exstResAddrAndResIDs, err = GetAddressAndResourceIDFromExistingResourceError(existingResErrMesg)
+	}
 	if err != nil {
 		log.Warnf("error getting existing resource address and resource ID: %s \n", err)
 		return existingResErrMesg, err
@@ -230,11 +438,7 @@ func (a *terraformDeploymentConfig) terraformImport(tf *tfexec.Terraform, existi
 
 	for addr, resID := range exstResAddrAndResIDs {
 		log.Warnf("importing existing resource: %s, %s ||\n", addr, resID)
-		if a.varFilePath != "" {
-			err = tf.Import(a.ctx, addr, resID, tfexec.VarFile(a.varFilePath))
-		} else {
-			err = tf.Import(a.ctx, addr, resID)
-		}
+		err = tf.Import(a.ctx, addr, resID, a.options.importOptions()...)
 
 		if err != nil {
 			log.Warnf("error importing existing resource: %s \n", err)
@@ -247,34 +451,91 @@ func (a *terraformDeploymentConfig) terraformImport(tf *tfexec.Terraform, existi
 }
 
 func (a *terraformDeploymentConfig) terraformDestroy(mpfConfig domain.MPFConfig, tf *tfexec.Terraform) (string, error) {
-	var err error
 	log.Infoln("in destroy phase")
-	if !inDestroyPhase {
-		err = createEnteredDestroyPhaseStateFile(a.workingDir, TFDestroyStateEnteredFileName)
-		if err != nil {
-			log.Warnf("error creating enteredDestroyPhaseStateFile: %s", err)
-		}
+	err := a.state.transition(TFPhaseDestroy)
+	if err != nil {
+		return "", err
 	}
 
-	switch {
-	case a.varFilePath == "" && a.targetModule == "":
-		err = tf.Destroy(a.ctx)
-	case a.varFilePath != "" && a.targetModule == "":
-		err = tf.Destroy(a.ctx, tfexec.VarFile(a.varFilePath))
-	case a.varFilePath == "" && a.targetModule != "":
-		err = tf.Destroy(a.ctx, tfexec.Target(a.targetModule))
-	case a.varFilePath != "" && a.targetModule != "":
-		err = tf.Destroy(a.ctx, tfexec.VarFile(a.varFilePath), tfexec.Target(a.targetModule))
-	}
+	output, err := a.runWithJSONOutput(tf, func(w io.Writer) error {
+		return tf.DestroyJSON(a.ctx, w, a.options.destroyOptions()...)
+	})
 
 	if err != nil {
-		errorMsg := err.Error()
+		diagnostics := getDiagnosticsOrError(output, err)
+		errorMsg := formatDiagnostics(diagnostics)
 		log.Debugln(errorMsg)
-		if strings.Contains(errorMsg, "Authorization") {
-			return errorMsg, nil
+		if msg := a.stateBackendAuthorizationError(errorMsg, mpfConfig); msg != "" {
+			return msg, nil
+		}
+		if authDiagnostics := filterDiagnostics(diagnostics, "Authorization"); len(authDiagnostics) > 0 {
+			return formatDiagnostics(authDiagnostics), nil
 		}
 		log.Warnf("terraform destroy: non authorizaton error occured: %s", errorMsg)
 		return errorMsg, err
 	}
-	return "", nil
+	return "", a.state.transition(TFPhaseDone)
+}
+
+// terraformInit runs terraform init once per run with the credentials of the service principal, later checks reuse
+// the initialized working directory
+func (a *terraformDeploymentConfig) terraformInit(tf *tfexec.Terraform) error {
+	if a.initialized {
+		log.Debugln("terraform working directory already initialized, skipping init")
+		return nil
+	}
+
+	err := a.options.terraformInit(a.ctx, tf)
+	if err != nil {
+		return err
+	}
+	a.initialized = true
+	return nil
+}
+
+// newAdminTerraform returns terraform for the commands run with the credentials of the environment MPF runs in
+func (a *terraformDeploymentConfig) newAdminTerraform() (*tfexec.Terraform, error) {
+	tf, err := tfexec.NewTerraform(a.workingDir, a.execPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tf.SetEnv(a.getAdminEnv())
+	if err != nil {
+		return nil, err
+	}
+	return tf, nil
+}
+
+// stateBackendAuthorizationError returns the authorization errors of the azurerm state backend in the terraform error,
+// or an empty string if the module has no azurerm backend or the error is not a state backend authorization error
+func (a *terraformDeploymentConfig) stateBackendAuthorizationError(errMsg string, mpfConfig domain.MPFConfig) string {
+	if a.backend == nil {
+		return ""
+	}
+
+	msg, err := a.backend.getStateBackendAuthorizationError(errMsg, mpfConfig)
+	if err != nil {
+		log.Debugf("no state backend authorization error: %s\n", err)
+		return ""
+	}
+	log.Infoln("state backend authorization error occured")
+	a.stateBackendError = true
+	return msg
+}
+
+// runWithJSONOutput runs the terraform command with -json, and returns its machine readable output. The JSON commands
+// replace the stdout of terraform, which is restored afterwards. The output is only shown at debug level, on stderr
+func (a *terraformDeploymentConfig) runWithJSONOutput(tf *tfexec.Terraform, run func(w io.Writer) error) (*bytes.Buffer, error) {
+	output := &bytes.Buffer{}
+	var w io.Writer = output
+	stdout := io.Discard
+	if log.IsLevelEnabled(log.DebugLevel) {
+		stdout = os.Stderr
+		w = io.MultiWriter(output, os.Stderr)
+	}
+
+	err := run(w)
+	tf.SetStdout(stdout)
+	return output, err
 }
@@ -0,0 +1,86 @@
+package terraform
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetPhases(t *testing.T) {
+	checker := &terraformDeploymentConfig{destroyPhase: true}
+
+	err := checker.SetPhases([]string{"apply"})
+	assert.Nil(t, err)
+	assert.False(t, checker.destroyPhase)
+	assert.Equal(t, []string{TFStateBackendStage, TFPhaseInit, TFPhasePlan, TFPhaseImport, TFPhaseApply}, checker.GetStages())
+
+	err = checker.SetPhases([]string{"Apply", "destroy"})
+	assert.Nil(t, err)
+	assert.True(t, checker.destroyPhase)
+	assert.Equal(t, []string{TFStateBackendStage, TFPhaseInit, TFPhasePlan, TFPhaseImport, TFPhaseApply, TFPhaseDestroy}, checker.GetStages())
+}
+
+func TestSetPhasesInvalid(t *testing.T) {
+	checker := &terraformDeploymentConfig{}
+
+	err := checker.SetPhases([]string{"destroy"})
+	assert.NotNil(t, err)
+
+	err = checker.SetPhases([]string{"apply", "refresh"})
+	assert.NotNil(t, err)
+}
+
+func TestSetPlanOnly(t *testing.T) {
+	state, err := loadTerraformRunState(newTestRunDir(t))
+	assert.Nil(t, err)
+	checker := &terraformDeploymentConfig{destroyPhase: true, state: state}
+
+	checker.SetPlanOnly(true)
+	assert.Equal(t, []string{TFStateBackendStage, TFPhaseInit, TFPhasePlan}, checker.GetStages())
+
+	// a plan only run is done once the plan succeeds, and cleaning up does not destroy the existing infrastructure
+	for _, phase := range []string{TFPhaseInit, TFPhasePlan, TFPhaseDone} {
+		err = state.transition(phase)
+		assert.Nil(t, err)
+	}
+	err = checker.CleanDeployment(domain.MPFConfig{})
+	assert.Nil(t, err)
+	assert.Equal(t, TFPhaseInit, state.Phase)
+}
+
+func TestRunWithJSONOutputDoesNotWriteToStdout(t *testing.T) {
+	tf, err := tfexec.NewTerraform(t.TempDir(), "terraform")
+	assert.Nil(t, err)
+
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	assert.Nil(t, err)
+	stdout := os.Stdout
+	os.Stdout = stdoutWriter
+	t.Cleanup(func() { os.Stdout = stdout })
+
+	checker := &terraformDeploymentConfig{}
+	for _, level := range []log.Level{log.WarnLevel, log.DebugLevel} {
+		logLevel := log.GetLevel()
+		log.SetLevel(level)
+		output, err := checker.runWithJSONOutput(tf, func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, `{"@level":"error","type":"diagnostic"}`)
+			return err
+		})
+		log.SetLevel(logLevel)
+		assert.Nil(t, err)
+		assert.Equal(t, "{\"@level\":\"error\",\"type\":\"diagnostic\"}\n", output.String())
+	}
+
+	// the output of MPF on stdout, such as the JSON output, is not mixed with the output of terraform
+	stdoutWriter.Close()
+	written, err := io.ReadAll(stdoutReader)
+	assert.Nil(t, err)
+	assert.Empty(t, written)
+}
@@ -0,0 +1,120 @@
+package terraform
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TerraformDiagnostic is an error diagnostic of the terraform machine readable UI output, with the address of the resource it is reported for
+type TerraformDiagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	Address  string `json:"address"`
+}
+
+type terraformJSONMessage struct {
+	Type       string              `json:"type"`
+	Diagnostic TerraformDiagnostic `json:"diagnostic"`
+}
+
+var tfExistingResourceIDRegex = regexp.MustCompile(`A resource with the ID "([^"]+)" already exists`)
+
+// ParseTerraformJSONDiagnostics returns the error diagnostics of the terraform -json output, lines which are not JSON messages are skipped
+func ParseTerraformJSONDiagnostics(r io.Reader) ([]TerraformDiagnostic, error) {
+	var diagnostics []TerraformDiagnostic
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var message terraformJSONMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			log.Debugf("skipping terraform output line which is not a JSON message: %s\n", scanner.Text())
+			continue
+		}
+
+		if message.Type == "diagnostic" && message.Diagnostic.Severity == "error" {
+			diagnostics = append(diagnostics, message.Diagnostic)
+		}
+	}
+	return diagnostics, scanner.Err()
+}
+
+// String formats the diagnostic as terraform prints it, so that it can be parsed by the authorization error parsers
+func (d TerraformDiagnostic) String() string {
+	var sb strings.Builder
+	sb.WriteString("Error: " + d.Summary + "\n\n")
+	if d.Detail != "" {
+		sb.WriteString(d.Detail + "\n\n")
+	}
+	if d.Address != "" {
+		sb.WriteString("  with " + d.Address + ",\n\n")
+	}
+	return sb.String()
+}
+
+func (d TerraformDiagnostic) contains(substr string) bool {
+	return strings.Contains(d.Summary, substr) || strings.Contains(d.Detail, substr)
+}
+
+// filterDiagnostics returns the diagnostics which contain the text in the summary or the detail
+func filterDiagnostics(diagnostics []TerraformDiagnostic, substr string) []TerraformDiagnostic {
+	var filtered []TerraformDiagnostic
+	for _, diagnostic := range diagnostics {
+		if diagnostic.contains(substr) {
+			filtered = append(filtered, diagnostic)
+		}
+	}
+	return filtered
+}
+
+func formatDiagnostics(diagnostics []TerraformDiagnostic) string {
+	var sb strings.Builder
+	for _, diagnostic := range diagnostics {
+		sb.WriteString(diagnostic.String())
+	}
+	return sb.String()
+}
+
+// GetAddressAndResourceIDFromExistingResourceDiagnostics returns the resource IDs of the existing resource diagnostics by resource address
+func GetAddressAndResourceIDFromExistingResourceDiagnostics(diagnostics []TerraformDiagnostic) (map[string]string, error) {
+	resMap := make(map[string]string)
+	for _, diagnostic := range filterDiagnostics(diagnostics, TFExistingResourceErrorMsg) {
+		match := tfExistingResourceIDRegex.FindStringSubmatch(diagnostic.Summary + diagnostic.Detail)
+		if len(match) != 2 {
+			log.Warnf("no resource ID found in existing resource diagnostic: %s\n", diagnostic.Summary)
+			continue
+		}
+		if diagnostic.Address == "" {
+			log.Warnf("no resource address in existing resource diagnostic for resource %s\n", match[1])
+			continue
+		}
+		resMap[diagnostic.Address] = match[1]
+	}
+
+	if len(resMap) == 0 {
+		return nil, errors.New("No existing resources found in terraform diagnostics")
+	}
+	return resMap, nil
+}
+
+// getDiagnosticsOrError returns the error diagnostics of the -json output, or when terraform failed without
+// reporting a diagnostic, for example when the configuration can not be loaded, a diagnostic with the error
+func getDiagnosticsOrError(output io.Reader, err error) []TerraformDiagnostic {
+	diagnostics, parseErr := ParseTerraformJSONDiagnostics(output)
+	if parseErr != nil {
+		log.Warnf("error parsing terraform JSON output: %s\n", parseErr)
+	}
+
+	if len(diagnostics) == 0 {
+		return []TerraformDiagnostic{{Severity: "error", Summary: fmt.Sprint(err)}}
+	}
+	return diagnostics
+}
@@ -0,0 +1,74 @@
+package terraform
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+const tfApplyJSONOutput = `{"@level":"info","@message":"Terraform 1.9.5","@module":"terraform.ui","type":"version","terraform":"1.9.5","ui":"1.2"}
+{"@level":"info","@message":"azurerm_resource_group.rg: Creating...","@module":"terraform.ui","type":"apply_start"}
+{"@level":"error","@message":"Error: creating Virtual Network","@module":"terraform.ui","type":"diagnostic","diagnostic":{"severity":"error","summary":"creating Virtual Network (Subscription: \"SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS\"\nResource Group Name: \"rg-mpf\"\nVirtual Network Name: \"vnet-mpf\"): unexpected status 403 (403 Forbidden) with error: AuthorizationFailed: The client 'ddfcf162-2cf2-40cf-bd4a-49a63e248436' with object id 'ddfcf162-2cf2-40cf-bd4a-49a63e248436' does not have authorization to perform action 'Microsoft.Network/virtualNetworks/write' over scope '/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/rg-mpf/providers/Microsoft.Network/virtualNetworks/vnet-mpf' or the scope is invalid. If access was recently granted, please refresh your credentials.","detail":"","address":"azurerm_virtual_network.vnet"}}
+{"@level":"error","@message":"Error: A resource with the ID already exists","@module":"terraform.ui","type":"diagnostic","diagnostic":{"severity":"error","summary":"A resource with the ID \"/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/rg-mpf/providers/Microsoft.ContainerRegistry/registries/acrmpf\" already exists - to be managed via Terraform this resource needs to be imported into the State. Please see the resource documentation for \"azurerm_container_registry\" for more information.","detail":"","address":"module.core.azurerm_container_registry.this"}}
+{"@level":"warn","@message":"Warning: Argument is deprecated","@module":"terraform.ui","type":"diagnostic","diagnostic":{"severity":"warning","summary":"Argument is deprecated","detail":""}}
+`
+
+func TestParseTerraformJSONDiagnostics(t *testing.T) {
+	diagnostics, err := ParseTerraformJSONDiagnostics(strings.NewReader(tfApplyJSONOutput))
+	assert.Nil(t, err)
+	assert.Len(t, diagnostics, 2)
+	assert.Equal(t, "azurerm_virtual_network.vnet", diagnostics[0].Address)
+
+	authDiagnostics := filterDiagnostics(diagnostics, "Authorization")
+	assert.Len(t, authDiagnostics, 1)
+
+	scopePermissions, err := domain.GetScopePermissionsFromAuthError(formatDiagnostics(authDiagnostics))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/write"}, scopePermissions["/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/rg-mpf/providers/Microsoft.Network/virtualNetworks/vnet-mpf"])
+}
+
+func TestGetAddressAndResourceIDFromExistingResourceDiagnostics(t *testing.T) {
+	diagnostics, err := ParseTerraformJSONDiagnostics(strings.NewReader(tfApplyJSONOutput))
+	assert.Nil(t, err)
+
+	existingResources, err := GetAddressAndResourceIDFromExistingResourceDiagnostics(diagnostics)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"module.core.azurerm_container_registry.this": "/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/rg-mpf/providers/Microsoft.ContainerRegistry/registries/acrmpf",
+	}, existingResources)
+
+	// the formatted diagnostics can be parsed by the human readable parser as well
+	existingResources, err = GetAddressAndResourceIDFromExistingResourceError(formatDiagnostics(filterDiagnostics(diagnostics, TFExistingResourceErrorMsg)))
+	assert.Nil(t, err)
+	assert.Len(t, existingResources, 1)
+}
+
+func TestGetDiagnosticsOrErrorWithoutJSONOutput(t *testing.T) {
+	diagnostics := getDiagnosticsOrError(strings.NewReader("exit status 1"), errors.New("Error: Failed to load configuration"))
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, "Error: Failed to load configuration", diagnostics[0].Summary)
+}
+
+const tfApplyAzapiAzureadJSONOutput = `{"@level":"error","@message":"Error: Failed to create/update resource","@module":"terraform.ui","type":"diagnostic","diagnostic":{"severity":"error","summary":"Failed to create/update resource","detail":"creating/updating Resource: (ResourceId \"/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/rg-mpf/providers/Microsoft.App/managedEnvironments/env-mpf\" / Api Version \"2024-03-01\"): PUT https://management.azure.com/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/rg-mpf/providers/Microsoft.App/managedEnvironments/env-mpf\n--------------------------------------------------------------------------------\nRESPONSE 403: 403 Forbidden\nERROR CODE: AuthorizationFailed\n--------------------------------------------------------------------------------\n{\n  \"error\": {\n    \"code\": \"AuthorizationFailed\",\n    \"message\": \"The client 'ddfcf162-2cf2-40cf-bd4a-49a63e248436' with object id 'ddfcf162-2cf2-40cf-bd4a-49a63e248436' does not have authorization to perform action 'Microsoft.App/managedEnvironments/write' over scope '/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/rg-mpf/providers/Microsoft.App/managedEnvironments/env-mpf' or the scope is invalid. If access was recently granted, please refresh your credentials.\"\n  }\n}\n--------------------------------------------------------------------------------\n","address":"azapi_resource.env"}}
+{"@level":"error","@message":"Error: Could not create group","@module":"terraform.ui","type":"diagnostic","diagnostic":{"severity":"error","summary":"Could not create group","detail":"unexpected status 403 with OData error: Authorization_RequestDenied: Insufficient privileges to complete the operation.","address":"azuread_group.admins"}}
+`
+
+func TestParseAzapiAndAzureadJSONDiagnostics(t *testing.T) {
+	diagnostics, err := ParseTerraformJSONDiagnostics(strings.NewReader(tfApplyAzapiAzureadJSONOutput))
+	assert.Nil(t, err)
+
+	authDiagnostics := filterDiagnostics(diagnostics, "Authorization")
+	assert.Len(t, authDiagnostics, 2)
+	authErrMesg := formatDiagnostics(authDiagnostics)
+
+	scopePermissions, err := domain.GetScopePermissionsFromAuthError(authErrMesg)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.App/managedEnvironments/write"}, scopePermissions["/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/rg-mpf/providers/Microsoft.App/managedEnvironments/env-mpf"])
+
+	graphPermissions, err := domain.GetGraphPermissionsFromAuthError(authErrMesg)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Group.ReadWrite.All"}, graphPermissions)
+}
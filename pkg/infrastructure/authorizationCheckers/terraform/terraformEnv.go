@@ -0,0 +1,93 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	tfPluginCacheDirEnvVar = "TF_PLUGIN_CACHE_DIR"
+	tfDataDirEnvVar        = "TF_DATA_DIR"
+)
+
+// The environment variables of the environment MPF runs in which are passed through to terraform, in addition to the
+// credentials of the service principal, so that the CLI configuration, plugin cache and proxy settings are used.
+// TF_DATA_DIR is only passed through when it is selected, so that the initialized data directory of the environment,
+// with its backend configuration and state, is not used by the runs of MPF
+var defaultTFEnvPassthrough = []string{
+	"HOME",
+	tfPluginCacheDirEnvVar,
+	"TF_CLI_CONFIG_FILE",
+	"HTTP_PROXY",
+	"HTTPS_PROXY",
+	"NO_PROXY",
+	"SSL_CERT_FILE",
+	"SSL_CERT_DIR",
+}
+
+// GetPluginCacheDir returns the provider plugin cache directory managed by MPF, which is shared by all runs,
+// so that the providers are downloaded once
+func GetPluginCacheDir() (string, error) {
+	baseDir, err := os.UserCacheDir()
+	if err != nil {
+		baseDir = os.TempDir()
+	}
+
+	pluginCacheDir := filepath.Join(baseDir, "az-mpf", "terraform", "plugin-cache")
+	err = os.MkdirAll(pluginCacheDir, 0700)
+	if err != nil {
+		return "", err
+	}
+	return pluginCacheDir, nil
+}
+
+// SetEnvPassthrough sets the names of the environment variables passed through to terraform, in addition to the default ones
+func (a *terraformDeploymentConfig) SetEnvPassthrough(names []string) {
+	a.envPassthrough = names
+}
+
+// getPassthroughEnv returns the environment variables passed through to terraform which are set, with the plugin cache
+// directory of MPF when no plugin cache directory is set. Variables managed by terraform-exec are not passed through
+func (a *terraformDeploymentConfig) getPassthroughEnv() map[string]string {
+	env := make(map[string]string)
+	for _, name := range append(append([]string{}, defaultTFEnvPassthrough...), a.envPassthrough...) {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+	a.setDefaultPluginCacheDir(env)
+
+	for _, name := range tfexec.ProhibitedEnv(env) {
+		log.Warnf("environment variable %s is managed by MPF, and is not passed through to terraform\n", name)
+	}
+	return tfexec.CleanEnv(env)
+}
+
+// getAdminEnv returns the environment MPF runs in, for the terraform commands run with its credentials,
+// with the plugin cache directory of MPF when no plugin cache directory is set. TF_DATA_DIR is only kept when it is
+// passed through, so that the commands use the same data directory as the commands run with the service principal
+func (a *terraformDeploymentConfig) getAdminEnv() map[string]string {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		name, value, found := strings.Cut(kv, "=")
+		if found {
+			env[name] = value
+		}
+	}
+	if !slices.Contains(a.envPassthrough, tfDataDirEnvVar) {
+		delete(env, tfDataDirEnvVar)
+	}
+	a.setDefaultPluginCacheDir(env)
+	return tfexec.CleanEnv(env)
+}
+
+func (a *terraformDeploymentConfig) setDefaultPluginCacheDir(env map[string]string) {
+	if _, ok := env[tfPluginCacheDirEnvVar]; !ok && a.pluginCacheDir != "" {
+		env[tfPluginCacheDirEnvVar] = a.pluginCacheDir
+	}
+}
@@ -0,0 +1,56 @@
+package terraform
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPassthroughEnv(t *testing.T) {
+	t.Setenv("HOME", "/home/mpf")
+	t.Setenv("TF_PLUGIN_CACHE_DIR", "")
+	t.Setenv("MPF_TEST_PASSTHROUGH", "value")
+	t.Setenv("TF_LOG", "TRACE")
+	t.Setenv("TF_DATA_DIR", "/home/mpf/.terraform-data")
+
+	checker := &terraformDeploymentConfig{pluginCacheDir: "/cache/plugins"}
+	checker.SetEnvPassthrough([]string{"MPF_TEST_PASSTHROUGH", "TF_LOG"})
+
+	env := checker.getPassthroughEnv()
+	assert.Equal(t, "/home/mpf", env["HOME"])
+	assert.Equal(t, "value", env["MPF_TEST_PASSTHROUGH"])
+	assert.NotContains(t, env, "TF_LOG")
+	// a plugin cache directory set in the environment is used instead of the one of MPF
+	assert.Equal(t, "", env["TF_PLUGIN_CACHE_DIR"])
+	// the data directory is only passed through when it is selected
+	assert.NotContains(t, env, "TF_DATA_DIR")
+
+	checker.SetEnvPassthrough([]string{"TF_DATA_DIR"})
+	env = checker.getPassthroughEnv()
+	assert.Equal(t, "/home/mpf/.terraform-data", env["TF_DATA_DIR"])
+}
+
+func TestGetAdminEnvWithDefaultPluginCacheDir(t *testing.T) {
+	t.Setenv("MPF_TEST_ADMIN", "value")
+	// unset for the test, and restored afterwards
+	t.Setenv("TF_PLUGIN_CACHE_DIR", "")
+	os.Unsetenv("TF_PLUGIN_CACHE_DIR")
+
+	checker := &terraformDeploymentConfig{pluginCacheDir: "/cache/plugins"}
+	env := checker.getAdminEnv()
+	assert.Equal(t, "value", env["MPF_TEST_ADMIN"])
+	assert.Equal(t, "/cache/plugins", env["TF_PLUGIN_CACHE_DIR"])
+}
+
+func TestGetAdminEnvWithDataDir(t *testing.T) {
+	t.Setenv("TF_DATA_DIR", "/home/mpf/.terraform-data")
+
+	checker := &terraformDeploymentConfig{}
+	env := checker.getAdminEnv()
+	assert.NotContains(t, env, "TF_DATA_DIR")
+
+	checker.SetEnvPassthrough([]string{"TF_DATA_DIR"})
+	env = checker.getAdminEnv()
+	assert.Equal(t, "/home/mpf/.terraform-data", env["TF_DATA_DIR"])
+}
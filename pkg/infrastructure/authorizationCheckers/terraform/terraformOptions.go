@@ -0,0 +1,165 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-exec/tfexec"
+	log "github.com/sirupsen/logrus"
+)
+
+// TerraformOptions are the terraform CLI options passed through to init, plan, apply, import and destroy.
+// Each command gets the options it supports from the builder methods
+type TerraformOptions struct {
+	VarFiles           []string
+	Vars               []string
+	Targets            []string
+	Parallelism        int
+	NoRefresh          bool
+	LockTimeout        string
+	Workspace          string
+	BackendConfigFiles []string
+	PluginDirs         []string
+}
+
+// Validate checks that the variables are key=value assignments
+func (o TerraformOptions) Validate() error {
+	for _, v := range o.Vars {
+		key, _, found := strings.Cut(v, "=")
+		if !found || key == "" {
+			return fmt.Errorf("invalid terraform variable %s, expected key=value", v)
+		}
+	}
+	if o.Parallelism < 0 {
+		return fmt.Errorf("invalid terraform parallelism %d", o.Parallelism)
+	}
+	return nil
+}
+
+func (o TerraformOptions) initOptions() []tfexec.InitOption {
+	var initOptions []tfexec.InitOption
+	for _, backendConfigFile := range o.BackendConfigFiles {
+		initOptions = append(initOptions, tfexec.BackendConfig(backendConfigFile))
+	}
+	// providers are installed from the local mirrors only, for environments without access to the registry
+	for _, pluginDir := range o.PluginDirs {
+		initOptions = append(initOptions, tfexec.PluginDir(pluginDir))
+	}
+	if o.LockTimeout != "" {
+		initOptions = append(initOptions, tfexec.LockTimeout(o.LockTimeout))
+	}
+	return initOptions
+}
+
+func (o TerraformOptions) planOptions(planFilePath string) []tfexec.PlanOption {
+	planOptions := []tfexec.PlanOption{tfexec.Out(planFilePath)}
+	for _, varFile := range o.VarFiles {
+		planOptions = append(planOptions, tfexec.VarFile(varFile))
+	}
+	for _, v := range o.Vars {
+		planOptions = append(planOptions, tfexec.Var(v))
+	}
+	for _, target := range o.Targets {
+		planOptions = append(planOptions, tfexec.Target(target))
+	}
+	if o.Parallelism > 0 {
+		planOptions = append(planOptions, tfexec.Parallelism(o.Parallelism))
+	}
+	if o.NoRefresh {
+		planOptions = append(planOptions, tfexec.Refresh(false))
+	}
+	if o.LockTimeout != "" {
+		planOptions = append(planOptions, tfexec.LockTimeout(o.LockTimeout))
+	}
+	return planOptions
+}
+
+// applyOptions returns the options to apply the saved plan, the variables, targets and refresh are part of the plan
+func (o TerraformOptions) applyOptions(planFilePath string) []tfexec.ApplyOption {
+	applyOptions := []tfexec.ApplyOption{tfexec.DirOrPlan(planFilePath)}
+	if o.Parallelism > 0 {
+		applyOptions = append(applyOptions, tfexec.Parallelism(o.Parallelism))
+	}
+	if o.LockTimeout != "" {
+		applyOptions = append(applyOptions, tfexec.LockTimeout(o.LockTimeout))
+	}
+	return applyOptions
+}
+
+// applyWithoutPlanOptions returns the options to apply the module without a saved plan
+func (o TerraformOptions) applyWithoutPlanOptions() []tfexec.ApplyOption {
+	var applyOptions []tfexec.ApplyOption
+	for _, varFile := range o.VarFiles {
+		applyOptions = append(applyOptions, tfexec.VarFile(varFile))
+	}
+	for _, v := range o.Vars {
+		applyOptions = append(applyOptions, tfexec.Var(v))
+	}
+	for _, target := range o.Targets {
+		applyOptions = append(applyOptions, tfexec.Target(target))
+	}
+	if o.Parallelism > 0 {
+		applyOptions = append(applyOptions, tfexec.Parallelism(o.Parallelism))
+	}
+	if o.LockTimeout != "" {
+		applyOptions = append(applyOptions, tfexec.LockTimeout(o.LockTimeout))
+	}
+	return applyOptions
+}
+
+func (o TerraformOptions) importOptions() []tfexec.ImportOption {
+	var importOptions []tfexec.ImportOption
+	for _, varFile := range o.VarFiles {
+		importOptions = append(importOptions, tfexec.VarFile(varFile))
+	}
+	for _, v := range o.Vars {
+		importOptions = append(importOptions, tfexec.Var(v))
+	}
+	if o.LockTimeout != "" {
+		importOptions = append(importOptions, tfexec.LockTimeout(o.LockTimeout))
+	}
+	return importOptions
+}
+
+func (o TerraformOptions) destroyOptions() []tfexec.DestroyOption {
+	var destroyOptions []tfexec.DestroyOption
+	for _, varFile := range o.VarFiles {
+		destroyOptions = append(destroyOptions, tfexec.VarFile(varFile))
+	}
+	for _, v := range o.Vars {
+		destroyOptions = append(destroyOptions, tfexec.Var(v))
+	}
+	for _, target := range o.Targets {
+		destroyOptions = append(destroyOptions, tfexec.Target(target))
+	}
+	if o.Parallelism > 0 {
+		destroyOptions = append(destroyOptions, tfexec.Parallelism(o.Parallelism))
+	}
+	if o.NoRefresh {
+		destroyOptions = append(destroyOptions, tfexec.Refresh(false))
+	}
+	if o.LockTimeout != "" {
+		destroyOptions = append(destroyOptions, tfexec.LockTimeout(o.LockTimeout))
+	}
+	return destroyOptions
+}
+
+// terraformInit runs terraform init with the backend configuration, and selects the workspace, creating it if it does not exist
+func (o TerraformOptions) terraformInit(ctx context.Context, tf *tfexec.Terraform) error {
+	err := tf.Init(ctx, o.initOptions()...)
+	if err != nil {
+		return err
+	}
+
+	if o.Workspace == "" {
+		return nil
+	}
+
+	err = tf.WorkspaceSelect(ctx, o.Workspace)
+	if err != nil {
+		log.Infof("creating terraform workspace %s\n", o.Workspace)
+		return tf.WorkspaceNew(ctx, o.Workspace)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerraformOptionsValidate(t *testing.T) {
+	options := TerraformOptions{Vars: []string{"location=eastus2", "tags={\"env\"=\"mpf\"}"}}
+	assert.Nil(t, options.Validate())
+
+	options = TerraformOptions{Vars: []string{"location"}}
+	assert.NotNil(t, options.Validate())
+
+	options = TerraformOptions{Vars: []string{"=eastus2"}}
+	assert.NotNil(t, options.Validate())
+
+	options = TerraformOptions{Parallelism: -1}
+	assert.NotNil(t, options.Validate())
+}
+
+func TestTerraformOptionsBuilders(t *testing.T) {
+	options := TerraformOptions{
+		VarFiles:           []string{"a.tfvars", "b.tfvars"},
+		Vars:               []string{"location=eastus2"},
+		Targets:            []string{"module.network", "module.aks"},
+		Parallelism:        4,
+		NoRefresh:          true,
+		LockTimeout:        "60s",
+		BackendConfigFiles: []string{"backend.hcl"},
+	}
+
+	assert.Len(t, options.initOptions(), 2)
+	// out, 2 var files, 1 var, 2 targets, parallelism, refresh, lock timeout
+	assert.Len(t, options.planOptions("mpf.tfplan"), 9)
+	// plan, parallelism, lock timeout
+	assert.Len(t, options.applyOptions("mpf.tfplan"), 3)
+	// 2 var files, 1 var, 2 targets, parallelism, lock timeout
+	assert.Len(t, options.applyWithoutPlanOptions(), 7)
+	// 2 var files, 1 var, lock timeout
+	assert.Len(t, options.importOptions(), 4)
+	assert.Len(t, options.destroyOptions(), 8)
+
+	assert.Len(t, TerraformOptions{PluginDirs: []string{"/mirror"}}.initOptions(), 1)
+	assert.Len(t, TerraformOptions{}.planOptions("mpf.tfplan"), 1)
+	assert.Len(t, TerraformOptions{}.destroyOptions(), 0)
+}
@@ -0,0 +1,44 @@
+package terraform
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/permissionPredictor"
+	log "github.com/sirupsen/logrus"
+)
+
+// PredictPermissions runs terraform plan, and predicts the permissions required to apply the plan and destroy the created resources.
+// The plan is run with the credentials of the environment MPF runs in, as the service principal has no permissions yet
+func (a *terraformDeploymentConfig) PredictPermissions() ([]string, error) {
+	tf, err := a.newAdminTerraform()
+	if err != nil {
+		return nil, fmt.Errorf("error running NewTerraform: %w", err)
+	}
+
+	err = a.options.terraformInit(a.ctx, tf)
+	if err != nil {
+		return nil, fmt.Errorf("error running Init: %w", err)
+	}
+
+	planDir, err := os.MkdirTemp("", "az-mpf-tfplan-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating plan directory: %w", err)
+	}
+	defer os.RemoveAll(planDir)
+
+	planFilePath := filepath.Join(planDir, "mpf.tfplan")
+	log.Infoln("running terraform plan to predict permissions")
+	_, err = tf.Plan(a.ctx, a.options.planOptions(planFilePath)...)
+	if err != nil {
+		return nil, fmt.Errorf("error running terraform plan: %w", err)
+	}
+
+	plan, err := tf.ShowPlanFile(a.ctx, planFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error running terraform show: %w", err)
+	}
+
+	return permissionPredictor.PredictTerraformPlanPermissions(plan), nil
+}
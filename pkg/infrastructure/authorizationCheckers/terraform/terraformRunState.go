@@ -0,0 +1,97 @@
+package terraform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
+	log "github.com/sirupsen/logrus"
+)
+
+const TFRunStateFileName = "terraformRunState.json"
+
+// The phases the terraform checker can move to from each phase. Every phase of the apply leg restarts with init on the next
+// check, the destroy phase is repeated until terraform destroy succeeds, and only a reset of the deployment leaves the destroy phase.
+// In plan only mode the run is done once the plan succeeds
+var validTFPhaseTransitions = map[string][]string{
+	"":             {TFPhaseInit},
+	TFPhaseInit:    {TFPhaseInit, TFPhasePlan},
+	TFPhasePlan:    {TFPhaseInit, TFPhaseApply, TFPhaseDone},
+	TFPhaseApply:   {TFPhaseInit, TFPhaseImport, TFPhaseDestroy, TFPhaseDone},
+	TFPhaseImport:  {TFPhaseInit},
+	TFPhaseDestroy: {TFPhaseDestroy, TFPhaseDone},
+	TFPhaseDone:    {TFPhaseInit},
+}
+
+// terraformRunState is the phase of the terraform checker. It is persisted in the run directory of MPF, which is resumable
+// in the destroy phase, so that the next run of a run interrupted in the destroy phase resumes with the destroy phase.
+// Runs whose terraform state does not outlive the run, such as runs in an isolated workspace, are not resumable
+type terraformRunState struct {
+	Phase     string
+	filePath  string
+	runDir    *mpfSharedUtils.RunDir
+	resumable bool
+}
+
+// loadTerraformRunState loads the state persisted in the run directory, a missing state starts with the init phase
+func loadTerraformRunState(runDir *mpfSharedUtils.RunDir) (*terraformRunState, error) {
+	state := &terraformRunState{
+		filePath:  filepath.Join(runDir.Path, TFRunStateFileName),
+		runDir:    runDir,
+		resumable: true,
+	}
+
+	content, err := os.ReadFile(state.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(content, state)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := validTFPhaseTransitions[state.Phase]; !ok {
+		return nil, fmt.Errorf("invalid terraform phase %s in %s", state.Phase, state.filePath)
+	}
+
+	if state.Phase == TFPhaseDestroy {
+		log.Infof("resuming terraform run in %s phase\n", state.Phase)
+	}
+	return state, nil
+}
+
+// transition moves to the phase, if it can be reached from the current phase, and persists the state
+func (s *terraformRunState) transition(phase string) error {
+	for _, validPhase := range validTFPhaseTransitions[s.Phase] {
+		if validPhase == phase {
+			s.Phase = phase
+			return s.save()
+		}
+	}
+	return fmt.Errorf("invalid terraform phase transition from %s to %s", s.Phase, phase)
+}
+
+// reset starts over with the init phase, once the deployment has been destroyed
+func (s *terraformRunState) reset() error {
+	s.Phase = TFPhaseInit
+	return s.save()
+}
+
+func (s *terraformRunState) save() error {
+	content, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(s.filePath, content, 0600)
+	if err != nil {
+		return err
+	}
+	return s.runDir.SetResumable(s.resumable && s.Phase == TFPhaseDestroy)
+}
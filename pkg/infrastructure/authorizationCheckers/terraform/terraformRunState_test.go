@@ -0,0 +1,147 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRunDir(t *testing.T) *mpfSharedUtils.RunDir {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	runDir, err := mpfSharedUtils.NewRunDir("terraform", t.TempDir())
+	assert.Nil(t, err)
+	return runDir
+}
+
+func TestTerraformRunStateTransitions(t *testing.T) {
+	runDir := newTestRunDir(t)
+
+	state, err := loadTerraformRunState(runDir)
+	assert.Nil(t, err)
+	assert.Equal(t, "", state.Phase)
+
+	for _, phase := range []string{TFPhaseInit, TFPhasePlan, TFPhaseApply, TFPhaseImport, TFPhaseInit, TFPhasePlan, TFPhaseApply, TFPhaseDestroy, TFPhaseDestroy} {
+		err = state.transition(phase)
+		assert.Nil(t, err)
+	}
+
+	// an interrupted run resumes in the destroy phase
+	resumedState, err := loadTerraformRunState(runDir)
+	assert.Nil(t, err)
+	assert.Equal(t, TFPhaseDestroy, resumedState.Phase)
+
+	err = resumedState.transition(TFPhaseInit)
+	assert.NotNil(t, err)
+
+	err = resumedState.transition(TFPhaseDone)
+	assert.Nil(t, err)
+
+	err = resumedState.reset()
+	assert.Nil(t, err)
+	assert.Equal(t, TFPhaseInit, resumedState.Phase)
+}
+
+func TestTerraformRunStateInvalidTransitions(t *testing.T) {
+	state, err := loadTerraformRunState(newTestRunDir(t))
+	assert.Nil(t, err)
+
+	err = state.transition(TFPhaseApply)
+	assert.NotNil(t, err)
+
+	err = state.transition(TFPhaseInit)
+	assert.Nil(t, err)
+
+	err = state.transition(TFPhaseDestroy)
+	assert.NotNil(t, err)
+}
+
+func TestTerraformRunStateResumesInterruptedDestroy(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	moduleDir := t.TempDir()
+
+	runDir, err := mpfSharedUtils.NewRunDir("terraform", moduleDir)
+	assert.Nil(t, err)
+	state, err := loadTerraformRunState(runDir)
+	assert.Nil(t, err)
+	for _, phase := range []string{TFPhaseInit, TFPhasePlan, TFPhaseApply, TFPhaseDestroy} {
+		err = state.transition(phase)
+		assert.Nil(t, err)
+	}
+
+	// a concurrent run does not resume the destroy phase of a run in progress
+	concurrentRunDir, err := mpfSharedUtils.NewRunDir("terraform", moduleDir)
+	assert.Nil(t, err)
+	assert.NotEqual(t, runDir.Path, concurrentRunDir.Path)
+	concurrentState, err := loadTerraformRunState(concurrentRunDir)
+	assert.Nil(t, err)
+	assert.Equal(t, "", concurrentState.Phase)
+	concurrentRunDir.Release()
+
+	// the next run resumes the destroy phase of the interrupted run
+	runDir.Release()
+	resumedRunDir, err := mpfSharedUtils.NewRunDir("terraform", moduleDir)
+	assert.Nil(t, err)
+	assert.Equal(t, runDir.Path, resumedRunDir.Path)
+	resumedState, err := loadTerraformRunState(resumedRunDir)
+	assert.Nil(t, err)
+	assert.Equal(t, TFPhaseDestroy, resumedState.Phase)
+
+	// once the deployment is destroyed, the run is no longer resumed
+	err = resumedState.transition(TFPhaseDone)
+	assert.Nil(t, err)
+	resumedRunDir.Release()
+	nextRunDir, err := mpfSharedUtils.NewRunDir("terraform", moduleDir)
+	assert.Nil(t, err)
+	assert.NotEqual(t, runDir.Path, nextRunDir.Path)
+}
+
+func TestTerraformRunStateDoesNotResumeIsolatedRun(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	moduleDir := t.TempDir()
+
+	runDir, err := mpfSharedUtils.NewRunDir("terraform", moduleDir)
+	assert.Nil(t, err)
+	state, err := loadTerraformRunState(runDir)
+	assert.Nil(t, err)
+	checker := &terraformDeploymentConfig{state: state}
+	err = checker.SetIsolated(true)
+	assert.Nil(t, err)
+	for _, phase := range []string{TFPhaseInit, TFPhasePlan, TFPhaseApply, TFPhaseDestroy} {
+		err = state.transition(phase)
+		assert.Nil(t, err)
+	}
+
+	// the state of the isolated workspace is removed with the workspace, so the interrupted run is not resumed
+	runDir.Release()
+	nextRunDir, err := mpfSharedUtils.NewRunDir("terraform", moduleDir)
+	assert.Nil(t, err)
+	assert.NotEqual(t, runDir.Path, nextRunDir.Path)
+	nextState, err := loadTerraformRunState(nextRunDir)
+	assert.Nil(t, err)
+	assert.Equal(t, "", nextState.Phase)
+}
+
+func TestTerraformRunStateInterruptedDestroyIsNotResumedInIsolatedWorkspace(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	moduleDir := t.TempDir()
+
+	runDir, err := mpfSharedUtils.NewRunDir("terraform", moduleDir)
+	assert.Nil(t, err)
+	state, err := loadTerraformRunState(runDir)
+	assert.Nil(t, err)
+	for _, phase := range []string{TFPhaseInit, TFPhasePlan, TFPhaseApply, TFPhaseDestroy} {
+		err = state.transition(phase)
+		assert.Nil(t, err)
+	}
+	runDir.Release()
+
+	resumedRunDir, err := mpfSharedUtils.NewRunDir("terraform", moduleDir)
+	assert.Nil(t, err)
+	assert.Equal(t, runDir.Path, resumedRunDir.Path)
+	resumedState, err := loadTerraformRunState(resumedRunDir)
+	assert.Nil(t, err)
+	checker := &terraformDeploymentConfig{state: resumedState}
+	err = checker.SetIsolated(true)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,36 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var tfVariableDeclarationRegex = regexp.MustCompile(`(?m)^\s*variable\s+"?([A-Za-z_][A-Za-z0-9_-]*)"?\s*\{`)
+
+// GetDeclaredVariables returns the names of the variables declared by the root module in the directory.
+// Terraform fails when a -var is passed for a variable which is not declared
+func GetDeclaredVariables(moduleDir string) (map[string]bool, error) {
+	entries, err := os.ReadDir(moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	variables := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tf") {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(moduleDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range tfVariableDeclarationRegex.FindAllStringSubmatch(string(content), -1) {
+			variables[match[1]] = true
+		}
+	}
+	return variables, nil
+}
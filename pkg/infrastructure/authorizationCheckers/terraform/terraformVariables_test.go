@@ -0,0 +1,32 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDeclaredVariables(t *testing.T) {
+	moduleDir := t.TempDir()
+	variables := `variable "mpf_resource_group_name" {
+  type = string
+}
+
+variable mpf_location {
+  type    = string
+  default = "eastus"
+}
+
+# variable "commented_out" {}
+`
+	assert.Nil(t, os.WriteFile(filepath.Join(moduleDir, "variables.tf"), []byte(variables), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(moduleDir, "main.tf"), []byte(`resource "azurerm_resource_group" "rg" { name = var.mpf_resource_group_name }`), 0644))
+	assert.Nil(t, os.MkdirAll(filepath.Join(moduleDir, "modules", "child"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(moduleDir, "modules", "child", "variables.tf"), []byte(`variable "child_variable" {}`), 0644))
+
+	declared, err := GetDeclaredVariables(moduleDir)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{"mpf_resource_group_name": true, "mpf_location": true}, declared)
+}
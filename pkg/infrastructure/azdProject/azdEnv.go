@@ -0,0 +1,94 @@
+package azdProject
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ${VAR}, ${VAR=default}, ${VAR-default}, ${VAR:=default} and ${VAR:-default} references, as substituted by azd
+var envReferenceRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?[=-])([^}]*))?\}`)
+
+// ReadEnvFile reads the KEY=value lines of the .env file of an azd environment. Values may be quoted, and the
+// escape sequences of double quoted values are unescaped
+func ReadEnvFile(envFilePath string) (map[string]string, error) {
+	file, err := os.Open(envFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading azd environment file: %w", err)
+	}
+	defer file.Close()
+
+	env := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid line %d of azd environment file %s, expected KEY=value", lineNumber, envFilePath)
+		}
+		env[strings.TrimSpace(key)] = getEnvValue(strings.TrimSpace(value))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading azd environment file: %w", err)
+	}
+	return env, nil
+}
+
+func getEnvValue(value string) string {
+	switch {
+	case len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`):
+		replacer := strings.NewReplacer(`\"`, `"`, `\\`, `\`, `\n`, "\n", `\r`, "\r", `\t`, "\t")
+		return replacer.Replace(value[1 : len(value)-1])
+	case len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'"):
+		return value[1 : len(value)-1]
+	}
+
+	// unquoted values end at an inline comment
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
+
+// SubstituteEnv replaces the ${VAR} references of the content with the values of the environment. Variables which are
+// not set are replaced with their default value, or an empty string, and returned sorted. With escapeJSON the values are
+// escaped to be used in JSON strings
+func SubstituteEnv(content string, env map[string]string, escapeJSON bool) (string, []string) {
+	missing := map[string]bool{}
+
+	substituted := envReferenceRegex.ReplaceAllStringFunc(content, func(reference string) string {
+		match := envReferenceRegex.FindStringSubmatch(reference)
+		key, operator, defaultValue := match[1], match[2], match[3]
+
+		value, ok := env[key]
+		switch {
+		case operator == "" && !ok:
+			missing[key] = true
+		case strings.HasPrefix(operator, ":") && value == "", !strings.HasPrefix(operator, ":") && operator != "" && !ok:
+			value = defaultValue
+		}
+
+		if escapeJSON {
+			escaped, _ := json.Marshal(value)
+			return string(escaped[1 : len(escaped)-1])
+		}
+		return value
+	})
+
+	missingKeys := make([]string, 0, len(missing))
+	for key := range missing {
+		missingKeys = append(missingKeys, key)
+	}
+	sort.Strings(missingKeys)
+	return substituted, missingKeys
+}
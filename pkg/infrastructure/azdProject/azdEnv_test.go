@@ -0,0 +1,81 @@
+package azdProject
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadEnvFile(t *testing.T) {
+	envFilePath := filepath.Join(t.TempDir(), ".env")
+	content := `# azd environment
+AZURE_ENV_NAME="mpf-dev"
+AZURE_LOCATION="eastus2"
+export AZURE_SUBSCRIPTION_ID=SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS # subscription
+SERVICE_WEB_SETTINGS="{\"tier\":\"basic\"}"
+SINGLE_QUOTED='a "quoted" value'
+EMPTY=
+`
+	assert.Nil(t, os.WriteFile(envFilePath, []byte(content), 0600))
+
+	env, err := ReadEnvFile(envFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"AZURE_ENV_NAME":        "mpf-dev",
+		"AZURE_LOCATION":        "eastus2",
+		"AZURE_SUBSCRIPTION_ID": "SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS",
+		"SERVICE_WEB_SETTINGS":  `{"tier":"basic"}`,
+		"SINGLE_QUOTED":         `a "quoted" value`,
+		"EMPTY":                 "",
+	}, env)
+}
+
+func TestReadEnvFileWithInvalidLine(t *testing.T) {
+	envFilePath := filepath.Join(t.TempDir(), ".env")
+	assert.Nil(t, os.WriteFile(envFilePath, []byte("AZURE_ENV_NAME=mpf-dev\nAZURE_LOCATION\n"), 0600))
+
+	_, err := ReadEnvFile(envFilePath)
+	assert.NotNil(t, err)
+}
+
+func TestSubstituteEnv(t *testing.T) {
+	env := map[string]string{
+		"AZURE_ENV_NAME": "mpf-dev",
+		"AZURE_LOCATION": "",
+		"TAGS":           `{"owner":"mpf"}`,
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+		missing  []string
+	}{
+		{"set", "${AZURE_ENV_NAME}", "mpf-dev", []string{}},
+		{"default of set", "${AZURE_ENV_NAME=other}", "mpf-dev", []string{}},
+		{"default of unset", "${AZURE_PRINCIPAL_ID=none}", "none", []string{}},
+		{"dash default of unset", "${AZURE_PRINCIPAL_ID-none}", "none", []string{}},
+		{"default of empty", "${AZURE_LOCATION=eastus}", "", []string{}},
+		{"colon default of empty", "${AZURE_LOCATION:=eastus}", "eastus", []string{}},
+		{"colon dash default of empty", "${AZURE_LOCATION:-eastus}", "eastus", []string{}},
+		{"unset", "${AZURE_PRINCIPAL_ID}-${AZURE_PRINCIPAL_ID}", "-", []string{"AZURE_PRINCIPAL_ID"}},
+		{"not a reference", "$AZURE_ENV_NAME", "$AZURE_ENV_NAME", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			substituted, missing := SubstituteEnv(tt.content, env, false)
+			assert.Equal(t, tt.expected, substituted)
+			assert.Equal(t, tt.missing, missing)
+		})
+	}
+}
+
+func TestSubstituteEnvEscapesJSON(t *testing.T) {
+	env := map[string]string{"TAGS": `{"owner":"mpf"}`}
+
+	substituted, _ := SubstituteEnv(`{"tags": {"value": "${TAGS}"}}`, env, true)
+	assert.Equal(t, `{"tags": {"value": "{\"owner\":\"mpf\"}"}}`, substituted)
+}
@@ -0,0 +1,205 @@
+package azdProject
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	AzureYamlFileName = "azure.yaml"
+
+	defaultInfraProvider = "bicep"
+	defaultInfraPath     = "infra"
+	defaultInfraModule   = "main"
+
+	azdDirName        = ".azure"
+	azdConfigFileName = "config.json"
+	azdEnvFileName    = ".env"
+
+	// The environment variables azd sets for the parameters of the infrastructure
+	EnvNameKey        = "AZURE_ENV_NAME"
+	LocationKey       = "AZURE_LOCATION"
+	SubscriptionIDKey = "AZURE_SUBSCRIPTION_ID"
+	PrincipalIDKey    = "AZURE_PRINCIPAL_ID"
+)
+
+type azureYaml struct {
+	Name  string         `yaml:"name"`
+	Infra azureYamlInfra `yaml:"infra"`
+}
+
+type azureYamlInfra struct {
+	Provider string `yaml:"provider"`
+	Path     string `yaml:"path"`
+	Module   string `yaml:"module"`
+}
+
+type azdConfig struct {
+	DefaultEnvironment string `json:"defaultEnvironment"`
+}
+
+// AzdProject is an Azure Developer CLI project, with the Bicep infrastructure of its azure.yaml, and the values of
+// the environment variables of one of its environments
+type AzdProject struct {
+	ProjectDir      string
+	Name            string
+	InfraPath       string
+	Module          string
+	EnvironmentName string
+	Env             map[string]string
+}
+
+// LoadAzdProject reads azure.yaml of the project, and the .azure/<environment>/.env file of the environment. When the
+// environment name is empty, AZURE_ENV_NAME or the default environment of .azure/config.json is used
+func LoadAzdProject(projectDir string, environmentName string) (*AzdProject, error) {
+	content, err := os.ReadFile(filepath.Join(projectDir, AzureYamlFileName))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s of azd project: %w", AzureYamlFileName, err)
+	}
+
+	var projectYaml azureYaml
+	err = yaml.Unmarshal(content, &projectYaml)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s of azd project: %w", AzureYamlFileName, err)
+	}
+
+	if projectYaml.Infra.Provider != "" && projectYaml.Infra.Provider != defaultInfraProvider {
+		return nil, fmt.Errorf("infra provider %s of azd project is not supported, only %s is", projectYaml.Infra.Provider, defaultInfraProvider)
+	}
+
+	project := &AzdProject{
+		ProjectDir: projectDir,
+		Name:       projectYaml.Name,
+		InfraPath:  projectYaml.Infra.Path,
+		Module:     projectYaml.Infra.Module,
+		Env:        map[string]string{},
+	}
+	if project.InfraPath == "" {
+		project.InfraPath = defaultInfraPath
+	}
+	if !filepath.IsAbs(project.InfraPath) {
+		project.InfraPath = filepath.Join(projectDir, project.InfraPath)
+	}
+	if project.Module == "" {
+		project.Module = defaultInfraModule
+	}
+
+	project.EnvironmentName, err = getEnvironmentName(projectDir, environmentName)
+	if err != nil {
+		return nil, err
+	}
+
+	envFilePath := filepath.Join(projectDir, azdDirName, project.EnvironmentName, azdEnvFileName)
+	if _, err := os.Stat(envFilePath); os.IsNotExist(err) {
+		log.Warnf("azd environment file %s does not exist, only the variables set by MPF are used\n", envFilePath)
+	} else {
+		project.Env, err = ReadEnvFile(envFilePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	project.Env[EnvNameKey] = project.EnvironmentName
+
+	return project, nil
+}
+
+// getEnvironmentName returns the environment name, AZURE_ENV_NAME, or the default environment of .azure/config.json
+func getEnvironmentName(projectDir string, environmentName string) (string, error) {
+	if environmentName != "" {
+		return environmentName, nil
+	}
+
+	if envName := os.Getenv(EnvNameKey); envName != "" {
+		return envName, nil
+	}
+
+	content, err := os.ReadFile(filepath.Join(projectDir, azdDirName, azdConfigFileName))
+	if err == nil {
+		var config azdConfig
+		err = json.Unmarshal(content, &config)
+		if err != nil {
+			return "", fmt.Errorf("error parsing %s of azd project: %w", azdConfigFileName, err)
+		}
+		if config.DefaultEnvironment != "" {
+			return config.DefaultEnvironment, nil
+		}
+	}
+
+	return "", fmt.Errorf("no azd environment is selected, set the environment name or create one with azd env new")
+}
+
+// BicepFilePath returns the path of the Bicep module of the infrastructure
+func (p *AzdProject) BicepFilePath() string {
+	return filepath.Join(p.InfraPath, p.Module+".bicep")
+}
+
+// ParametersFilePath returns the path of the <module>.parameters.json or <module>.bicepparam file of the infrastructure,
+// or an empty string if the module has no parameters file
+func (p *AzdProject) ParametersFilePath() string {
+	for _, fileName := range []string{p.Module + ".parameters.json", p.Module + ".bicepparam"} {
+		parametersFilePath := filepath.Join(p.InfraPath, fileName)
+		if _, err := os.Stat(parametersFilePath); err == nil {
+			return parametersFilePath
+		}
+	}
+	return ""
+}
+
+// SetEnv sets the value of the environment variable, overriding the value of the environment
+func (p *AzdProject) SetEnv(key string, value string) {
+	p.Env[key] = value
+}
+
+// SetEnvDefault sets the value of the environment variable, unless the environment has a value for it
+func (p *AzdProject) SetEnvDefault(key string, value string) {
+	if p.Env[key] == "" {
+		p.Env[key] = value
+	}
+}
+
+// GetEnvList returns the environment variables as sorted key=value strings, to be added to the environment of commands
+func (p *AzdProject) GetEnvList() []string {
+	env := make([]string, 0, len(p.Env))
+	for key, value := range p.Env {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// SubstituteParametersFile replaces the ${VAR} references of the JSON parameters file with the values of the environment,
+// as azd does before provisioning, and saves the result to a temporary file, which is removed by the returned cleanup function
+func (p *AzdProject) SubstituteParametersFile(parametersFilePath string) (string, func(), error) {
+	content, err := os.ReadFile(parametersFilePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading parameters file: %w", err)
+	}
+
+	substituted, missing := SubstituteEnv(string(content), p.Env, true)
+	for _, key := range missing {
+		log.Warnf("environment variable %s of parameters file %s is not set, an empty value is used\n", key, parametersFilePath)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "az-mpf-azd-")
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating temporary directory for parameters file: %w", err)
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(tmpDir)
+	}
+
+	substitutedFilePath := filepath.Join(tmpDir, filepath.Base(parametersFilePath))
+	err = os.WriteFile(substitutedFilePath, []byte(substituted), 0600)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("error saving parameters file: %w", err)
+	}
+
+	return substitutedFilePath, cleanup, nil
+}
@@ -0,0 +1,129 @@
+package azdProject
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/mpfSharedUtils"
+	"github.com/stretchr/testify/assert"
+)
+
+const mainParametersWithEnvReferences = `{
+  "$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentParameters.json#",
+  "contentVersion": "1.0.0.0",
+  "parameters": {
+    "environmentName": {
+      "value": "${AZURE_ENV_NAME}"
+    },
+    "location": {
+      "value": "${AZURE_LOCATION}"
+    },
+    "principalId": {
+      "value": "${AZURE_PRINCIPAL_ID}"
+    }
+  }
+}`
+
+func writeTestFile(t *testing.T, path string, content string) {
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func writeTestAzdProject(t *testing.T, azureYamlContent string) string {
+	t.Setenv(EnvNameKey, "")
+
+	projectDir := t.TempDir()
+	writeTestFile(t, filepath.Join(projectDir, AzureYamlFileName), azureYamlContent)
+	writeTestFile(t, filepath.Join(projectDir, ".azure", "config.json"), `{"version": 1, "defaultEnvironment": "mpf-dev"}`)
+	writeTestFile(t, filepath.Join(projectDir, ".azure", "mpf-dev", ".env"), "AZURE_ENV_NAME=\"mpf-dev\"\nAZURE_LOCATION=\"eastus2\"\n")
+	writeTestFile(t, filepath.Join(projectDir, ".azure", "mpf-test", ".env"), "AZURE_ENV_NAME=\"mpf-test\"\nAZURE_LOCATION=\"westus3\"\n")
+	return projectDir
+}
+
+func TestLoadAzdProjectWithDefaults(t *testing.T) {
+	projectDir := writeTestAzdProject(t, "name: todo-nodejs-mongo\nservices:\n  web:\n    project: ./src/web\n    host: appservice\n")
+
+	project, err := LoadAzdProject(projectDir, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "todo-nodejs-mongo", project.Name)
+	assert.Equal(t, filepath.Join(projectDir, "infra", "main.bicep"), project.BicepFilePath())
+	assert.Equal(t, "mpf-dev", project.EnvironmentName)
+	assert.Equal(t, "eastus2", project.Env[LocationKey])
+}
+
+func TestLoadAzdProjectWithInfraAndEnvironment(t *testing.T) {
+	projectDir := writeTestAzdProject(t, "name: todo\ninfra:\n  provider: bicep\n  path: deploy/bicep\n  module: app\n")
+
+	project, err := LoadAzdProject(projectDir, "mpf-test")
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(projectDir, "deploy", "bicep", "app.bicep"), project.BicepFilePath())
+	assert.Equal(t, "mpf-test", project.EnvironmentName)
+	assert.Equal(t, "westus3", project.Env[LocationKey])
+
+	// an environment without a .env file only has its name
+	project, err = LoadAzdProject(projectDir, "mpf-new")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{EnvNameKey: "mpf-new"}, project.Env)
+}
+
+func TestLoadAzdProjectErrors(t *testing.T) {
+	projectDir := writeTestAzdProject(t, "name: todo\ninfra:\n  provider: terraform\n")
+	_, err := LoadAzdProject(projectDir, "")
+	assert.NotNil(t, err)
+
+	_, err = LoadAzdProject(t.TempDir(), "")
+	assert.NotNil(t, err)
+
+	// without an environment name, or a default environment
+	projectDir = t.TempDir()
+	writeTestFile(t, filepath.Join(projectDir, AzureYamlFileName), "name: todo\n")
+	_, err = LoadAzdProject(projectDir, "")
+	assert.NotNil(t, err)
+}
+
+func TestParametersFilePath(t *testing.T) {
+	projectDir := writeTestAzdProject(t, "name: todo\n")
+	project, err := LoadAzdProject(projectDir, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "", project.ParametersFilePath())
+
+	writeTestFile(t, filepath.Join(projectDir, "infra", "main.bicepparam"), "using 'main.bicep'\n")
+	assert.Equal(t, filepath.Join(projectDir, "infra", "main.bicepparam"), project.ParametersFilePath())
+
+	writeTestFile(t, filepath.Join(projectDir, "infra", "main.parameters.json"), mainParametersWithEnvReferences)
+	assert.Equal(t, filepath.Join(projectDir, "infra", "main.parameters.json"), project.ParametersFilePath())
+}
+
+func TestSubstituteParametersFile(t *testing.T) {
+	projectDir := writeTestAzdProject(t, "name: todo\n")
+	parametersFilePath := filepath.Join(projectDir, "infra", "main.parameters.json")
+	writeTestFile(t, parametersFilePath, mainParametersWithEnvReferences)
+
+	project, err := LoadAzdProject(projectDir, "")
+	assert.Nil(t, err)
+	project.SetEnvDefault(LocationKey, "eastus")
+	project.SetEnvDefault(PrincipalIDKey, "PPPPPPPP-PPPP-PPPP-PPPP-PPPPPPPPPPPP")
+
+	substitutedFilePath, cleanup, err := project.SubstituteParametersFile(parametersFilePath)
+	assert.Nil(t, err)
+	defer cleanup()
+
+	parameters, err := mpfSharedUtils.ReadJson(substitutedFilePath)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"environmentName": map[string]interface{}{"value": "mpf-dev"},
+		"location":        map[string]interface{}{"value": "eastus2"},
+		"principalId":     map[string]interface{}{"value": "PPPPPPPP-PPPP-PPPP-PPPP-PPPPPPPPPPPP"},
+	}, parameters["parameters"])
+
+	cleanup()
+	_, err = os.Stat(substitutedFilePath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGetEnvList(t *testing.T) {
+	project := &AzdProject{Env: map[string]string{LocationKey: "eastus2", EnvNameKey: "mpf-dev"}}
+	project.SetEnv(LocationKey, "westus3")
+	assert.Equal(t, []string{"AZURE_ENV_NAME=mpf-dev", "AZURE_LOCATION=westus3"}, project.GetEnvList())
+}
@@ -23,9 +23,11 @@ type AzureAPIClients struct {
 	ResourceGroupsClient *armresources.ResourceGroupsClient
 
 	// Default CLI Creds
-	DefaultCred                         *azidentity.DefaultAzureCredential
-	defaultAPIBearerToken               string
-	defaultAPIBearerTokenLastCachedTime time.Time
+	DefaultCred                           *azidentity.DefaultAzureCredential
+	defaultAPIBearerToken                 string
+	defaultAPIBearerTokenLastCachedTime   time.Time
+	defaultGraphBearerToken               string
+	defaultGraphBearerTokenLastCachedTime time.Time
 	// SPCred                *azidentity.ClientSecretCredential
 }
 
@@ -51,7 +53,11 @@ type TokenProvider interface {
 }
 
 func (a *AzureAPIClients) getBearerToken(tp TokenProvider) (bearerToken string, err error) {
-	opts := policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}}
+	return a.getBearerTokenForScope(tp, "https://management.azure.com/.default")
+}
+
+func (a *AzureAPIClients) getBearerTokenForScope(tp TokenProvider, scope string) (bearerToken string, err error) {
+	opts := policy.TokenRequestOptions{Scopes: []string{scope}}
 	tok, err := tp.GetToken(context.Background(), opts)
 	if err != nil {
 		return "", err
@@ -142,6 +148,22 @@ func (a *AzureAPIClients) GetDefaultAPIBearerToken() (bearerToken string, err er
 	return a.defaultAPIBearerToken, nil
 }
 
+// GetDefaultGraphBearerToken returns a Microsoft Graph token of the default credentials
+func (a *AzureAPIClients) GetDefaultGraphBearerToken() (bearerToken string, err error) {
+	if a.defaultGraphBearerToken == "" || time.Since(a.defaultGraphBearerTokenLastCachedTime) > defaultTokenCacheDuration {
+		bearerToken, err = a.getBearerTokenForScope(a.DefaultCred, "https://graph.microsoft.com/.default")
+		if err != nil {
+			return "", err
+		}
+
+		a.defaultGraphBearerToken = bearerToken
+		a.defaultGraphBearerTokenLastCachedTime = time.Now()
+		log.Infoln("Default Graph Bearer Token set")
+	}
+
+	return a.defaultGraphBearerToken, nil
+}
+
 // func (m *MinPermFinder) RefreshSPAPIAccessBearerToken() error {
 // 	// Get the bearer token for the API access
 
@@ -0,0 +1,178 @@
+package bicepCompiler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// BicepCompiler compiles bicep and bicepparam files into ARM templates and parameters files in a temporary directory,
+// so that files next to the bicep sources are never overwritten
+type BicepCompiler struct {
+	execPath  string
+	outputDir string
+	env       []string
+}
+
+func NewBicepCompiler(execPath string) (*BicepCompiler, error) {
+	outputDir, err := os.MkdirTemp("", "az-mpf-bicep-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating bicep output directory: %w", err)
+	}
+
+	return &BicepCompiler{
+		execPath:  execPath,
+		outputDir: outputDir,
+	}, nil
+}
+
+// SetEnv sets environment variables, as key=value strings, of the bicep commands, for example the variables
+// a bicepparam file reads with readEnvironmentVariable
+func (b *BicepCompiler) SetEnv(env []string) {
+	b.env = env
+}
+
+// Build compiles the bicep file, and returns the path of the ARM template
+func (b *BicepCompiler) Build(bicepFilePath string) (string, error) {
+	err := b.restoreExternalModules(bicepFilePath)
+	if err != nil {
+		return "", err
+	}
+
+	armTemplatePath := filepath.Join(b.outputDir, strings.TrimSuffix(filepath.Base(bicepFilePath), filepath.Ext(bicepFilePath))+".json")
+	err = b.run(bicepFilePath, "build", bicepFilePath, "--outfile", armTemplatePath)
+	if err != nil {
+		return "", err
+	}
+
+	log.Infoln("Bicep build successful, ARM Template created at:", armTemplatePath)
+	return armTemplatePath, nil
+}
+
+// BuildParams compiles the bicepparam file for the bicep file, and returns the path of the ARM parameters file
+func (b *BicepCompiler) BuildParams(bicepparamFilePath string, bicepFilePath string) (string, error) {
+	parametersFilePath := filepath.Join(b.outputDir, strings.TrimSuffix(filepath.Base(bicepparamFilePath), filepath.Ext(bicepparamFilePath))+".parameters.json")
+
+	args := []string{"build-params", bicepparamFilePath, "--outfile", parametersFilePath}
+	if bicepFilePath != "" {
+		args = append(args, "--bicep-file", bicepFilePath)
+	}
+
+	err := b.run(bicepparamFilePath, args...)
+	if err != nil {
+		return "", err
+	}
+
+	log.Infoln("Bicep build-params successful, parameters file created at:", parametersFilePath)
+	return parametersFilePath, nil
+}
+
+// Cleanup deletes the compiled files
+func (b *BicepCompiler) Cleanup() error {
+	log.Infoln("Deleting compiled bicep files...")
+	return os.RemoveAll(b.outputDir)
+}
+
+// external modules, for example 'br/myAlias:storage:v1' or 'ts:<subscription>/<rg>/<spec>:v1', need to be restored before build
+var externalModuleRegex = regexp.MustCompile(`'(br|ts)[:/]`)
+
+func (b *BicepCompiler) restoreExternalModules(bicepFilePath string) error {
+	source, err := os.ReadFile(bicepFilePath)
+	if err != nil {
+		return fmt.Errorf("error reading bicep file: %w", err)
+	}
+
+	if !externalModuleRegex.Match(source) {
+		return nil
+	}
+
+	// aliases which are not defined fail the restore with an error which does not name the bicepconfig.json used
+	var aliases []string
+	bicepConfigPath := FindBicepConfig(filepath.Dir(bicepFilePath))
+	if bicepConfigPath != "" {
+		aliases, err = GetModuleAliases(bicepConfigPath)
+		if err != nil {
+			return err
+		}
+		log.Infof("Using module aliases %v from %s\n", aliases, bicepConfigPath)
+	}
+
+	undefinedAliases := GetUndefinedModuleAliases(source, aliases)
+	if len(undefinedAliases) > 0 {
+		if bicepConfigPath == "" {
+			return fmt.Errorf("module aliases %s of %s are not defined, no %s was found in its directory or its parents", strings.Join(undefinedAliases, ", "), bicepFilePath, bicepConfigFileName)
+		}
+		return fmt.Errorf("module aliases %s of %s are not defined in the moduleAliases of %s", strings.Join(undefinedAliases, ", "), bicepFilePath, bicepConfigPath)
+	}
+
+	log.Infoln("Restoring external bicep modules...")
+	return b.run(bicepFilePath, "restore", bicepFilePath)
+}
+
+// run executes the bicep command from the directory of the source file, so that bicepconfig.json of the source is used
+func (b *BicepCompiler) run(sourceFilePath string, args ...string) error {
+	bicepCmd := exec.Command(b.execPath, args...)
+	bicepCmd.Dir = filepath.Dir(sourceFilePath)
+	if len(b.env) > 0 {
+		bicepCmd.Env = append(os.Environ(), b.env...)
+	}
+
+	output, runErr := bicepCmd.CombinedOutput()
+	log.Debugln(string(output))
+
+	diagnostics := ParseBicepDiagnostics(string(output))
+	var errorDiagnostics []BicepDiagnostic
+	for _, d := range diagnostics {
+		if d.Level == BicepDiagnosticLevelError {
+			errorDiagnostics = append(errorDiagnostics, d)
+			continue
+		}
+		log.Warnln(d.String())
+	}
+
+	if runErr == nil && len(errorDiagnostics) == 0 {
+		return nil
+	}
+
+	return &BicepCompilationError{
+		Command:     args[0],
+		Diagnostics: errorDiagnostics,
+		Output:      string(output),
+		Err:         runErr,
+	}
+}
+
+var ErrBicepCompilation = errors.New("BicepCompilationFailed")
+
+type BicepCompilationError struct {
+	Command     string
+	Diagnostics []BicepDiagnostic
+	Output      string
+	Err         error
+}
+
+func (e *BicepCompilationError) Error() string {
+	if len(e.Diagnostics) == 0 {
+		return fmt.Sprintf("bicep %s failed: %v: %s", e.Command, e.Err, strings.TrimSpace(e.Output))
+	}
+
+	msgs := make([]string, 0, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		msgs = append(msgs, d.String())
+	}
+	return fmt.Sprintf("bicep %s failed with %d error(s):\n%s", e.Command, len(e.Diagnostics), strings.Join(msgs, "\n"))
+}
+
+func (e *BicepCompilationError) Is(target error) bool {
+	return target == ErrBicepCompilation
+}
+
+func (e *BicepCompilationError) Unwrap() error {
+	return e.Err
+}
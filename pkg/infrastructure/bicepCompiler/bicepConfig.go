@@ -0,0 +1,159 @@
+package bicepCompiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const bicepConfigFileName = "bicepconfig.json"
+
+// FindBicepConfig returns the path of the bicepconfig.json which bicep uses for files in the directory,
+// that is the first one found in the directory or its parents. An empty string is returned if none exists
+func FindBicepConfig(dir string) string {
+	for {
+		configPath := filepath.Join(dir, bicepConfigFileName)
+		if _, err := os.Stat(configPath); err == nil {
+			return configPath
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// GetModuleAliases returns the module aliases defined in the bicepconfig.json, prefixed with br/ or ts/ as used in module references
+func GetModuleAliases(bicepConfigPath string) ([]string, error) {
+	content, err := os.ReadFile(bicepConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", bicepConfigPath, err)
+	}
+
+	var bicepConfig struct {
+		ModuleAliases map[string]map[string]interface{} `json:"moduleAliases"`
+	}
+
+	err = json.Unmarshal(stripJSONComments(content), &bicepConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", bicepConfigPath, err)
+	}
+
+	var aliases []string
+	for moduleType, typeAliases := range bicepConfig.ModuleAliases {
+		for alias := range typeAliases {
+			aliases = append(aliases, fmt.Sprintf("%s/%s", moduleType, alias))
+		}
+	}
+	sort.Strings(aliases)
+
+	return aliases, nil
+}
+
+// module references using an alias, for example 'br/CoreModules:storage:v1' or 'ts/CoreSpecs:storage:v1'
+var moduleAliasReferenceRegex = regexp.MustCompile(`'((?:br|ts)/[^:'/]+):`)
+
+// br/public is the alias of the public module registry, which bicep defines without a bicepconfig.json
+var builtInModuleAliases = []string{"br/public"}
+
+// GetUndefinedModuleAliases returns the module aliases referenced in the bicep source which are neither built in nor
+// defined in the aliases, sorted
+func GetUndefinedModuleAliases(source []byte, aliases []string) []string {
+	defined := map[string]bool{}
+	for _, alias := range append(aliases, builtInModuleAliases...) {
+		defined[alias] = true
+	}
+
+	undefinedSet := map[string]bool{}
+	for _, match := range moduleAliasReferenceRegex.FindAllSubmatch(source, -1) {
+		alias := string(match[1])
+		if !defined[alias] {
+			undefinedSet[alias] = true
+		}
+	}
+
+	undefined := make([]string, 0, len(undefinedSet))
+	for alias := range undefinedSet {
+		undefined = append(undefined, alias)
+	}
+	sort.Strings(undefined)
+	return undefined
+}
+
+var usingRegex = regexp.MustCompile(`(?m)^\s*using\s+'([^']+)'`)
+
+// GetBicepFileFromBicepparam returns the path of the bicep file referenced by the using declaration of the bicepparam file
+func GetBicepFileFromBicepparam(bicepparamFilePath string) (string, error) {
+	content, err := os.ReadFile(bicepparamFilePath)
+	if err != nil {
+		return "", fmt.Errorf("error reading bicepparam file: %w", err)
+	}
+
+	match := usingRegex.FindSubmatch(content)
+	if len(match) != 2 {
+		return "", fmt.Errorf("no using declaration found in bicepparam file: %s", bicepparamFilePath)
+	}
+
+	bicepFilePath := string(match[1])
+	if !strings.HasSuffix(bicepFilePath, ".bicep") {
+		return "", fmt.Errorf("using declaration of bicepparam file does not reference a local bicep file: %s", bicepFilePath)
+	}
+
+	if !filepath.IsAbs(bicepFilePath) {
+		bicepFilePath = filepath.Join(filepath.Dir(bicepparamFilePath), bicepFilePath)
+	}
+	return bicepFilePath, nil
+}
+
+// bicepconfig.json allows comments, which encoding/json does not
+func stripJSONComments(content []byte) []byte {
+	var out []byte
+	inString := false
+	escaped := false
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+
+		if inString {
+			out = append(out, c)
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(content) && content[i+1] == '/':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			if i < len(content) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			i += 2
+			for i+1 < len(content) && !(content[i] == '*' && content[i+1] == '/') {
+				i++
+			}
+			i++
+		default:
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
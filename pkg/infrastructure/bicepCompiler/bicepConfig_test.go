@@ -0,0 +1,103 @@
+package bicepCompiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const bicepConfigWithComments = `{
+	// aliases for registries used by the modules
+	"moduleAliases": {
+		"br": {
+			"CoreModules": {
+				"registry": "contoso.azurecr.io",
+				"modulePath": "bicep/modules/core"
+			}
+		},
+		/* template spec aliases */
+		"ts": {
+			"CoreSpecs": {
+				"subscription": "00000000-0000-0000-0000-000000000000",
+				"resourceGroup": "templateSpecs"
+			}
+		}
+	},
+	"analyzers": {
+		"core": {
+			"rules": {
+				"no-hardcoded-env-urls": {
+					"disallowedhosts": ["https://management.azure.com//"]
+				}
+			}
+		}
+	}
+}`
+
+func TestFindBicepConfigAndGetModuleAliases(t *testing.T) {
+	dir := t.TempDir()
+	modulesDir := filepath.Join(dir, "infra", "modules")
+	assert.NoError(t, os.MkdirAll(modulesDir, 0755))
+
+	assert.Equal(t, "", FindBicepConfig(modulesDir))
+
+	bicepConfigPath := filepath.Join(dir, bicepConfigFileName)
+	assert.NoError(t, os.WriteFile(bicepConfigPath, []byte(bicepConfigWithComments), 0600))
+
+	assert.Equal(t, bicepConfigPath, FindBicepConfig(modulesDir))
+
+	aliases, err := GetModuleAliases(bicepConfigPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"br/CoreModules", "ts/CoreSpecs"}, aliases)
+}
+
+func TestGetUndefinedModuleAliases(t *testing.T) {
+	source := []byte(`module storage 'br/CoreModules:storage:v1' = {}
+module network 'br/public:avm/res/network/virtual-network:0.1.0' = {}
+module spec 'ts/CoreSpecs:storage:v1' = {}
+module other 'br/OtherModules:app:v2' = {}
+module another 'br/OtherModules:db:v1' = {}
+module direct 'br:contoso.azurecr.io/bicep/modules/app:v1' = {}
+module local './modules/local.bicep' = {}
+`)
+
+	assert.Equal(t, []string{"br/OtherModules"}, GetUndefinedModuleAliases(source, []string{"br/CoreModules", "ts/CoreSpecs"}))
+	assert.Equal(t, []string{"br/CoreModules", "br/OtherModules", "ts/CoreSpecs"}, GetUndefinedModuleAliases(source, nil))
+	assert.Equal(t, []string{}, GetUndefinedModuleAliases([]byte("module local './modules/local.bicep' = {}"), nil))
+}
+
+func TestGetBicepFileFromBicepparam(t *testing.T) {
+	dir := t.TempDir()
+	bicepparamFilePath := filepath.Join(dir, "main.bicepparam")
+	assert.NoError(t, os.WriteFile(bicepparamFilePath, []byte("// parameters for dev\nusing './infra/main.bicep'\n\nparam location = 'eastus'\n"), 0600))
+
+	bicepFilePath, err := GetBicepFileFromBicepparam(bicepparamFilePath)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "infra", "main.bicep"), bicepFilePath)
+}
+
+func TestGetBicepFileFromBicepparamRegistryReference(t *testing.T) {
+	dir := t.TempDir()
+	bicepparamFilePath := filepath.Join(dir, "main.bicepparam")
+	assert.NoError(t, os.WriteFile(bicepparamFilePath, []byte("using 'br/public:avm/res/storage/storage-account:0.9.0'\n"), 0600))
+
+	_, err := GetBicepFileFromBicepparam(bicepparamFilePath)
+	assert.Error(t, err)
+}
+
+func TestBuildWithUndefinedModuleAlias(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, bicepConfigFileName), []byte(bicepConfigWithComments), 0600))
+	bicepFilePath := filepath.Join(dir, "main.bicep")
+	assert.NoError(t, os.WriteFile(bicepFilePath, []byte("module app 'br/AppModules:app:v1' = {\n  name: 'app'\n}\n"), 0600))
+
+	// the aliases are checked before bicep runs
+	compiler, err := NewBicepCompiler(filepath.Join(dir, "bicep"))
+	assert.NoError(t, err)
+	defer compiler.Cleanup()
+
+	_, err = compiler.Build(bicepFilePath)
+	assert.ErrorContains(t, err, "br/AppModules")
+}
@@ -0,0 +1,57 @@
+package bicepCompiler
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	BicepDiagnosticLevelError   = "Error"
+	BicepDiagnosticLevelWarning = "Warning"
+	BicepDiagnosticLevelInfo    = "Info"
+)
+
+type BicepDiagnostic struct {
+	FilePath string
+	Line     int
+	Column   int
+	Level    string
+	Code     string
+	Message  string
+}
+
+func (d BicepDiagnostic) String() string {
+	return fmt.Sprintf("%s(%d,%d): %s %s: %s", d.FilePath, d.Line, d.Column, d.Level, d.Code, d.Message)
+}
+
+// Sample diagnostic
+// /home/user/main.bicep(12,7) : Error BCP057: The name "vnetName" does not exist in the current context.
+var bicepDiagnosticRegex = regexp.MustCompile(`^(.+)\((\d+),(\d+)\)\s*:\s*(Error|Warning|Info)\s+([^:\s]+):\s*(.*)$`)
+
+// ParseBicepDiagnostics parses the diagnostics printed by bicep build and build-params
+func ParseBicepDiagnostics(output string) []BicepDiagnostic {
+	var diagnostics []BicepDiagnostic
+
+	for _, line := range strings.Split(output, "\n") {
+		match := bicepDiagnosticRegex.FindStringSubmatch(strings.TrimSpace(line))
+		if len(match) != 7 {
+			continue
+		}
+
+		lineNumber, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+
+		diagnostics = append(diagnostics, BicepDiagnostic{
+			FilePath: match[1],
+			Line:     lineNumber,
+			Column:   column,
+			Level:    match[4],
+			Code:     match[5],
+			Message:  match[6],
+		})
+	}
+
+	return diagnostics
+}
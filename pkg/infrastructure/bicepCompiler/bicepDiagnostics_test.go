@@ -0,0 +1,48 @@
+package bicepCompiler
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBicepDiagnostics(t *testing.T) {
+	output := `/home/user/main.bicep(7,5) : Warning BCP037: The property "names" is not allowed on objects of type "Microsoft.Network/virtualNetworks".
+/home/user/main.bicep(12,7) : Error BCP057: The name "vnetName" does not exist in the current context.
+Some other output
+`
+
+	diagnostics := ParseBicepDiagnostics(output)
+	assert.Len(t, diagnostics, 2)
+
+	assert.Equal(t, BicepDiagnostic{
+		FilePath: "/home/user/main.bicep",
+		Line:     12,
+		Column:   7,
+		Level:    BicepDiagnosticLevelError,
+		Code:     "BCP057",
+		Message:  `The name "vnetName" does not exist in the current context.`,
+	}, diagnostics[1])
+	assert.Equal(t, BicepDiagnosticLevelWarning, diagnostics[0].Level)
+	assert.Equal(t, "BCP037", diagnostics[0].Code)
+}
+
+func TestParseBicepDiagnosticsNoDiagnostics(t *testing.T) {
+	diagnostics := ParseBicepDiagnostics("")
+	assert.Empty(t, diagnostics)
+}
+
+func TestBicepCompilationError(t *testing.T) {
+	err := &BicepCompilationError{
+		Command: "build",
+		Diagnostics: []BicepDiagnostic{
+			{FilePath: "main.bicep", Line: 1, Column: 2, Level: BicepDiagnosticLevelError, Code: "BCP018", Message: `Expected the "=" character at this location.`},
+		},
+		Err: errors.New("exit status 1"),
+	}
+
+	assert.True(t, errors.Is(err, ErrBicepCompilation))
+	assert.Contains(t, err.Error(), "bicep build failed with 1 error(s)")
+	assert.Contains(t, err.Error(), `main.bicep(1,2): Error BCP018: Expected the "=" character at this location.`)
+}
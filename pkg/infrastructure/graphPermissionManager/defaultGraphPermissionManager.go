@@ -0,0 +1,265 @@
+package graphpermissionmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/azureAPI"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	graphBaseURL = "https://graph.microsoft.com/v1.0"
+	// The application ID of the Microsoft Graph service principal, which is the same in every tenant
+	microsoftGraphAppID = "00000003-0000-0000-c000-000000000000"
+	// The error message of Microsoft Graph when the app role is already assigned to the service principal
+	appRoleAssignmentExistsErr = "Permission being assigned already exists"
+)
+
+type graphAppRole struct {
+	ID                 string   `json:"id"`
+	Value              string   `json:"value"`
+	AllowedMemberTypes []string `json:"allowedMemberTypes"`
+}
+
+type graphServicePrincipal struct {
+	ID       string         `json:"id"`
+	AppRoles []graphAppRole `json:"appRoles"`
+}
+
+type appRoleAssignment struct {
+	ID          string `json:"id,omitempty"`
+	PrincipalID string `json:"principalId"`
+	ResourceID  string `json:"resourceId"`
+	AppRoleID   string `json:"appRoleId"`
+}
+
+type GraphPermissionManager struct {
+	azAPIClient           *azureAPI.AzureAPIClients
+	graphServicePrincipal *graphServicePrincipal
+	// graphRequest sends the requests to Microsoft Graph, and returns the response body and status code
+	graphRequest func(method string, url string, data interface{}) ([]byte, int, error)
+}
+
+// NewGraphPermissionManager returns the manager of the Microsoft Graph application permissions of the service principal,
+// which assigns and removes app roles of Microsoft Graph with the default credentials MPF runs with. The default credentials
+// require the AppRoleAssignment.ReadWrite.All permission, or the Privileged Role Administrator role
+func NewGraphPermissionManager(subscriptionID string) *GraphPermissionManager {
+	azAPIClient := azureAPI.NewAzureAPIClients(subscriptionID)
+	m := &GraphPermissionManager{
+		azAPIClient: azAPIClient,
+	}
+	m.graphRequest = m.doGraphRequest
+	return m
+}
+
+// AssignGraphPermissionsToSP assigns the app roles of Microsoft Graph of the permissions to the service principal, and returns
+// the permissions of the app role assignments it created. App roles which are already assigned are skipped, and are not returned,
+// so that only the assignments created by MPF are removed. On error the permissions assigned before the error are returned
+func (m *GraphPermissionManager) AssignGraphPermissionsToSP(SPObjectID string, permissions []string) ([]string, error) {
+	graphSP, err := m.getGraphServicePrincipal()
+	if err != nil {
+		return nil, err
+	}
+
+	assignments, err := m.listAppRoleAssignments(SPObjectID)
+	if err != nil {
+		return nil, err
+	}
+	assignedAppRoleIDs := make(map[string]bool)
+	for _, assignment := range assignments {
+		if assignment.ResourceID == graphSP.ID {
+			assignedAppRoleIDs[assignment.AppRoleID] = true
+		}
+	}
+
+	var assignedPermissions []string
+	for _, permission := range permissions {
+		appRoleID, err := getAppRoleID(graphSP, permission)
+		if err != nil {
+			return assignedPermissions, err
+		}
+
+		if assignedAppRoleIDs[appRoleID] {
+			log.Infof("Microsoft Graph permission %s already assigned to service principal. Skipping...\n", permission)
+			continue
+		}
+
+		assignment := appRoleAssignment{
+			PrincipalID: SPObjectID,
+			ResourceID:  graphSP.ID,
+			AppRoleID:   appRoleID,
+		}
+		url := fmt.Sprintf("%s/servicePrincipals/%s/appRoleAssignments", graphBaseURL, SPObjectID)
+		body, statusCode, err := m.graphRequest("POST", url, assignment)
+		if err != nil {
+			return assignedPermissions, err
+		}
+
+		if statusCode == http.StatusBadRequest && strings.Contains(string(body), appRoleAssignmentExistsErr) {
+			log.Infof("Microsoft Graph permission %s already assigned to service principal. Skipping...\n", permission)
+			continue
+		}
+		if statusCode != http.StatusCreated {
+			return assignedPermissions, fmt.Errorf("Failed to assign Microsoft Graph permission %s to SP. Status code: %d, %s", permission, statusCode, string(body))
+		}
+		log.Infof("Microsoft Graph permission %s assigned to service principal successfully\n", permission)
+		assignedPermissions = append(assignedPermissions, permission)
+	}
+	return assignedPermissions, nil
+}
+
+// RevokeGraphPermissionsFromSP removes the app role assignments of Microsoft Graph of the permissions from the service principal
+func (m *GraphPermissionManager) RevokeGraphPermissionsFromSP(SPObjectID string, permissions []string) error {
+	graphSP, err := m.getGraphServicePrincipal()
+	if err != nil {
+		return err
+	}
+
+	appRoleIDs := make(map[string]bool)
+	for _, permission := range permissions {
+		appRoleID, err := getAppRoleID(graphSP, permission)
+		if err != nil {
+			return err
+		}
+		appRoleIDs[appRoleID] = true
+	}
+
+	assignments, err := m.listAppRoleAssignments(SPObjectID)
+	if err != nil {
+		return err
+	}
+
+	for _, assignment := range assignments {
+		if assignment.ResourceID != graphSP.ID || !appRoleIDs[assignment.AppRoleID] {
+			continue
+		}
+
+		url := fmt.Sprintf("%s/servicePrincipals/%s/appRoleAssignments/%s", graphBaseURL, SPObjectID, assignment.ID)
+		body, statusCode, err := m.graphRequest("DELETE", url, nil)
+		if err != nil {
+			return err
+		}
+		if statusCode != http.StatusNoContent {
+			return fmt.Errorf("Failed to remove app role assignment %s of SP. Status code: %d, %s", assignment.ID, statusCode, string(body))
+		}
+	}
+
+	log.Infoln("Microsoft Graph permissions revoked from service principal successfully")
+	return nil
+}
+
+// listAppRoleAssignments returns the app role assignments of the service principal, following the next links of the pages
+func (m *GraphPermissionManager) listAppRoleAssignments(SPObjectID string) ([]appRoleAssignment, error) {
+	var assignments []appRoleAssignment
+	url := fmt.Sprintf("%s/servicePrincipals/%s/appRoleAssignments", graphBaseURL, SPObjectID)
+	for url != "" {
+		body, statusCode, err := m.graphRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		if statusCode != http.StatusOK {
+			return nil, fmt.Errorf("Failed to list app role assignments of SP. Status code: %d, %s", statusCode, string(body))
+		}
+
+		var page appRoleAssignmentsPage
+		err = json.Unmarshal(body, &page)
+		if err != nil {
+			return nil, err
+		}
+		assignments = append(assignments, page.Value...)
+		url = page.NextLink
+	}
+	return assignments, nil
+}
+
+type appRoleAssignmentsPage struct {
+	Value    []appRoleAssignment `json:"value"`
+	NextLink string              `json:"@odata.nextLink"`
+}
+
+// getGraphServicePrincipal returns the Microsoft Graph service principal of the tenant, with the app roles it defines
+func (m *GraphPermissionManager) getGraphServicePrincipal() (*graphServicePrincipal, error) {
+	if m.graphServicePrincipal != nil {
+		return m.graphServicePrincipal, nil
+	}
+
+	url := fmt.Sprintf("%s/servicePrincipals(appId='%s')?$select=id,appRoles", graphBaseURL, microsoftGraphAppID)
+	body, statusCode, err := m.graphRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to get Microsoft Graph service principal. Status code: %d, %s", statusCode, string(body))
+	}
+
+	graphSP := &graphServicePrincipal{}
+	err = json.Unmarshal(body, graphSP)
+	if err != nil {
+		return nil, err
+	}
+
+	m.graphServicePrincipal = graphSP
+	return graphSP, nil
+}
+
+// getAppRoleID returns the ID of the app role of the application permission
+func getAppRoleID(graphSP *graphServicePrincipal, permission string) (string, error) {
+	for _, appRole := range graphSP.AppRoles {
+		if appRole.Value != permission {
+			continue
+		}
+		for _, memberType := range appRole.AllowedMemberTypes {
+			if memberType == "Application" {
+				return appRole.ID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("No Microsoft Graph application permission %s found", permission)
+}
+
+func (m *GraphPermissionManager) doGraphRequest(method string, url string, data interface{}) ([]byte, int, error) {
+	var reqBody io.Reader
+	if data != nil {
+		jsonData, err := json.Marshal(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		log.Debugf("jsonString: %s", string(jsonData))
+		reqBody = bytes.NewBuffer(jsonData)
+	}
+
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Go HTTP Client")
+
+	graphBearerToken, err := m.azAPIClient.GetDefaultGraphBearerToken()
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Add("Authorization", "Bearer "+graphBearerToken)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	log.Debugln(string(body))
+	return body, resp.StatusCode, nil
+}
@@ -0,0 +1,106 @@
+package graphpermissionmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAppRoleID(t *testing.T) {
+	graphSP := &graphServicePrincipal{
+		ID: "graph-sp",
+		AppRoles: []graphAppRole{
+			{ID: "user-role", Value: "User.Read.All", AllowedMemberTypes: []string{"User"}},
+			{ID: "application-role", Value: "Application.ReadWrite.All", AllowedMemberTypes: []string{"Application"}},
+			{ID: "group-role", Value: "Group.ReadWrite.All", AllowedMemberTypes: []string{"User", "Application"}},
+		},
+	}
+
+	appRoleID, err := getAppRoleID(graphSP, "Application.ReadWrite.All")
+	assert.Nil(t, err)
+	assert.Equal(t, "application-role", appRoleID)
+
+	appRoleID, err = getAppRoleID(graphSP, "Group.ReadWrite.All")
+	assert.Nil(t, err)
+	assert.Equal(t, "group-role", appRoleID)
+
+	// delegated permissions can not be assigned to a service principal
+	_, err = getAppRoleID(graphSP, "User.Read.All")
+	assert.NotNil(t, err)
+
+	_, err = getAppRoleID(graphSP, "Unknown.Permission")
+	assert.NotNil(t, err)
+}
+
+// fakeGraph serves the app role assignments of the service principal in pages of one assignment, and records the requests
+type fakeGraph struct {
+	assignments []appRoleAssignment
+	requests    []string
+}
+
+func (f *fakeGraph) graphRequest(method string, url string, data interface{}) ([]byte, int, error) {
+	f.requests = append(f.requests, method+" "+url)
+	assignmentsURL := fmt.Sprintf("%s/servicePrincipals/sp/appRoleAssignments", graphBaseURL)
+
+	switch method {
+	case "GET":
+		page := appRoleAssignmentsPage{Value: []appRoleAssignment{}}
+		index := 0
+		if url != assignmentsURL {
+			_, err := fmt.Sscanf(url, assignmentsURL+"?page=%d", &index)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		if index < len(f.assignments) {
+			page.Value = append(page.Value, f.assignments[index])
+		}
+		if index+1 < len(f.assignments) {
+			page.NextLink = fmt.Sprintf("%s?page=%d", assignmentsURL, index+1)
+		}
+		body, err := json.Marshal(page)
+		return body, http.StatusOK, err
+	case "POST":
+		assignment := data.(appRoleAssignment)
+		assignment.ID = "assignment-" + assignment.AppRoleID
+		f.assignments = append(f.assignments, assignment)
+		return nil, http.StatusCreated, nil
+	case "DELETE":
+		return nil, http.StatusNoContent, nil
+	}
+	return nil, http.StatusMethodNotAllowed, nil
+}
+
+func TestAssignAndRevokeGraphPermissions(t *testing.T) {
+	graph := &fakeGraph{
+		assignments: []appRoleAssignment{
+			{ID: "other-resource", PrincipalID: "sp", ResourceID: "other-sp", AppRoleID: "application-role"},
+			{ID: "existing", PrincipalID: "sp", ResourceID: "graph-sp", AppRoleID: "group-role"},
+		},
+	}
+	m := &GraphPermissionManager{
+		graphServicePrincipal: &graphServicePrincipal{
+			ID: "graph-sp",
+			AppRoles: []graphAppRole{
+				{ID: "application-role", Value: "Application.ReadWrite.All", AllowedMemberTypes: []string{"Application"}},
+				{ID: "group-role", Value: "Group.ReadWrite.All", AllowedMemberTypes: []string{"Application"}},
+			},
+		},
+		graphRequest: graph.graphRequest,
+	}
+
+	// the assignment on the second page is found, and the permission which is already assigned is not returned
+	assigned, err := m.AssignGraphPermissionsToSP("sp", []string{"Group.ReadWrite.All", "Application.ReadWrite.All"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Application.ReadWrite.All"}, assigned)
+
+	graph.requests = nil
+	err = m.RevokeGraphPermissionsFromSP("sp", assigned)
+	assert.Nil(t, err)
+	assert.Contains(t, graph.requests, fmt.Sprintf("DELETE %s/servicePrincipals/sp/appRoleAssignments/assignment-application-role", graphBaseURL))
+	assert.NotContains(t, graph.requests, fmt.Sprintf("DELETE %s/servicePrincipals/sp/appRoleAssignments/existing", graphBaseURL))
+	assert.NotContains(t, graph.requests, fmt.Sprintf("DELETE %s/servicePrincipals/sp/appRoleAssignments/other-resource", graphBaseURL))
+}
@@ -18,3 +18,12 @@ func ReadJson(path string) (map[string]interface{}, error) {
 
 	return template, nil
 }
+
+func WriteJson(path string, data interface{}) error {
+	jsonBytes, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, jsonBytes, 0600)
+}
@@ -34,3 +34,15 @@ func TestReadJson(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, expected, result)
 }
+
+func TestWriteJson(t *testing.T) {
+	filePath := t.TempDir() + "/test.json"
+
+	data := map[string]interface{}{"name": "John Doe", "age": float64(30)}
+	err := WriteJson(filePath, data)
+	assert.Nil(t, err)
+
+	result, err := ReadJson(filePath)
+	assert.Nil(t, err)
+	assert.Equal(t, data, result)
+}
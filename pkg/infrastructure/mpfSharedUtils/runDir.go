@@ -0,0 +1,206 @@
+package mpfSharedUtils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	RunLockFileName   = "mpf.lock"
+	ResumeRunFileName = "resumeRun"
+	runsDirName       = "runs"
+)
+
+var ErrRunDirLocked = errors.New("run directory is locked by a running MPF process")
+
+// RunDir is the directory in which MPF keeps the files of one run for a module directory, so that no files are written
+// into the module directory, and concurrent runs for the same module do not share files. The run directory is locked
+// while the run is in progress. The module dir of MPF keeps the files shared by the runs of the module, such as the
+// pointer to the run directory of an interrupted run, which the next run resumes
+type RunDir struct {
+	ModuleDir string
+	Path      string
+}
+
+// NewRunDir locks and returns the run directory of a new run of the tool for the module directory. When an interrupted run of
+// the module is resumable, and no running process holds its lock, its run directory is returned instead. The run directories of
+// earlier runs which are neither in progress nor resumable are removed
+func NewRunDir(tool string, moduleDir string) (*RunDir, error) {
+	mpfModuleDir, err := getMPFModuleDir(tool, moduleDir)
+	if err != nil {
+		return nil, err
+	}
+
+	runsDir := filepath.Join(mpfModuleDir, runsDirName)
+	err = os.MkdirAll(runsDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	runDir := &RunDir{ModuleDir: mpfModuleDir}
+	resumeRunName := getResumeRunName(mpfModuleDir)
+	if resumeRunName != "" {
+		resumeRunPath := filepath.Join(runsDir, resumeRunName)
+		if _, err := os.Stat(resumeRunPath); err == nil && lockRunDir(resumeRunPath) == nil {
+			log.Infof("resuming interrupted MPF run in %s\n", resumeRunPath)
+			runDir.Path = resumeRunPath
+		}
+	}
+
+	if runDir.Path == "" {
+		// the process ID in the name marks the run directory as in progress until it is locked
+		runDir.Path = filepath.Join(runsDir, fmt.Sprintf("%d-%s", os.Getpid(), GenerateRandomString(7)))
+		err = os.Mkdir(runDir.Path, 0700)
+		if err != nil {
+			return nil, err
+		}
+		err = lockRunDir(runDir.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	removeEarlierRunDirs(runsDir, runDir.Path, resumeRunName)
+	return runDir, nil
+}
+
+// getMPFModuleDir returns the directory of MPF for the tool and the module directory, keyed by the absolute path of the module directory
+func getMPFModuleDir(tool string, moduleDir string) (string, error) {
+	absModuleDir, err := filepath.Abs(moduleDir)
+	if err != nil {
+		return "", err
+	}
+
+	baseDir, err := os.UserCacheDir()
+	if err != nil {
+		baseDir = os.TempDir()
+	}
+
+	hash := sha256.Sum256([]byte(absModuleDir))
+	return filepath.Join(baseDir, "az-mpf", tool, hex.EncodeToString(hash[:8])), nil
+}
+
+// SetResumable points the next run of the module to this run directory, so that it resumes this run if it is interrupted.
+// When the run is no longer resumable, the pointer is removed, unless it points to another run directory
+func (r *RunDir) SetResumable(resumable bool) error {
+	resumeRunFilePath := filepath.Join(r.ModuleDir, ResumeRunFileName)
+	if resumable {
+		return os.WriteFile(resumeRunFilePath, []byte(filepath.Base(r.Path)), 0600)
+	}
+
+	if getResumeRunName(r.ModuleDir) != filepath.Base(r.Path) {
+		return nil
+	}
+	err := os.Remove(resumeRunFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Release unlocks the run directory. Its files are kept until the next run of the module, unless the run is resumable
+func (r *RunDir) Release() {
+	err := os.Remove(filepath.Join(r.Path, RunLockFileName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warnf("error unlocking MPF run directory %s: %s\n", r.Path, err)
+	}
+}
+
+func getResumeRunName(mpfModuleDir string) string {
+	content, err := os.ReadFile(filepath.Join(mpfModuleDir, ResumeRunFileName))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(strings.TrimSpace(string(content)))
+}
+
+// lockRunDir creates the lock file of the run directory with the process ID of MPF. The lock file of a process
+// which is no longer running is replaced
+func lockRunDir(runDirPath string) error {
+	lockFilePath := filepath.Join(runDirPath, RunLockFileName)
+	if pid, ok := readLockPID(lockFilePath); ok {
+		if isProcessRunning(pid) {
+			return ErrRunDirLocked
+		}
+		_ = os.Remove(lockFilePath)
+	}
+
+	lockFile, err := os.OpenFile(lockFilePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return ErrRunDirLocked
+	}
+	if err != nil {
+		return err
+	}
+	defer lockFile.Close()
+
+	_, err = lockFile.WriteString(strconv.Itoa(os.Getpid()))
+	return err
+}
+
+// isRunDirInProgress returns whether the run directory is locked by a running process, or was created by a running process
+// which has not locked it yet
+func isRunDirInProgress(runDirPath string) bool {
+	if pid, ok := readLockPID(filepath.Join(runDirPath, RunLockFileName)); ok {
+		return isProcessRunning(pid)
+	}
+
+	pidPrefix, _, _ := strings.Cut(filepath.Base(runDirPath), "-")
+	pid, err := strconv.Atoi(pidPrefix)
+	return err == nil && pid != os.Getpid() && isProcessRunning(pid)
+}
+
+// removeEarlierRunDirs removes the run directories of earlier runs, which are neither in progress nor resumable
+func removeEarlierRunDirs(runsDir string, currentRunDirPath string, resumeRunName string) {
+	entries, err := os.ReadDir(runsDir)
+	if err != nil {
+		log.Warnf("error reading MPF runs directory %s: %s\n", runsDir, err)
+		return
+	}
+
+	for _, entry := range entries {
+		runDirPath := filepath.Join(runsDir, entry.Name())
+		if !entry.IsDir() || runDirPath == currentRunDirPath || entry.Name() == resumeRunName || isRunDirInProgress(runDirPath) {
+			continue
+		}
+		err = os.RemoveAll(runDirPath)
+		if err != nil {
+			log.Warnf("error removing earlier MPF run directory %s: %s\n", runDirPath, err)
+		}
+	}
+}
+
+func readLockPID(lockFilePath string) (int, bool) {
+	content, err := os.ReadFile(lockFilePath)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	return pid, err == nil
+}
+
+func isProcessRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// on windows FindProcess fails for processes which are not running
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
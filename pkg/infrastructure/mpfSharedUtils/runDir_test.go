@@ -0,0 +1,66 @@
+package mpfSharedUtils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRunDir(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	runDir, err := NewRunDir("terraform", "/tmp/module")
+	assert.Nil(t, err)
+	assert.DirExists(t, runDir.Path)
+	assert.FileExists(t, filepath.Join(runDir.Path, RunLockFileName))
+
+	// concurrent runs of the same module have their own run directory
+	concurrentRunDir, err := NewRunDir("terraform", "/tmp/module")
+	assert.Nil(t, err)
+	assert.Equal(t, runDir.ModuleDir, concurrentRunDir.ModuleDir)
+	assert.NotEqual(t, runDir.Path, concurrentRunDir.Path)
+
+	otherRunDir, err := NewRunDir("terraform", "/tmp/other-module")
+	assert.Nil(t, err)
+	assert.NotEqual(t, runDir.ModuleDir, otherRunDir.ModuleDir)
+
+	// the run directories of earlier runs are removed, unless they are in progress
+	runDir.Release()
+	nextRunDir, err := NewRunDir("terraform", "/tmp/module")
+	assert.Nil(t, err)
+	assert.NoDirExists(t, runDir.Path)
+	assert.DirExists(t, concurrentRunDir.Path)
+	assert.DirExists(t, nextRunDir.Path)
+}
+
+func TestNewRunDirResumesInterruptedRun(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	runDir, err := NewRunDir("pulumi", "/tmp/project")
+	assert.Nil(t, err)
+	err = runDir.SetResumable(true)
+	assert.Nil(t, err)
+
+	// the run directory is not resumed while its run is in progress
+	concurrentRunDir, err := NewRunDir("pulumi", "/tmp/project")
+	assert.Nil(t, err)
+	assert.NotEqual(t, runDir.Path, concurrentRunDir.Path)
+
+	// the lock of a run which is no longer running does not prevent resuming it
+	err = os.WriteFile(filepath.Join(runDir.Path, RunLockFileName), []byte("-1"), 0600)
+	assert.Nil(t, err)
+	resumedRunDir, err := NewRunDir("pulumi", "/tmp/project")
+	assert.Nil(t, err)
+	assert.Equal(t, runDir.Path, resumedRunDir.Path)
+
+	// another run does not remove the pointer to the resumable run
+	err = concurrentRunDir.SetResumable(false)
+	assert.Nil(t, err)
+	assert.FileExists(t, filepath.Join(runDir.ModuleDir, ResumeRunFileName))
+
+	err = resumedRunDir.SetResumable(false)
+	assert.Nil(t, err)
+	assert.NoFileExists(t, filepath.Join(runDir.ModuleDir, ResumeRunFileName))
+}
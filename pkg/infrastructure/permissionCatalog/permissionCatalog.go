@@ -0,0 +1,236 @@
+package permissionCatalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/azureAPI"
+	log "github.com/sirupsen/logrus"
+)
+
+// PermissionCatalog reads the actions of built-in roles and the operations of resource providers,
+// which are used as the broad set of permissions for subtractive discovery
+type PermissionCatalog struct {
+	subscriptionID string
+	azAPIClient    *azureAPI.AzureAPIClients
+}
+
+func NewPermissionCatalog(subscriptionID string) *PermissionCatalog {
+	return &PermissionCatalog{
+		subscriptionID: subscriptionID,
+		azAPIClient:    azureAPI.NewAzureAPIClients(subscriptionID),
+	}
+}
+
+// GetBroadPermissions returns the operations of the providers, and the actions of the built-in role with
+// wildcards expanded to the matching operations. Wildcards of the role are expanded using the operations of the
+// namespace of the action, or for actions without a namespace such as *, using the operations of the providers
+func (c *PermissionCatalog) GetBroadPermissions(builtInRoleName string, providerNamespaces []string) ([]string, error) {
+	operationsByNamespace := make(map[string][]string)
+	var providerOperations []string
+	for _, namespace := range providerNamespaces {
+		operations, err := c.GetProviderOperations(namespace)
+		if err != nil {
+			return nil, err
+		}
+		operationsByNamespace[strings.ToLower(namespace)] = operations
+		providerOperations = append(providerOperations, operations...)
+	}
+
+	permissions := append([]string{}, providerOperations...)
+
+	if builtInRoleName != "" {
+		actions, err := c.GetBuiltInRoleActions(builtInRoleName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, action := range actions {
+			if !strings.Contains(action, "*") {
+				permissions = append(permissions, action)
+				continue
+			}
+
+			namespace, _, found := strings.Cut(action, "/")
+			if !found || strings.Contains(namespace, "*") {
+				permissions = append(permissions, ExpandPermissions([]string{action}, providerOperations)...)
+				continue
+			}
+
+			operations, ok := operationsByNamespace[strings.ToLower(namespace)]
+			if !ok {
+				operations, err = c.GetProviderOperations(namespace)
+				if err != nil {
+					log.Warnf("Unable to expand role action %s, skipping: %v\n", action, err)
+					continue
+				}
+				operationsByNamespace[strings.ToLower(namespace)] = operations
+			}
+			permissions = append(permissions, ExpandPermissions([]string{action}, operations)...)
+		}
+	}
+
+	return getSortedUniquePermissions(permissions), nil
+}
+
+// GetBuiltInRoleActions returns the actions of the built-in role, without its not actions
+func (c *PermissionCatalog) GetBuiltInRoleActions(roleName string) ([]string, error) {
+	filter := url.QueryEscape(fmt.Sprintf("roleName eq '%s'", roleName))
+	requestURL := fmt.Sprintf("https://management.azure.com/subscriptions/%s/providers/Microsoft.Authorization/roleDefinitions?$filter=%s&api-version=2022-04-01", c.subscriptionID, filter)
+
+	var roleDefinitions struct {
+		Value []struct {
+			Properties struct {
+				RoleName    string `json:"roleName"`
+				Permissions []struct {
+					Actions    []string `json:"actions"`
+					NotActions []string `json:"notActions"`
+				} `json:"permissions"`
+			} `json:"properties"`
+		} `json:"value"`
+	}
+
+	err := c.get(requestURL, &roleDefinitions)
+	if err != nil {
+		return nil, fmt.Errorf("error getting role definition %s: %w", roleName, err)
+	}
+
+	if len(roleDefinitions.Value) == 0 {
+		return nil, fmt.Errorf("built-in role not found: %s", roleName)
+	}
+
+	var actions []string
+	for _, permission := range roleDefinitions.Value[0].Properties.Permissions {
+		if len(permission.NotActions) > 0 {
+			log.Warnf("Not actions of role %s are ignored: %v\n", roleName, permission.NotActions)
+		}
+		actions = append(actions, permission.Actions...)
+	}
+	return actions, nil
+}
+
+// GetProviderOperations returns the control plane operations of the resource provider namespace
+func (c *PermissionCatalog) GetProviderOperations(namespace string) ([]string, error) {
+	requestURL := fmt.Sprintf("https://management.azure.com/providers/Microsoft.Authorization/providerOperations/%s?$expand=resourceTypes&api-version=2022-04-01", namespace)
+
+	type operation struct {
+		Name         string `json:"name"`
+		IsDataAction bool   `json:"isDataAction"`
+	}
+	var providerOperations struct {
+		Operations    []operation `json:"operations"`
+		ResourceTypes []struct {
+			Operations []operation `json:"operations"`
+		} `json:"resourceTypes"`
+	}
+
+	err := c.get(requestURL, &providerOperations)
+	if err != nil {
+		return nil, fmt.Errorf("error getting operations of provider %s: %w", namespace, err)
+	}
+
+	allOperations := providerOperations.Operations
+	for _, resourceType := range providerOperations.ResourceTypes {
+		allOperations = append(allOperations, resourceType.Operations...)
+	}
+
+	var operations []string
+	for _, op := range allOperations {
+		if !op.IsDataAction {
+			operations = append(operations, op.Name)
+		}
+	}
+	return getSortedUniquePermissions(operations), nil
+}
+
+func (c *PermissionCatalog) get(requestURL string, v interface{}) error {
+	bearerToken, err := c.azAPIClient.GetDefaultAPIBearerToken()
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{}
+
+	req, err := http.NewRequest("GET", requestURL, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "Go HTTP Client")
+
+	// add bearer token to header
+	req.Header.Add("Authorization", "Bearer "+bearerToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status code: %d, %s", resp.StatusCode, string(body))
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// ExpandPermissions returns the operations matching the permissions, which can contain * wildcards
+func ExpandPermissions(permissions []string, operations []string) []string {
+	var expanded []string
+	for _, permission := range permissions {
+		for _, op := range operations {
+			if matchesPermission(permission, op) {
+				expanded = append(expanded, op)
+			}
+		}
+	}
+	return getSortedUniquePermissions(expanded)
+}
+
+// matchesPermission matches the operation against the permission case insensitively, where * matches any characters
+func matchesPermission(permission string, operation string) bool {
+	parts := strings.Split(strings.ToLower(permission), "*")
+	op := strings.ToLower(operation)
+
+	if !strings.HasPrefix(op, parts[0]) {
+		return false
+	}
+	op = op[len(parts[0]):]
+
+	for i := 1; i < len(parts); i++ {
+		if i == len(parts)-1 {
+			return strings.HasSuffix(op, parts[i])
+		}
+		idx := strings.Index(op, parts[i])
+		if idx < 0 {
+			return false
+		}
+		op = op[idx+len(parts[i]):]
+	}
+	return op == ""
+}
+
+func getSortedUniquePermissions(permissions []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, permission := range permissions {
+		if !seen[strings.ToLower(permission)] {
+			seen[strings.ToLower(permission)] = true
+			unique = append(unique, permission)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
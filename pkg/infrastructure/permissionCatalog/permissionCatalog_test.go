@@ -0,0 +1,73 @@
+package permissionCatalog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var networkOperations = []string{
+	"Microsoft.Network/virtualNetworks/read",
+	"Microsoft.Network/virtualNetworks/write",
+	"Microsoft.Network/virtualNetworks/delete",
+	"Microsoft.Network/virtualNetworks/subnets/read",
+	"Microsoft.Network/virtualNetworks/subnets/join/action",
+	"Microsoft.Network/publicIPAddresses/read",
+}
+
+func TestExpandPermissions(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions []string
+		expected    []string
+	}{
+		{
+			name:        "Trailing wildcard",
+			permissions: []string{"Microsoft.Network/virtualNetworks/*"},
+			expected: []string{
+				"Microsoft.Network/virtualNetworks/delete",
+				"Microsoft.Network/virtualNetworks/read",
+				"Microsoft.Network/virtualNetworks/subnets/join/action",
+				"Microsoft.Network/virtualNetworks/subnets/read",
+				"Microsoft.Network/virtualNetworks/write",
+			},
+		},
+		{
+			name:        "Wildcard within permission, case insensitive",
+			permissions: []string{"microsoft.network/*/read"},
+			expected: []string{
+				"Microsoft.Network/publicIPAddresses/read",
+				"Microsoft.Network/virtualNetworks/read",
+				"Microsoft.Network/virtualNetworks/subnets/read",
+			},
+		},
+		{
+			name:        "All operations",
+			permissions: []string{"*"},
+			expected: []string{
+				"Microsoft.Network/publicIPAddresses/read",
+				"Microsoft.Network/virtualNetworks/delete",
+				"Microsoft.Network/virtualNetworks/read",
+				"Microsoft.Network/virtualNetworks/subnets/join/action",
+				"Microsoft.Network/virtualNetworks/subnets/read",
+				"Microsoft.Network/virtualNetworks/write",
+			},
+		},
+		{
+			name:        "Permission without wildcard",
+			permissions: []string{"Microsoft.Network/virtualNetworks/write", "Microsoft.Network/virtualNetworks/write"},
+			expected:    []string{"Microsoft.Network/virtualNetworks/write"},
+		},
+		{
+			name:        "No match",
+			permissions: []string{"Microsoft.Storage/*"},
+			expected:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ExpandPermissions(tt.permissions, networkOperations))
+		})
+	}
+}
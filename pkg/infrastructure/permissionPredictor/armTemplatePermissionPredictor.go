@@ -0,0 +1,129 @@
+package permissionPredictor
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/infrastructure/ARMTemplateShared"
+)
+
+const deploymentsResourceType = "Microsoft.Resources/deployments"
+
+// Linked actions required when a resource references another resource through the property,
+// for example a subnet ID in the properties of an AKS agent pool requires the subnet join action
+var linkedActionsByPropertyName = map[string]string{
+	"subnet":                          "Microsoft.Network/virtualNetworks/subnets/join/action",
+	"subnetid":                        "Microsoft.Network/virtualNetworks/subnets/join/action",
+	"vnetsubnetid":                    "Microsoft.Network/virtualNetworks/subnets/join/action",
+	"podsubnetid":                     "Microsoft.Network/virtualNetworks/subnets/join/action",
+	"virtualnetworksubnetid":          "Microsoft.Network/virtualNetworks/subnets/join/action",
+	"networksecuritygroup":            "Microsoft.Network/networkSecurityGroups/join/action",
+	"publicipaddress":                 "Microsoft.Network/publicIPAddresses/join/action",
+	"publicipaddresses":               "Microsoft.Network/publicIPAddresses/join/action",
+	"routetable":                      "Microsoft.Network/routeTables/join/action",
+	"networkinterfaces":               "Microsoft.Network/networkInterfaces/join/action",
+	"loadbalancerbackendaddresspools": "Microsoft.Network/loadBalancers/backendAddressPools/join/action",
+	"applicationsecuritygroups":       "Microsoft.Network/applicationSecurityGroups/joinIpConfiguration/action",
+	"userassignedidentities":          "Microsoft.ManagedIdentity/userAssignedIdentities/assign/action",
+}
+
+// PredictARMTemplateFilePermissions predicts the permissions required to deploy the ARM template file,
+// with relative linked templates resolved
+func PredictARMTemplateFilePermissions(templateFilePath string) ([]string, error) {
+	template, err := ARMTemplateShared.LoadTemplate(templateFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return PredictARMTemplatePermissions(template), nil
+}
+
+// PredictARMTemplatePermissions predicts the permissions required to deploy the ARM template without calling Azure.
+// Write and read permissions are predicted for each resource, read permissions for existing resources, and linked
+// actions for references to other resources. Nested deployments with inline templates are included
+func PredictARMTemplatePermissions(template map[string]interface{}) []string {
+	permissions := make(map[string]bool)
+	addTemplatePermissions(template, permissions)
+
+	predictedPermissions := make([]string, 0, len(permissions))
+	for permission := range permissions {
+		predictedPermissions = append(predictedPermissions, permission)
+	}
+	sort.Strings(predictedPermissions)
+	return predictedPermissions
+}
+
+func addTemplatePermissions(template map[string]interface{}, permissions map[string]bool) {
+	for _, resource := range ARMTemplateShared.GetTemplateResources(template) {
+		addResourcePermissions(resource, "", permissions)
+	}
+}
+
+func addResourcePermissions(resource map[string]interface{}, parentType string, permissions map[string]bool) {
+	resourceType, _ := resource["type"].(string)
+	if resourceType == "" || strings.HasPrefix(resourceType, "[") {
+		return
+	}
+
+	// child resources declared within the parent use the type relative to the parent, for example subnets
+	if parentType != "" && !strings.Contains(strings.Split(resourceType, "/")[0], ".") {
+		resourceType = parentType + "/" + resourceType
+	}
+
+	properties, _ := resource["properties"].(map[string]interface{})
+
+	if strings.EqualFold(resourceType, deploymentsResourceType) {
+		// permissions for the deployments themselves are part of the initial permissions
+		if nestedTemplate, ok := properties["template"].(map[string]interface{}); ok {
+			addTemplatePermissions(nestedTemplate, permissions)
+		}
+		return
+	}
+
+	permissions[resourceType+"/read"] = true
+
+	if existing, _ := resource["existing"].(bool); existing {
+		return
+	}
+
+	permissions[resourceType+"/write"] = true
+
+	addLinkedActions(properties, permissions)
+	addLinkedActions(resource["identity"], permissions)
+
+	childResources, _ := resource["resources"].([]interface{})
+	for _, r := range childResources {
+		if childResource, ok := r.(map[string]interface{}); ok {
+			addResourcePermissions(childResource, resourceType, permissions)
+		}
+	}
+}
+
+func addLinkedActions(value interface{}, permissions map[string]bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for propertyName, propertyValue := range v {
+			if linkedAction, ok := linkedActionsByPropertyName[strings.ToLower(propertyName)]; ok && !isEmptyValue(propertyValue) {
+				permissions[linkedAction] = true
+			}
+			addLinkedActions(propertyValue, permissions)
+		}
+	case []interface{}:
+		for _, item := range v {
+			addLinkedActions(item, permissions)
+		}
+	}
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package permissionPredictor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const aksTemplate = `{
+	"$schema": "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#",
+	"contentVersion": "1.0.0.0",
+	"resources": [
+		{
+			"type": "Microsoft.Network/virtualNetworks",
+			"apiVersion": "2021-02-01",
+			"name": "myVNet",
+			"properties": {
+				"addressSpace": {
+					"addressPrefixes": ["10.0.0.0/16"]
+				}
+			},
+			"resources": [
+				{
+					"type": "subnets",
+					"apiVersion": "2021-02-01",
+					"name": "mySubnet",
+					"properties": {
+						"addressPrefix": "10.0.0.0/24",
+						"networkSecurityGroup": {
+							"id": "[resourceId('Microsoft.Network/networkSecurityGroups', 'myNsg')]"
+						}
+					}
+				}
+			]
+		},
+		{
+			"type": "Microsoft.ContainerService/managedClusters",
+			"apiVersion": "2021-05-01",
+			"name": "myAKSCluster",
+			"identity": {
+				"type": "UserAssigned",
+				"userAssignedIdentities": {
+					"[resourceId('Microsoft.ManagedIdentity/userAssignedIdentities', 'myIdentity')]": {}
+				}
+			},
+			"properties": {
+				"agentPoolProfiles": [
+					{
+						"name": "agentpool",
+						"vnetSubnetID": "[resourceId('Microsoft.Network/virtualNetworks/subnets', 'myVNet', 'mySubnet')]",
+						"podSubnetID": ""
+					}
+				]
+			}
+		},
+		{
+			"type": "Microsoft.Resources/deployments",
+			"apiVersion": "2022-09-01",
+			"name": "nested",
+			"properties": {
+				"mode": "Incremental",
+				"template": {
+					"resources": {
+						"logs": {
+							"type": "Microsoft.OperationalInsights/workspaces",
+							"apiVersion": "2022-10-01",
+							"name": "myLogs",
+							"existing": true
+						},
+						"storage": {
+							"type": "Microsoft.Storage/storageAccounts",
+							"apiVersion": "2022-09-01",
+							"name": "mystorage"
+						}
+					}
+				}
+			}
+		}
+	]
+}`
+
+func TestPredictARMTemplatePermissions(t *testing.T) {
+	var template map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(aksTemplate), &template))
+
+	expected := []string{
+		"Microsoft.ContainerService/managedClusters/read",
+		"Microsoft.ContainerService/managedClusters/write",
+		"Microsoft.ManagedIdentity/userAssignedIdentities/assign/action",
+		"Microsoft.Network/networkSecurityGroups/join/action",
+		"Microsoft.Network/virtualNetworks/read",
+		"Microsoft.Network/virtualNetworks/subnets/join/action",
+		"Microsoft.Network/virtualNetworks/subnets/read",
+		"Microsoft.Network/virtualNetworks/subnets/write",
+		"Microsoft.Network/virtualNetworks/write",
+		"Microsoft.OperationalInsights/workspaces/read",
+		"Microsoft.Storage/storageAccounts/read",
+		"Microsoft.Storage/storageAccounts/write",
+	}
+
+	assert.Equal(t, expected, PredictARMTemplatePermissions(template))
+}
+
+func TestPredictARMTemplatePermissionsWithoutResources(t *testing.T) {
+	assert.Empty(t, PredictARMTemplatePermissions(map[string]interface{}{}))
+}
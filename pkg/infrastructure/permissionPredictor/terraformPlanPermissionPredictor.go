@@ -0,0 +1,82 @@
+package permissionPredictor
+
+import (
+	"sort"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	log "github.com/sirupsen/logrus"
+)
+
+const azapiResourceType = "azapi_resource"
+
+// PredictTerraformPlanPermissions predicts the permissions required to apply the terraform plan, and to destroy the
+// resources it creates. Resource types which are not part of the mapping table are skipped
+func PredictTerraformPlanPermissions(plan *tfjson.Plan) []string {
+	permissions := make(map[string]bool)
+
+	for _, resourceChange := range plan.ResourceChanges {
+		if resourceChange == nil || resourceChange.Change == nil {
+			continue
+		}
+
+		armResourceType := getARMResourceTypeFromResourceChange(resourceChange)
+		if armResourceType == "" {
+			log.Debugf("No ARM resource type mapping for terraform resource %s of type %s\n", resourceChange.Address, resourceChange.Type)
+			continue
+		}
+
+		actions := resourceChange.Change.Actions
+		switch {
+		case actions.Create() || actions.Replace():
+			// the resources created are destroyed by MPF after the apply
+			permissions[armResourceType+"/write"] = true
+			permissions[armResourceType+"/read"] = true
+			permissions[armResourceType+"/delete"] = true
+			addTerraformLinkedActions(resourceChange.Type, permissions)
+		case actions.Update():
+			permissions[armResourceType+"/write"] = true
+			permissions[armResourceType+"/read"] = true
+			addTerraformLinkedActions(resourceChange.Type, permissions)
+		case actions.Delete():
+			permissions[armResourceType+"/delete"] = true
+			permissions[armResourceType+"/read"] = true
+		case actions.Read() || actions.NoOp():
+			permissions[armResourceType+"/read"] = true
+		}
+	}
+
+	predictedPermissions := make([]string, 0, len(permissions))
+	for permission := range permissions {
+		predictedPermissions = append(predictedPermissions, permission)
+	}
+	sort.Strings(predictedPermissions)
+	return predictedPermissions
+}
+
+func addTerraformLinkedActions(terraformType string, permissions map[string]bool) {
+	for _, linkedAction := range linkedActionsByTerraformType[terraformType] {
+		permissions[linkedAction] = true
+	}
+}
+
+// getARMResourceTypeFromResourceChange returns the ARM resource type from the mapping table, or for azapi resources
+// from the type attribute, for example Microsoft.Network/virtualNetworks@2023-04-01
+func getARMResourceTypeFromResourceChange(resourceChange *tfjson.ResourceChange) string {
+	if resourceChange.Type != azapiResourceType {
+		return armResourceTypesByTerraformType[resourceChange.Type]
+	}
+
+	for _, values := range []interface{}{resourceChange.Change.After, resourceChange.Change.Before} {
+		attributes, ok := values.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		azapiType, _ := attributes["type"].(string)
+		if azapiType != "" {
+			armResourceType, _, _ := strings.Cut(azapiType, "@")
+			return armResourceType
+		}
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package permissionPredictor
+
+import (
+	"encoding/json"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+)
+
+const terraformPlanJSON = `{
+	"format_version": "1.2",
+	"terraform_version": "1.7.0",
+	"resource_changes": [
+		{
+			"address": "azurerm_resource_group.rg",
+			"mode": "managed",
+			"type": "azurerm_resource_group",
+			"name": "rg",
+			"change": {"actions": ["no-op"], "before": {}, "after": {}}
+		},
+		{
+			"address": "azurerm_network_interface.nic",
+			"mode": "managed",
+			"type": "azurerm_network_interface",
+			"name": "nic",
+			"change": {"actions": ["create"], "before": null, "after": {}}
+		},
+		{
+			"address": "azurerm_storage_account.sa",
+			"mode": "managed",
+			"type": "azurerm_storage_account",
+			"name": "sa",
+			"change": {"actions": ["update"], "before": {}, "after": {}}
+		},
+		{
+			"address": "azapi_resource.workspace",
+			"mode": "managed",
+			"type": "azapi_resource",
+			"name": "workspace",
+			"change": {"actions": ["delete"], "before": {"type": "Microsoft.OperationalInsights/workspaces@2022-10-01"}, "after": null}
+		},
+		{
+			"address": "random_string.suffix",
+			"mode": "managed",
+			"type": "random_string",
+			"name": "suffix",
+			"change": {"actions": ["create"], "before": null, "after": {}}
+		}
+	]
+}`
+
+func TestPredictTerraformPlanPermissions(t *testing.T) {
+	var plan tfjson.Plan
+	assert.NoError(t, json.Unmarshal([]byte(terraformPlanJSON), &plan))
+
+	expected := []string{
+		"Microsoft.Network/networkInterfaces/delete",
+		"Microsoft.Network/networkInterfaces/read",
+		"Microsoft.Network/networkInterfaces/write",
+		"Microsoft.Network/virtualNetworks/subnets/join/action",
+		"Microsoft.OperationalInsights/workspaces/delete",
+		"Microsoft.OperationalInsights/workspaces/read",
+		"Microsoft.Resources/subscriptions/resourceGroups/read",
+		"Microsoft.Storage/storageAccounts/read",
+		"Microsoft.Storage/storageAccounts/write",
+	}
+
+	assert.Equal(t, expected, PredictTerraformPlanPermissions(&plan))
+}
@@ -0,0 +1,76 @@
+package permissionPredictor
+
+// ARM resource types of the terraform azurerm resource types
+var armResourceTypesByTerraformType = map[string]string{
+	"azurerm_resource_group":                            "Microsoft.Resources/subscriptions/resourceGroups",
+	"azurerm_virtual_network":                           "Microsoft.Network/virtualNetworks",
+	"azurerm_subnet":                                    "Microsoft.Network/virtualNetworks/subnets",
+	"azurerm_virtual_network_peering":                   "Microsoft.Network/virtualNetworks/virtualNetworkPeerings",
+	"azurerm_network_security_group":                    "Microsoft.Network/networkSecurityGroups",
+	"azurerm_network_security_rule":                     "Microsoft.Network/networkSecurityGroups/securityRules",
+	"azurerm_subnet_network_security_group_association": "Microsoft.Network/virtualNetworks/subnets",
+	"azurerm_subnet_route_table_association":            "Microsoft.Network/virtualNetworks/subnets",
+	"azurerm_subnet_nat_gateway_association":            "Microsoft.Network/virtualNetworks/subnets",
+	"azurerm_route_table":                               "Microsoft.Network/routeTables",
+	"azurerm_route":                                     "Microsoft.Network/routeTables/routes",
+	"azurerm_public_ip":                                 "Microsoft.Network/publicIPAddresses",
+	"azurerm_nat_gateway":                               "Microsoft.Network/natGateways",
+	"azurerm_network_interface":                         "Microsoft.Network/networkInterfaces",
+	"azurerm_lb":                                        "Microsoft.Network/loadBalancers",
+	"azurerm_application_gateway":                       "Microsoft.Network/applicationGateways",
+	"azurerm_web_application_firewall_policy":           "Microsoft.Network/ApplicationGatewayWebApplicationFirewallPolicies",
+	"azurerm_private_endpoint":                          "Microsoft.Network/privateEndpoints",
+	"azurerm_private_dns_zone":                          "Microsoft.Network/privateDnsZones",
+	"azurerm_private_dns_zone_virtual_network_link":     "Microsoft.Network/privateDnsZones/virtualNetworkLinks",
+	"azurerm_dns_zone":                                  "Microsoft.Network/dnsZones",
+	"azurerm_storage_account":                           "Microsoft.Storage/storageAccounts",
+	"azurerm_storage_container":                         "Microsoft.Storage/storageAccounts/blobServices/containers",
+	"azurerm_storage_share":                             "Microsoft.Storage/storageAccounts/fileServices/shares",
+	"azurerm_key_vault":                                 "Microsoft.KeyVault/vaults",
+	"azurerm_kubernetes_cluster":                        "Microsoft.ContainerService/managedClusters",
+	"azurerm_kubernetes_cluster_node_pool":              "Microsoft.ContainerService/managedClusters/agentPools",
+	"azurerm_container_registry":                        "Microsoft.ContainerRegistry/registries",
+	"azurerm_log_analytics_workspace":                   "Microsoft.OperationalInsights/workspaces",
+	"azurerm_application_insights":                      "Microsoft.Insights/components",
+	"azurerm_monitor_diagnostic_setting":                "Microsoft.Insights/diagnosticSettings",
+	"azurerm_user_assigned_identity":                    "Microsoft.ManagedIdentity/userAssignedIdentities",
+	"azurerm_role_assignment":                           "Microsoft.Authorization/roleAssignments",
+	"azurerm_role_definition":                           "Microsoft.Authorization/roleDefinitions",
+	"azurerm_linux_virtual_machine":                     "Microsoft.Compute/virtualMachines",
+	"azurerm_windows_virtual_machine":                   "Microsoft.Compute/virtualMachines",
+	"azurerm_linux_virtual_machine_scale_set":           "Microsoft.Compute/virtualMachineScaleSets",
+	"azurerm_windows_virtual_machine_scale_set":         "Microsoft.Compute/virtualMachineScaleSets",
+	"azurerm_managed_disk":                              "Microsoft.Compute/disks",
+	"azurerm_service_plan":                              "Microsoft.Web/serverfarms",
+	"azurerm_linux_web_app":                             "Microsoft.Web/sites",
+	"azurerm_windows_web_app":                           "Microsoft.Web/sites",
+	"azurerm_linux_function_app":                        "Microsoft.Web/sites",
+	"azurerm_windows_function_app":                      "Microsoft.Web/sites",
+	"azurerm_container_app_environment":                 "Microsoft.App/managedEnvironments",
+	"azurerm_container_app":                             "Microsoft.App/containerApps",
+	"azurerm_cosmosdb_account":                          "Microsoft.DocumentDB/databaseAccounts",
+	"azurerm_mssql_server":                              "Microsoft.Sql/servers",
+	"azurerm_mssql_database":                            "Microsoft.Sql/servers/databases",
+	"azurerm_postgresql_flexible_server":                "Microsoft.DBforPostgreSQL/flexibleServers",
+	"azurerm_redis_cache":                               "Microsoft.Cache/redis",
+	"azurerm_eventhub_namespace":                        "Microsoft.EventHub/namespaces",
+	"azurerm_eventhub":                                  "Microsoft.EventHub/namespaces/eventhubs",
+	"azurerm_servicebus_namespace":                      "Microsoft.ServiceBus/namespaces",
+	"azurerm_servicebus_queue":                          "Microsoft.ServiceBus/namespaces/queues",
+	"azurerm_api_management":                            "Microsoft.ApiManagement/service",
+}
+
+// Linked actions required to create or update the terraform resource types, in addition to the write permission
+var linkedActionsByTerraformType = map[string][]string{
+	"azurerm_subnet_network_security_group_association": {"Microsoft.Network/networkSecurityGroups/join/action"},
+	"azurerm_subnet_route_table_association":            {"Microsoft.Network/routeTables/join/action"},
+	"azurerm_subnet_nat_gateway_association":            {"Microsoft.Network/natGateways/join/action"},
+	"azurerm_network_interface":                         {"Microsoft.Network/virtualNetworks/subnets/join/action"},
+	"azurerm_private_endpoint":                          {"Microsoft.Network/virtualNetworks/subnets/join/action"},
+	"azurerm_private_dns_zone_virtual_network_link":     {"Microsoft.Network/virtualNetworks/join/action"},
+	"azurerm_linux_virtual_machine":                     {"Microsoft.Network/networkInterfaces/join/action"},
+	"azurerm_windows_virtual_machine":                   {"Microsoft.Network/networkInterfaces/join/action"},
+	"azurerm_application_gateway":                       {"Microsoft.Network/virtualNetworks/subnets/join/action", "Microsoft.Network/publicIPAddresses/join/action"},
+	"azurerm_lb":                                        {"Microsoft.Network/publicIPAddresses/join/action"},
+	"azurerm_nat_gateway":                               {"Microsoft.Network/publicIPAddresses/join/action"},
+}
@@ -60,6 +60,7 @@ func (r *SPRoleAssignmentManager) createUpdateCustomRole(subscription string, ro
 
 	// rgScope := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s", subscription, resourceGroupName)
 	subScope := fmt.Sprintf("/subscriptions/%s", subscription)
+	actions, dataActions := domain.SplitActionsAndDataActions(permissions)
 
 	data := map[string]interface{}{
 		"assignableScopes": []string{
@@ -71,8 +72,8 @@ func (r *SPRoleAssignmentManager) createUpdateCustomRole(subscription string, ro
 		"name":        role.RoleDefinitionID,
 		"permissions": []map[string]interface{}{
 			{
-				"actions":        permissions,
-				"dataActions":    []string{},
+				"actions":        actions,
+				"dataActions":    dataActions,
 				"notActions":     []string{},
 				"notDataActions": []string{},
 			},
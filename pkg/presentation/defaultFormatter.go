@@ -26,6 +26,18 @@ func (d *displayConfig) displayText(w io.Writer) error {
 	fmt.Println("------------------------------------------------------------------------------------------------------------------------------------------")
 	fmt.Println()
 
+	d.displayTextByStage()
+
+	d.displayTextRolesByStage()
+
+	d.displayTextPredictedPermissions()
+
+	d.displayTextVerifiedPermissions()
+
+	d.displayTextSufficiencyVerification()
+
+	d.displayTextGraphPermissions()
+
 	if !d.displayOptions.ShowDetailedOutput {
 		return nil
 	}
@@ -53,3 +65,133 @@ func (d *displayConfig) displayText(w io.Writer) error {
 	}
 	return nil
 }
+
+// print the permissions found by each stage, for checkers that discover permissions in multiple stages
+func (d *displayConfig) displayTextByStage() {
+	if len(d.result.RequiredPermissionsByStage) == 0 {
+		return
+	}
+
+	stages := make([]string, 0, len(d.result.RequiredPermissionsByStage))
+	for stage := range d.result.RequiredPermissionsByStage {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+
+	fmt.Println("Permissions found by each stage:")
+	fmt.Println()
+	for _, stage := range stages {
+		perms := d.result.RequiredPermissionsByStage[stage]
+		sort.Strings(perms)
+
+		fmt.Printf("Permissions found in stage %s: \n", stage)
+		for _, perm := range perms {
+			fmt.Println(perm)
+		}
+		fmt.Println("--------------")
+		fmt.Println()
+	}
+}
+
+// print the permissions of a role which can run each stage, for example a role for terraform apply and one for terraform destroy
+func (d *displayConfig) displayTextRolesByStage() {
+	if len(d.result.RolePermissionsByStage) == 0 {
+		return
+	}
+
+	stages := make([]string, 0, len(d.result.RolePermissionsByStage))
+	for stage := range d.result.RolePermissionsByStage {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+
+	fmt.Println("Role permissions required to run each stage:")
+	fmt.Println()
+	for _, stage := range stages {
+		fmt.Printf("Role for stage %s: \n", stage)
+		for _, perm := range d.result.RolePermissionsByStage[stage] {
+			fmt.Println(perm)
+		}
+		fmt.Println("--------------")
+		fmt.Println()
+	}
+}
+
+// print the predicted permissions which were confirmed to be required, and which were pruned
+func (d *displayConfig) displayTextPredictedPermissions() {
+	if len(d.result.ConfirmedPredictedPermissions) == 0 && len(d.result.PrunedPredictedPermissions) == 0 {
+		return
+	}
+
+	fmt.Println("Predicted permissions confirmed as required:")
+	for _, perm := range d.result.ConfirmedPredictedPermissions {
+		fmt.Println(perm)
+	}
+	fmt.Println("--------------")
+	fmt.Println()
+
+	fmt.Println("Predicted permissions pruned as not required:")
+	for _, perm := range d.result.PrunedPredictedPermissions {
+		fmt.Println(perm)
+	}
+	fmt.Println("--------------")
+	fmt.Println()
+}
+
+// print the permissions marked as required or unnecessary by the ablation pass
+func (d *displayConfig) displayTextVerifiedPermissions() {
+	if len(d.result.VerifiedRequiredPermissions) == 0 && len(d.result.UnnecessaryPermissions) == 0 {
+		return
+	}
+
+	fmt.Println("Permissions verified as required:")
+	for _, perm := range d.result.VerifiedRequiredPermissions {
+		fmt.Println(perm)
+	}
+	fmt.Println("--------------")
+	fmt.Println()
+
+	fmt.Println("Permissions verified as unnecessary:")
+	for _, perm := range d.result.UnnecessaryPermissions {
+		fmt.Println(perm)
+	}
+	fmt.Println("--------------")
+	fmt.Println()
+}
+
+// print whether a fresh role with exactly the required permissions was sufficient for the deployment
+func (d *displayConfig) displayTextSufficiencyVerification() {
+	verification := d.result.SufficiencyVerification
+	if verification == nil {
+		return
+	}
+
+	if verification.Passed {
+		fmt.Println("Sufficiency verification with a fresh role: PASSED")
+	} else {
+		fmt.Println("Sufficiency verification with a fresh role: FAILED")
+		fmt.Println(verification.Details)
+	}
+	if len(verification.InitialPermissions) > 0 {
+		fmt.Println("The fresh role also contained the initial permissions MPF grants for the checks:")
+		for _, perm := range verification.InitialPermissions {
+			fmt.Println(perm)
+		}
+	}
+	fmt.Println("--------------")
+	fmt.Println()
+}
+
+// print the Microsoft Graph permissions required, which can not be granted by an Azure role
+func (d *displayConfig) displayTextGraphPermissions() {
+	if len(d.result.GraphPermissions) == 0 {
+		return
+	}
+
+	fmt.Println("Microsoft Graph permissions required:")
+	for _, perm := range d.result.GraphPermissions {
+		fmt.Println(perm)
+	}
+	fmt.Println("--------------")
+	fmt.Println()
+}
@@ -6,8 +6,20 @@ import (
 	"log"
 )
 
+// The key of the Microsoft Graph permissions, which are output alongside the permissions of the scopes, as they are not granted at a scope
+const jsonGraphPermissionsKey = "MicrosoftGraph"
+
 func (d *displayConfig) displayJSON(w io.Writer) error {
-	jsonBytes, err := json.Marshal(d.result.RequiredPermissions)
+	permissions := d.result.RequiredPermissions
+	if len(d.result.GraphPermissions) > 0 {
+		permissions = make(map[string][]string, len(d.result.RequiredPermissions)+1)
+		for scope, perms := range d.result.RequiredPermissions {
+			permissions[scope] = perms
+		}
+		permissions[jsonGraphPermissionsKey] = d.result.GraphPermissions
+	}
+
+	jsonBytes, err := json.Marshal(permissions)
 	if err != nil {
 		log.Fatalf("Error converting output to JSON :%v \n", err)
 	}
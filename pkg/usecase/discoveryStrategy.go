@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+// DiscoveryStrategy discovers the permissions required by the deployment, with the custom role initialized and assigned to the
+// service principal. The permissions found are added to the required permissions of the service
+type DiscoveryStrategy interface {
+	DiscoverPermissions(s *MPFService) error
+}
+
+// SetDiscoveryStrategy sets the strategy used to discover the permissions, by default permissions are added from authorization errors
+func (s *MPFService) SetDiscoveryStrategy(discoveryStrategy DiscoveryStrategy) {
+	s.discoveryStrategy = discoveryStrategy
+}
+
+func (s *MPFService) getDiscoveryStrategy() DiscoveryStrategy {
+	if s.discoveryStrategy == nil {
+		return NewAdditiveDiscoveryStrategy()
+	}
+	return s.discoveryStrategy
+}
+
+type additiveDiscoveryStrategy struct{}
+
+// NewAdditiveDiscoveryStrategy returns the strategy which runs the deployment, and adds the permissions of each authorization error
+// to the role until the deployment is authorized
+func NewAdditiveDiscoveryStrategy() DiscoveryStrategy {
+	return &additiveDiscoveryStrategy{}
+}
+
+func (a *additiveDiscoveryStrategy) DiscoverPermissions(s *MPFService) error {
+	maxIterations := 50
+	iterCount := 0
+	for {
+		authErrMesg, err := s.deploymentAuthCheckerCleaner.GetDeploymentAuthorizationErrors(s.mpfConfig)
+
+		log.Debugf("Iteration Number: %d \n", iterCount)
+
+		if authErrMesg == "" && err == nil && len(s.predictedPermissions) > 0 && !s.predictedPermissionsVerified {
+			log.Infoln("Authorization Successful with predicted permissions, removing the predicted permissions which are not required")
+			err = s.prunePredictedPermissions()
+			if err != nil {
+				log.Warn(err)
+				return err
+			}
+			log.Infoln("Authorization Successful")
+			return nil
+		}
+
+		if authErrMesg == "" && err == nil {
+			log.Infoln("Authorization Successful")
+			return nil
+		}
+
+		log.Debugln("authErrMesg: ", authErrMesg)
+
+		// Temporary fix to workaround issue https://github.com/hashicorp/terraform-provider-azurerm/issues/27961
+		// It is observed only once, so retrying works
+		if err == nil && strings.Contains(authErrMesg, BillingFeaturesPayloadError) {
+			log.Warnf("Billing Features Payload Error: %v, retrying.... \n", err)
+			continue
+		}
+
+		// if err == nil && strings.Contains(err.Error(), AuthorizationPermissionMismatchErr) {
+		// 	log.Warnf("AuthrorizationPermissionMismatchErr Error: %v, retrying.... \n", err)
+		// 	continue
+		// }
+
+		if err != nil {
+			log.Warnf("Non Authorization error received: %v \n", err)
+			return err
+		}
+
+		log.Debugln("Deployment Authorization Error:", authErrMesg)
+
+		if domain.IsGraphAuthorizationError(authErrMesg) {
+			err = s.addGraphPermissions(authErrMesg)
+			if err != nil {
+				log.Warnf("Microsoft Graph Authorization Error: %v \n", err)
+				return err
+			}
+			// the Microsoft Graph permissions were granted to the service principal, retry the deployment
+			if !domain.IsAzureAuthorizationError(authErrMesg) {
+				continue
+			}
+		}
+
+		scpMp, err := domain.GetScopePermissionsFromAuthError(authErrMesg)
+		if err != nil {
+			log.Warnf("Could Not Parse Deployment Authorization Error: %v \n", err)
+			return err
+		}
+
+		log.Infoln("Successfully Parsed Deployment Authorization Error")
+		log.Debugln("scope permissions found from deployment error:", scpMp)
+
+		// auto add read and delete permissions as per configuration
+		for scope, permissions := range scpMp {
+			for _, permission := range permissions {
+				if s.autoAddReadPermissionForEachWrite && strings.HasSuffix(permission, "/write") {
+					readPermission := strings.Replace(permission, "/write", "/read", 1)
+					scpMp[scope] = append(scpMp[scope], readPermission)
+				}
+				if s.autoAddDeletePermissionForEachWrite && strings.HasSuffix(permission, "/write") {
+					deletePermission := strings.Replace(permission, "/write", "/delete", 1)
+					scpMp[scope] = append(scpMp[scope], deletePermission)
+				}
+			}
+		}
+
+		log.Infoln("Adding mising scopes/permissions to final result map...")
+		for k, v := range scpMp {
+			s.requiredPermissions[k] = append(s.requiredPermissions[k], v...)
+			s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID] = append(s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID], v...)
+		}
+
+		if stageReporter, ok := s.deploymentAuthCheckerCleaner.(DeploymentAuthorizationCheckerStageReporter); ok {
+			stage := stageReporter.GetCurrentStage()
+			for _, v := range scpMp {
+				s.requiredPermissionsByStage[stage] = append(s.requiredPermissionsByStage[stage], v...)
+			}
+		}
+
+		// assign permission to role
+		log.Infoln("Adding permission/scope to role...........")
+		log.Debugln("Number of Permissions added to role:", len(s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID]))
+
+		err = s.spRoleAssignmentManager.CreateUpdateCustomRole(s.mpfConfig.SubscriptionID, s.mpfConfig.Role, s.getRolePermissions())
+
+		// err = s.spRoleAssignmentManager.CreateUpdateCustomRole(s.mpfConfig.SubscriptionID, s.mpfConfig.ResourceGroup.ResourceGroupName, s.mpfConfig.Role, s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID])
+
+		if err != nil {
+			log.Infoln("Error when adding permission/scope to role: \n", err)
+			log.Warn(err)
+			return err
+		}
+		log.Infoln("Permission/scope added to role successfully")
+
+		iterCount++
+		if iterCount == maxIterations {
+			log.Warnln("max iterations for fetching authorization errors reached, exiting...")
+			return err
+		}
+	}
+}
+
+type subtractiveDiscoveryStrategy struct {
+	broadPermissions []string
+}
+
+// NewSubtractiveDiscoveryStrategy returns the strategy which starts from the broad permissions, for example the actions of a built-in
+// role or the operations of resource providers, confirms that the deployment is authorized, and then removes permissions by bisection
+// until only the required permissions remain
+func NewSubtractiveDiscoveryStrategy(broadPermissions []string) DiscoveryStrategy {
+	return &subtractiveDiscoveryStrategy{
+		broadPermissions: broadPermissions,
+	}
+}
+
+func (d *subtractiveDiscoveryStrategy) DiscoverPermissions(s *MPFService) error {
+	candidates := getUniqueStrings(d.broadPermissions)
+	log.Infof("Confirming that the deployment is authorized with the %d broad permissions\n", len(candidates))
+
+	authErrMesg, authorized, err := s.checkWithPermissions(append(append([]string{}, s.initialPermissionsToAdd...), candidates...))
+	if err != nil {
+		return fmt.Errorf("error checking the deployment with the %d broad permissions: %w", len(candidates), err)
+	}
+	if !authorized {
+		return fmt.Errorf("deployment is not authorized with the %d broad permissions, a broader set of permissions is required: %s", len(candidates), authErrMesg)
+	}
+
+	log.Infoln("Authorization Successful with broad permissions, removing permissions which are not required")
+	required, unnecessary, err := s.ablatePermissions(candidates, s.initialPermissionsToAdd)
+	if err != nil {
+		return err
+	}
+	log.Infof("Found %d required permissions, removed %d permissions which are not required\n", len(required), len(unnecessary))
+
+	s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID] = append(s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID], required...)
+	return s.spRoleAssignmentManager.CreateUpdateCustomRole(s.mpfConfig.SubscriptionID, s.mpfConfig.Role, s.getRolePermissions())
+}
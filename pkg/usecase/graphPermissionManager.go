@@ -0,0 +1,9 @@
+package usecase
+
+// GraphPermissionManager grants and revokes the Microsoft Graph application permissions of the service principal,
+// which are app roles of the Microsoft Graph service principal assigned to it. AssignGraphPermissionsToSP returns the permissions
+// it assigned, without the permissions which were already assigned to the service principal
+type GraphPermissionManager interface {
+	AssignGraphPermissionsToSP(SPObjectID string, permissions []string) ([]string, error)
+	RevokeGraphPermissionsFromSP(SPObjectID string, permissions []string) error
+}
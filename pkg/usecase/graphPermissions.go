@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+// SetGraphPermissionManager sets the manager used to grant the Microsoft Graph permissions found to the service principal,
+// so that permissions are discovered past Microsoft Graph authorization errors. The permissions granted are revoked on clean up
+func (s *MPFService) SetGraphPermissionManager(graphPermissionManager GraphPermissionManager) {
+	s.graphPermissionManager = graphPermissionManager
+}
+
+// addGraphPermissions adds the Microsoft Graph permissions of the Microsoft Graph authorization errors to the Microsoft Graph
+// permissions of the result, and grants them to the service principal when a Microsoft Graph permission manager is set.
+// They are reported separately, as Microsoft Graph permissions can not be granted by an Azure role
+func (s *MPFService) addGraphPermissions(authErrMesg string) error {
+	graphPermissions, err := domain.GetGraphPermissionsFromAuthError(authErrMesg)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Microsoft Graph permissions required: %v \n", graphPermissions)
+	s.graphPermissions = domain.GetUniqueSortedPermissions(append(s.graphPermissions, graphPermissions...))
+
+	if s.graphPermissionManager == nil {
+		if domain.IsAzureAuthorizationError(authErrMesg) {
+			return nil
+		}
+		return fmt.Errorf("the deployment requires the Microsoft Graph permissions %v, which can not be granted by the custom role", s.graphPermissions)
+	}
+
+	granted := make(map[string]bool)
+	for _, permission := range s.grantedGraphPermissions {
+		granted[permission] = true
+	}
+
+	var permissionsToGrant []string
+	for _, permission := range graphPermissions {
+		if !granted[permission] {
+			permissionsToGrant = append(permissionsToGrant, permission)
+		}
+	}
+	if len(permissionsToGrant) == 0 {
+		return fmt.Errorf("the Microsoft Graph permissions %v are granted to the service principal, but the deployment is still not authorized by Microsoft Graph", graphPermissions)
+	}
+
+	log.Infof("Granting Microsoft Graph permissions %v to service principal \n", permissionsToGrant)
+	createdPermissions, err := s.graphPermissionManager.AssignGraphPermissionsToSP(s.mpfConfig.SP.SPObjectID, permissionsToGrant)
+	// only the permissions assigned by MPF are revoked, and not the permissions the service principal already had
+	s.createdGraphPermissions = append(s.createdGraphPermissions, createdPermissions...)
+	if err != nil {
+		return err
+	}
+	s.grantedGraphPermissions = append(s.grantedGraphPermissions, permissionsToGrant...)
+
+	// app role assignments are part of the tokens issued after they propagate
+	time.Sleep(s.roleUpdatePropagationWait)
+	return nil
+}
+
+// revokeGraphPermissions revokes the Microsoft Graph permissions assigned to the service principal during the run.
+// Permissions the service principal had before the run are kept
+func (s *MPFService) revokeGraphPermissions() {
+	if s.graphPermissionManager == nil || len(s.grantedGraphPermissions) == 0 {
+		return
+	}
+
+	if len(s.createdGraphPermissions) > 0 {
+		err := s.graphPermissionManager.RevokeGraphPermissionsFromSP(s.mpfConfig.SP.SPObjectID, s.createdGraphPermissions)
+		if err != nil {
+			log.Warnf("Could not revoke Microsoft Graph permissions from SP: %s\n", err)
+			return
+		}
+	}
+	s.grantedGraphPermissions = nil
+	s.createdGraphPermissions = nil
+}
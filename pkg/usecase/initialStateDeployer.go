@@ -0,0 +1,43 @@
+package usecase
+
+import (
+	"fmt"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+// InitialStateDeployer deploys the initial state of an update scenario with the credentials MPF runs with, so that the
+// deployment of the checker updates existing resources, and the permissions required to update them are discovered
+type InitialStateDeployer interface {
+	DeployInitialState(mpfConfig domain.MPFConfig) error
+}
+
+// SetInitialStateDeployer selects the update scenario, in which the initial state is deployed before permissions are discovered
+func (s *MPFService) SetInitialStateDeployer(initialStateDeployer InitialStateDeployer) {
+	s.initialStateDeployer = initialStateDeployer
+}
+
+func (s *MPFService) deployInitialState() error {
+	if s.initialStateDeployer == nil {
+		return nil
+	}
+
+	log.Infoln("Deploying initial state of the update scenario")
+	err := s.initialStateDeployer.DeployInitialState(s.mpfConfig)
+	if err != nil {
+		return fmt.Errorf("error deploying initial state of the update scenario: %w", err)
+	}
+	log.Infoln("Initial state of the update scenario deployed successfully")
+	return nil
+}
+
+// resetDeployment cleans the deployment before a check, and in the update scenario deploys the initial state again,
+// so that each check updates the existing resources
+func (s *MPFService) resetDeployment() error {
+	err := s.deploymentAuthCheckerCleaner.CleanDeployment(s.mpfConfig)
+	if err != nil {
+		log.Warnf("Cleaning up deployment before check returned an error: %v\n", err)
+	}
+	return s.deployInitialState()
+}
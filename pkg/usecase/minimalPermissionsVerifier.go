@@ -0,0 +1,210 @@
+package usecase
+
+import (
+	"sort"
+	"time"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+// Role definition updates take time to propagate, a permission removed from the role can still be granted right after the update
+const defaultRoleUpdatePropagationWait = 30 * time.Second
+
+// SetVerifyMinimal enables the ablation pass after the permissions have been discovered, which removes permissions from the
+// custom role and reruns the authorization checker, to mark each permission of the role as required or unnecessary
+func (s *MPFService) SetVerifyMinimal(verifyMinimal bool) {
+	s.verifyMinimal = verifyMinimal
+}
+
+// verifyMinimalPermissions marks each permission of the role as required or unnecessary
+func (s *MPFService) verifyMinimalPermissions() error {
+	candidates := getUniqueStrings(append(append([]string{}, s.initialPermissionsToAdd...), s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID]...))
+	log.Infof("Verifying that each of the %d permissions is required\n", len(candidates))
+
+	required, unnecessary, err := s.ablatePermissions(candidates, nil)
+	if err != nil {
+		return err
+	}
+
+	s.verifiedRequiredPermissions = required
+	s.unnecessaryPermissions = unnecessary
+	log.Infof("Verified %d permissions as required and %d as unnecessary\n", len(required), len(unnecessary))
+	return nil
+}
+
+// permissionsAblation removes groups of permissions from the role, halving the groups which can not be removed,
+// until each permission is marked as required or unnecessary. Permissions found to be unnecessary stay removed while
+// the remaining groups are checked, so that the unnecessary permissions can be removed together. Permissions of the group
+// named by the authorization error are marked as required without halving the group
+type permissionsAblation struct {
+	s              *MPFService
+	candidates     []string
+	retained       []string
+	required       []string
+	unnecessary    []string
+	requiredStages map[string]string
+}
+
+// ablatePermissions returns the candidate permissions which are required, and the ones which are unnecessary.
+// The retained permissions are kept in the role throughout
+func (s *MPFService) ablatePermissions(candidates []string, retained []string) ([]string, []string, error) {
+	ablation, err := s.runPermissionsAblation(candidates, retained)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ablation.required, ablation.unnecessary, nil
+}
+
+// runPermissionsAblation returns the ablation of the candidate permissions, with the stage of the checker each required
+// permission was found in
+func (s *MPFService) runPermissionsAblation(candidates []string, retained []string) (*permissionsAblation, error) {
+	ablation := &permissionsAblation{
+		s:              s,
+		candidates:     candidates,
+		retained:       retained,
+		required:       []string{},
+		unnecessary:    []string{},
+		requiredStages: make(map[string]string),
+	}
+
+	err := ablation.ablate(candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(ablation.required)
+	sort.Strings(ablation.unnecessary)
+	return ablation, nil
+}
+
+func (a *permissionsAblation) ablate(group []string) error {
+	if len(group) == 0 {
+		return nil
+	}
+
+	authErrMesg, authorized, err := a.checkWithoutPermissions(group)
+	if err != nil {
+		return err
+	}
+
+	if authorized {
+		log.Infof("Permissions not required: %v\n", group)
+		a.unnecessary = append(a.unnecessary, group...)
+		return nil
+	}
+
+	named, rest := splitPermissionsNamedByAuthorizationError(group, authErrMesg)
+	if len(named) > 0 {
+		log.Infof("Permissions required: %v\n", named)
+		a.markRequired(named...)
+		return a.ablate(rest)
+	}
+
+	if len(group) == 1 {
+		log.Infof("Permission required: %s\n", group[0])
+		a.markRequired(group[0])
+		return nil
+	}
+
+	mid := len(group) / 2
+	err = a.ablate(group[:mid])
+	if err != nil {
+		return err
+	}
+	return a.ablate(group[mid:])
+}
+
+// markRequired marks the permissions as required, in the current stage of the checker
+func (a *permissionsAblation) markRequired(permissions ...string) {
+	stage := ""
+	if stageReporter, ok := a.s.deploymentAuthCheckerCleaner.(DeploymentAuthorizationCheckerStageReporter); ok {
+		stage = stageReporter.GetCurrentStage()
+	}
+	for _, permission := range permissions {
+		a.required = append(a.required, permission)
+		a.requiredStages[permission] = stage
+	}
+}
+
+// splitPermissionsNamedByAuthorizationError returns the permissions of the group named by the authorization error, and the rest
+func splitPermissionsNamedByAuthorizationError(group []string, authErrMesg string) ([]string, []string) {
+	if authErrMesg == "" {
+		return nil, group
+	}
+
+	scopePermissions, err := domain.GetScopePermissionsFromAuthError(authErrMesg)
+	if err != nil {
+		return nil, group
+	}
+
+	namedPermissions := make(map[string]bool)
+	for _, permissions := range scopePermissions {
+		for _, permission := range permissions {
+			namedPermissions[permission] = true
+		}
+	}
+
+	var named, rest []string
+	for _, permission := range group {
+		if namedPermissions[permission] {
+			named = append(named, permission)
+		} else {
+			rest = append(rest, permission)
+		}
+	}
+	return named, rest
+}
+
+// checkWithoutPermissions updates the role to the candidates without the group and the unnecessary permissions found so far,
+// and returns the authorization error, and whether the deployment is authorized. A non authorization error of the checker aborts the
+// ablation, as it does not tell whether the group is required
+func (a *permissionsAblation) checkWithoutPermissions(group []string) (string, bool, error) {
+	removed := make(map[string]bool)
+	for _, permission := range append(append([]string{}, a.unnecessary...), group...) {
+		removed[permission] = true
+	}
+
+	rolePermissions := append([]string{}, a.retained...)
+	for _, permission := range a.candidates {
+		if !removed[permission] {
+			rolePermissions = append(rolePermissions, permission)
+		}
+	}
+
+	return a.s.checkWithPermissions(rolePermissions)
+}
+
+// checkWithPermissions updates the role to the permissions, and returns the authorization error, and whether the deployment is authorized
+func (s *MPFService) checkWithPermissions(rolePermissions []string) (string, bool, error) {
+	err := s.spRoleAssignmentManager.CreateUpdateCustomRole(s.mpfConfig.SubscriptionID, s.mpfConfig.Role, rolePermissions)
+	if err != nil {
+		return "", false, err
+	}
+	time.Sleep(s.roleUpdatePropagationWait)
+
+	// start each check from a clean deployment, so that resources created by an earlier check do not hide missing permissions
+	err = s.resetDeployment()
+	if err != nil {
+		return "", false, err
+	}
+
+	authErrMesg, err := s.deploymentAuthCheckerCleaner.GetDeploymentAuthorizationErrors(s.mpfConfig)
+	if err != nil {
+		log.Warnf("Non authorization error received: %v\n", err)
+		return "", false, err
+	}
+	return authErrMesg, authErrMesg == "", nil
+}
+
+func getUniqueStrings(s []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, val := range s {
+		if !seen[val] {
+			seen[val] = true
+			unique = append(unique, val)
+		}
+	}
+	return unique
+}
@@ -2,7 +2,7 @@ package usecase
 
 import (
 	"context"
-	"strings"
+	"time"
 
 	"github.com/manisbindra/az-mpf/pkg/domain"
 	log "github.com/sirupsen/logrus"
@@ -21,9 +21,24 @@ type MPFService struct {
 	initialPermissionsToAdd             []string
 	permissionsToAddToResult            []string
 	requiredPermissions                 map[string][]string
+	requiredPermissionsByStage          map[string][]string
 	autoAddReadPermissionForEachWrite   bool
 	autoAddDeletePermissionForEachWrite bool
 	autoCreateResourceGroup             bool
+	predictedPermissions                []string
+	predictedPermissionsVerified        bool
+	verifyMinimal                       bool
+	roleUpdatePropagationWait           time.Duration
+	verifiedRequiredPermissions         []string
+	unnecessaryPermissions              []string
+	sufficiencyVerificationRole         *domain.Role
+	sufficiencyVerification             *domain.SufficiencyVerification
+	discoveryStrategy                   DiscoveryStrategy
+	initialStateDeployer                InitialStateDeployer
+	graphPermissions                    []string
+	graphPermissionManager              GraphPermissionManager
+	grantedGraphPermissions             []string
+	createdGraphPermissions             []string
 }
 
 func NewMPFService(ctx context.Context, rgMgr ResourceGroupManager, spRoleAssgnMgr ServicePrincipalRolemAssignmentManager, deploymentAuthChkCln DeploymentAuthorizationCheckerCleaner, mpfConfig domain.MPFConfig, initialPermissionsToAdd []string, permissionsToAddToResult []string, autoAddReadPermissionForEachWrite bool, autoAddDeletePermissionForEachWrite bool, autoCreateResourceGroup bool) *MPFService {
@@ -36,20 +51,77 @@ func NewMPFService(ctx context.Context, rgMgr ResourceGroupManager, spRoleAssgnM
 		initialPermissionsToAdd:             initialPermissionsToAdd,
 		permissionsToAddToResult:            permissionsToAddToResult,
 		requiredPermissions:                 make(map[string][]string),
+		requiredPermissionsByStage:          make(map[string][]string),
 		autoAddReadPermissionForEachWrite:   autoAddReadPermissionForEachWrite,
 		autoAddDeletePermissionForEachWrite: autoAddDeletePermissionForEachWrite,
 		autoCreateResourceGroup:             autoCreateResourceGroup,
+		roleUpdatePropagationWait:           defaultRoleUpdatePropagationWait,
 	}
 }
 
+// SetPredictedPermissions seeds the custom role with the predicted permissions, so that the predicted permissions are not
+// discovered one authorization error at a time. Once authorization succeeds, the predicted permissions are pruned by bisection,
+// with the permissions found so far kept in the role, so that only the predicted permissions which are required are added
+// to the result
+func (s *MPFService) SetPredictedPermissions(predictedPermissions []string) {
+	s.predictedPermissions = predictedPermissions
+}
+
+// prunePredictedPermissions removes the predicted permissions which are not required from the role, and adds the ones
+// which are required to the required permissions
+func (s *MPFService) prunePredictedPermissions() error {
+	found := make(map[string]bool)
+	for _, permission := range s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID] {
+		found[permission] = true
+	}
+
+	var candidates []string
+	for _, permission := range getUniqueStrings(s.predictedPermissions) {
+		if !found[permission] {
+			candidates = append(candidates, permission)
+		}
+	}
+
+	retained := append(append([]string{}, s.initialPermissionsToAdd...), s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID]...)
+	ablation, err := s.runPermissionsAblation(candidates, retained)
+	if err != nil {
+		return err
+	}
+	confirmed, pruned := ablation.required, ablation.unnecessary
+	log.Infof("Confirmed %d predicted permissions, pruned %d predicted permissions which are not required\n", len(confirmed), len(pruned))
+
+	s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID] = append(s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID], confirmed...)
+	if _, ok := s.deploymentAuthCheckerCleaner.(DeploymentAuthorizationCheckerStageReporter); ok {
+		for _, permission := range confirmed {
+			stage := ablation.requiredStages[permission]
+			s.requiredPermissionsByStage[stage] = append(s.requiredPermissionsByStage[stage], permission)
+		}
+	}
+	s.predictedPermissionsVerified = true
+
+	return s.spRoleAssignmentManager.CreateUpdateCustomRole(s.mpfConfig.SubscriptionID, s.mpfConfig.Role, s.getRolePermissions())
+}
+
 func (s *MPFService) returnMPFResult(err error) (domain.MPFResult, error) {
-	mpfResult := domain.GetMPFResult(s.requiredPermissions)
+	mpfResult := domain.GetMPFResultWithStages(s.requiredPermissions, s.requiredPermissionsByStage)
+	if stageReporter, ok := s.deploymentAuthCheckerCleaner.(DeploymentAuthorizationCheckerStageReporter); ok && len(s.requiredPermissionsByStage) > 0 {
+		mpfResult.RolePermissionsByStage = domain.GetRolePermissionsByStage(stageReporter.GetStages(), s.requiredPermissionsByStage, s.permissionsToAddToResult)
+	}
+	if s.predictedPermissionsVerified {
+		mpfResult.ConfirmedPredictedPermissions, mpfResult.PrunedPredictedPermissions = domain.SplitPredictedPermissions(s.predictedPermissions, mpfResult.RequiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID])
+	}
+	mpfResult.VerifiedRequiredPermissions = s.verifiedRequiredPermissions
+	mpfResult.UnnecessaryPermissions = s.unnecessaryPermissions
+	mpfResult.SufficiencyVerification = s.sufficiencyVerification
+	if len(s.graphPermissions) > 0 {
+		mpfResult.GraphPermissions = domain.GetUniqueSortedPermissions(s.graphPermissions)
+	}
 
-	if err != nil && len(mpfResult.RequiredPermissions) == 0 {
+	if err != nil && len(mpfResult.RequiredPermissions) == 0 && len(mpfResult.GraphPermissions) == 0 {
 		return domain.MPFResult{}, err
 	}
 
-	if err != nil && len(mpfResult.RequiredPermissions) > 0 {
+	if err != nil {
 		return mpfResult, err
 	}
 
@@ -71,8 +143,14 @@ func (s *MPFService) GetMinimumPermissionsRequired() (domain.MPFResult, error) {
 
 	defer s.CleanUpResources()
 
+	// In the update scenario, the initial state is deployed with the credentials MPF runs with, before the role is assigned
+	err := s.deployInitialState()
+	if err != nil {
+		return s.returnMPFResult(err)
+	}
+
 	// Delete all existing role assignments for the service principal
-	err := s.spRoleAssignmentManager.DetachRolesFromSP(s.ctx, s.mpfConfig.SubscriptionID, s.mpfConfig.SP.SPObjectID, s.mpfConfig.Role)
+	err = s.spRoleAssignmentManager.DetachRolesFromSP(s.ctx, s.mpfConfig.SubscriptionID, s.mpfConfig.SP.SPObjectID, s.mpfConfig.Role)
 	if err != nil {
 		log.Warnf("Unable to delete Role Assignments: %v\n", err)
 		return s.returnMPFResult(err)
@@ -83,7 +161,10 @@ func (s *MPFService) GetMinimumPermissionsRequired() (domain.MPFResult, error) {
 	log.Infoln("Initializing Custom Role")
 	// err = mpf.CreateUpdateCustomRole([]string{})
 
-	err = s.spRoleAssignmentManager.CreateUpdateCustomRole(s.mpfConfig.SubscriptionID, s.mpfConfig.Role, s.initialPermissionsToAdd)
+	if len(s.predictedPermissions) > 0 {
+		log.Infof("Seeding custom role with %d predicted permissions\n", len(s.predictedPermissions))
+	}
+	err = s.spRoleAssignmentManager.CreateUpdateCustomRole(s.mpfConfig.SubscriptionID, s.mpfConfig.Role, s.getRolePermissions())
 	if err != nil {
 		log.Warn(err)
 		return s.returnMPFResult(err)
@@ -107,87 +188,22 @@ func (s *MPFService) GetMinimumPermissionsRequired() (domain.MPFResult, error) {
 	}
 	// s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID] = append(s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID], s.permissionsToAddToResult...)
 
-	maxIterations := 50
-	iterCount := 0
-	for {
-		authErrMesg, err := s.deploymentAuthCheckerCleaner.GetDeploymentAuthorizationErrors(s.mpfConfig)
-
-		log.Debugf("Iteration Number: %d \n", iterCount)
-
-		if authErrMesg == "" && err == nil {
-			log.Infoln("Authorization Successful")
-			break
-		}
-
-		log.Debugln("authErrMesg: ", authErrMesg)
-
-		// Temporary fix to workaround issue https://github.com/hashicorp/terraform-provider-azurerm/issues/27961
-		// It is observed only once, so retrying works
-		if err == nil && strings.Contains(authErrMesg, BillingFeaturesPayloadError) {
-			log.Warnf("Billing Features Payload Error: %v, retrying.... \n", err)
-			continue
-		}
-
-		// if err == nil && strings.Contains(err.Error(), AuthorizationPermissionMismatchErr) {
-		// 	log.Warnf("AuthrorizationPermissionMismatchErr Error: %v, retrying.... \n", err)
-		// 	continue
-		// }
-
-		if err != nil {
-			log.Warnf("Non Authorization error received: %v \n", err)
-			return s.returnMPFResult(err)
-		}
-
-		log.Debugln("Deployment Authorization Error:", authErrMesg)
+	err = s.getDiscoveryStrategy().DiscoverPermissions(s)
+	if err != nil {
+		return s.returnMPFResult(err)
+	}
 
-		scpMp, err := domain.GetScopePermissionsFromAuthError(authErrMesg)
+	if s.verifyMinimal {
+		err = s.verifyMinimalPermissions()
 		if err != nil {
-			log.Warnf("Could Not Parse Deployment Authorization Error: %v \n", err)
+			log.Warnf("Error verifying minimal permissions: %v\n", err)
 			return s.returnMPFResult(err)
 		}
-
-		log.Infoln("Successfully Parsed Deployment Authorization Error")
-		log.Debugln("scope permissions found from deployment error:", scpMp)
-
-		// auto add read and delete permissions as per configuration
-		for scope, permissions := range scpMp {
-			for _, permission := range permissions {
-				if s.autoAddReadPermissionForEachWrite && strings.HasSuffix(permission, "/write") {
-					readPermission := strings.Replace(permission, "/write", "/read", 1)
-					scpMp[scope] = append(scpMp[scope], readPermission)
-				}
-				if s.autoAddDeletePermissionForEachWrite && strings.HasSuffix(permission, "/write") {
-					deletePermission := strings.Replace(permission, "/write", "/delete", 1)
-					scpMp[scope] = append(scpMp[scope], deletePermission)
-				}
-			}
-		}
-
-		log.Infoln("Adding mising scopes/permissions to final result map...")
-		for k, v := range scpMp {
-			s.requiredPermissions[k] = append(s.requiredPermissions[k], v...)
-			s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID] = append(s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID], v...)
-		}
-
-		// assign permission to role
-		log.Infoln("Adding permission/scope to role...........")
-		log.Debugln("Number of Permissions added to role:", len(s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID]))
-
-		permissionsIncludingInitialPermissions := append(s.initialPermissionsToAdd, s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID]...)
-		err = s.spRoleAssignmentManager.CreateUpdateCustomRole(s.mpfConfig.SubscriptionID, s.mpfConfig.Role, permissionsIncludingInitialPermissions)
-
-		// err = s.spRoleAssignmentManager.CreateUpdateCustomRole(s.mpfConfig.SubscriptionID, s.mpfConfig.ResourceGroup.ResourceGroupName, s.mpfConfig.Role, s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID])
-
+	}
+	if s.sufficiencyVerificationRole != nil {
+		err = s.verifySufficiency()
 		if err != nil {
-			log.Infoln("Error when adding permission/scope to role: \n", err)
-			log.Warn(err)
-			return s.returnMPFResult(err)
-		}
-		log.Infoln("Permission/scope added to role successfully")
-
-		iterCount++
-		if iterCount == maxIterations {
-			log.Warnln("max iterations for fetching authorization errors reached, exiting...")
+			log.Warnf("Error verifying sufficiency of permissions: %v\n", err)
 			return s.returnMPFResult(err)
 		}
 	}
@@ -196,6 +212,17 @@ func (s *MPFService) GetMinimumPermissionsRequired() (domain.MPFResult, error) {
 
 }
 
+// getRolePermissions returns the permissions of the custom role, that is the initial permissions, the permissions found
+// so far, and the predicted permissions until they are pruned
+func (s *MPFService) getRolePermissions() []string {
+	rolePermissions := append([]string{}, s.initialPermissionsToAdd...)
+	rolePermissions = append(rolePermissions, s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID]...)
+	if !s.predictedPermissionsVerified {
+		rolePermissions = append(rolePermissions, s.predictedPermissions...)
+	}
+	return rolePermissions
+}
+
 func (s *MPFService) CleanUpResources() {
 	log.Infoln("Cleaning up resources...")
 	log.Infoln("*************************")
@@ -214,6 +241,8 @@ func (s *MPFService) CleanUpResources() {
 		log.Warnf("Could not detach roles from SP: %s\n", err)
 	}
 
+	s.revokeGraphPermissions()
+
 	// Delete Custom Role
 	err = s.spRoleAssignmentManager.DeleteCustomRole(s.mpfConfig.SubscriptionID, s.mpfConfig.Role)
 	if err != nil {
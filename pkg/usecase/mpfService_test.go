@@ -0,0 +1,373 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+const testResourceGroupResourceID = "/subscriptions/SSSSSSSS-SSSS-SSSS-SSSS-SSSSSSSSSSSS/resourceGroups/testdeployrg"
+
+type fakeResourceGroupManager struct{}
+
+func (f *fakeResourceGroupManager) CreateResourceGroup(ctx context.Context, rgName, location string) error {
+	return nil
+}
+
+func (f *fakeResourceGroupManager) DeleteResourceGroup(ctx context.Context, rgName string) error {
+	return nil
+}
+
+// fakeRoleAssignmentManager keeps the permissions of the custom role, and counts the role updates
+type fakeRoleAssignmentManager struct {
+	permissions []string
+	updates     int
+}
+
+func (f *fakeRoleAssignmentManager) DetachRolesFromSP(ctx context.Context, subscription string, SPOBjectID string, role domain.Role) error {
+	return nil
+}
+
+func (f *fakeRoleAssignmentManager) AssignRoleToSP(subscription string, SPOBjectID string, role domain.Role) error {
+	return nil
+}
+
+func (f *fakeRoleAssignmentManager) CreateUpdateCustomRole(subscription string, role domain.Role, permissions []string) error {
+	f.permissions = append([]string{}, permissions...)
+	f.updates++
+	return nil
+}
+
+func (f *fakeRoleAssignmentManager) DeleteCustomRole(subscription string, role domain.Role) error {
+	return nil
+}
+
+// fakePermissionsChecker returns an AuthorizationFailed error for the first required permission missing from the custom role
+type fakePermissionsChecker struct {
+	roleManager         *fakeRoleAssignmentManager
+	requiredPermissions []string
+	calls               int
+}
+
+func (f *fakePermissionsChecker) GetDeploymentAuthorizationErrors(mpfConfig domain.MPFConfig) (string, error) {
+	f.calls++
+	granted := make(map[string]bool)
+	for _, permission := range f.roleManager.permissions {
+		granted[permission] = true
+	}
+
+	for _, permission := range f.requiredPermissions {
+		if !granted[permission] {
+			return fmt.Sprintf("{\"error\":{\"code\":\"AuthorizationFailed\",\"message\":\"The client 'XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX' with object id 'XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX' does not have authorization to perform action '%s' over scope '%s/providers/Test/resources/test' or the scope is invalid. If access was recently granted, please refresh your credentials.\"}}", permission, testResourceGroupResourceID), nil
+		}
+	}
+	return "", nil
+}
+
+func (f *fakePermissionsChecker) CleanDeployment(mpfConfig domain.MPFConfig) error {
+	return nil
+}
+
+func getTestMPFService(requiredPermissions []string) (*MPFService, *fakeRoleAssignmentManager, *fakePermissionsChecker) {
+	roleManager := &fakeRoleAssignmentManager{}
+	checker := &fakePermissionsChecker{roleManager: roleManager, requiredPermissions: requiredPermissions}
+	mpfConfig := domain.MPFConfig{
+		ResourceGroup: domain.ResourceGroup{
+			ResourceGroupName:       "testdeployrg",
+			ResourceGroupResourceID: testResourceGroupResourceID,
+		},
+	}
+
+	mpfService := NewMPFService(context.Background(), &fakeResourceGroupManager{}, roleManager, checker, mpfConfig, []string{"Microsoft.Resources/deployments/*"}, []string{}, false, false, false)
+	return mpfService, roleManager, checker
+}
+
+func TestGetMinimumPermissionsRequired(t *testing.T) {
+	mpfService, _, checker := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write", "Microsoft.Network/virtualNetworks/read"})
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/read", "Microsoft.Network/virtualNetworks/write"}, mpfResult.RequiredPermissions[testResourceGroupResourceID])
+	assert.Equal(t, 3, checker.calls)
+	assert.Empty(t, mpfResult.ConfirmedPredictedPermissions)
+}
+
+func TestGetMinimumPermissionsRequiredWithPredictedPermissions(t *testing.T) {
+	mpfService, _, _ := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write", "Microsoft.Network/virtualNetworks/read", "Microsoft.ContainerService/managedClusters/write"})
+	mpfService.roleUpdatePropagationWait = 0
+	mpfService.SetPredictedPermissions([]string{"Microsoft.Network/virtualNetworks/write", "Microsoft.Network/virtualNetworks/read", "Microsoft.Network/virtualNetworks/subnets/join/action"})
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.ContainerService/managedClusters/write", "Microsoft.Network/virtualNetworks/read", "Microsoft.Network/virtualNetworks/write"}, mpfResult.RequiredPermissions[testResourceGroupResourceID])
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/read", "Microsoft.Network/virtualNetworks/write"}, mpfResult.ConfirmedPredictedPermissions)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/subnets/join/action"}, mpfResult.PrunedPredictedPermissions)
+}
+
+func TestGetMinimumPermissionsRequiredCheckerCallsWithPredictedPermissions(t *testing.T) {
+	requiredPermissions := []string{"Microsoft.Network/virtualNetworks/write", "Microsoft.Network/virtualNetworks/read", "Microsoft.ContainerService/managedClusters/write"}
+
+	mpfService, _, checker := getTestMPFService(requiredPermissions)
+	_, err := mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	callsWithoutPredictions := checker.calls
+	assert.Equal(t, 4, callsWithoutPredictions)
+
+	// the predicted permissions are confirmed from the authorization errors of the pruning checks, and are not discovered again
+	mpfService, roleManager, checker := getTestMPFService(requiredPermissions)
+	mpfService.roleUpdatePropagationWait = 0
+	mpfService.SetPredictedPermissions([]string{"Microsoft.Network/virtualNetworks/write", "Microsoft.Network/virtualNetworks/read"})
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	assert.LessOrEqual(t, checker.calls, callsWithoutPredictions)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/read", "Microsoft.Network/virtualNetworks/write"}, mpfResult.ConfirmedPredictedPermissions)
+	assert.ElementsMatch(t, append([]string{"Microsoft.Resources/deployments/*"}, requiredPermissions...), roleManager.permissions)
+
+	// the predicted permissions which are not required are removed together, with one more check
+	mpfService, roleManager, checker = getTestMPFService(requiredPermissions)
+	mpfService.roleUpdatePropagationWait = 0
+	mpfService.SetPredictedPermissions([]string{"Microsoft.Network/virtualNetworks/write", "Microsoft.Network/virtualNetworks/read", "Microsoft.Network/virtualNetworks/subnets/join/action", "Microsoft.Network/networkSecurityGroups/write"})
+	mpfResult, err = mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	assert.Equal(t, callsWithoutPredictions+1, checker.calls)
+	assert.Equal(t, []string{"Microsoft.Network/networkSecurityGroups/write", "Microsoft.Network/virtualNetworks/subnets/join/action"}, mpfResult.PrunedPredictedPermissions)
+	assert.ElementsMatch(t, append([]string{"Microsoft.Resources/deployments/*"}, requiredPermissions...), roleManager.permissions)
+}
+
+func TestGetMinimumPermissionsRequiredWithVerifyMinimal(t *testing.T) {
+	mpfService, _, _ := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write", "Microsoft.ContainerService/managedClusters/write"})
+	mpfService.autoAddReadPermissionForEachWrite = true
+	mpfService.roleUpdatePropagationWait = 0
+	mpfService.SetVerifyMinimal(true)
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.ContainerService/managedClusters/write", "Microsoft.Network/virtualNetworks/write"}, mpfResult.VerifiedRequiredPermissions)
+	assert.Equal(t, []string{"Microsoft.ContainerService/managedClusters/read", "Microsoft.Network/virtualNetworks/read", "Microsoft.Resources/deployments/*"}, mpfResult.UnnecessaryPermissions)
+	assert.Len(t, mpfResult.RequiredPermissions[testResourceGroupResourceID], 4)
+}
+
+func TestGetMinimumPermissionsRequiredWithSufficiencyVerification(t *testing.T) {
+	mpfService, roleManager, _ := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write"})
+	mpfService.roleUpdatePropagationWait = 0
+	mpfService.SetSufficiencyVerificationRole(domain.Role{RoleDefinitionName: "verification-role"})
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	assert.True(t, mpfResult.SufficiencyVerification.Passed)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/write", "Microsoft.Resources/deployments/*"}, roleManager.permissions)
+	assert.Equal(t, []string{"Microsoft.Resources/deployments/*"}, mpfResult.SufficiencyVerification.InitialPermissions)
+	assert.Equal(t, "verification-role", mpfService.mpfConfig.Role.RoleDefinitionName)
+}
+
+func TestGetMinimumPermissionsRequiredWithSufficiencyVerificationOfInitialPermissions(t *testing.T) {
+	// the initial permissions, which every check needs, are in the verification role, but not in the result
+	mpfService, _, _ := getTestMPFService([]string{"Microsoft.Resources/deployments/*", "Microsoft.Network/virtualNetworks/write"})
+	mpfService.roleUpdatePropagationWait = 0
+	mpfService.SetSufficiencyVerificationRole(domain.Role{RoleDefinitionName: "verification-role"})
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	assert.True(t, mpfResult.SufficiencyVerification.Passed)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/write"}, mpfResult.RequiredPermissions[testResourceGroupResourceID])
+	assert.Equal(t, []string{"Microsoft.Resources/deployments/*"}, mpfResult.SufficiencyVerification.InitialPermissions)
+}
+
+// fakeFailingPermissionsChecker fails with a non authorization error once it has been called the number of times of failAfterCalls
+type fakeFailingPermissionsChecker struct {
+	*fakePermissionsChecker
+	failAfterCalls int
+}
+
+func (f *fakeFailingPermissionsChecker) GetDeploymentAuthorizationErrors(mpfConfig domain.MPFConfig) (string, error) {
+	if f.calls >= f.failAfterCalls {
+		f.calls++
+		return "", fmt.Errorf("ResourceGroupNotFound")
+	}
+	return f.fakePermissionsChecker.GetDeploymentAuthorizationErrors(mpfConfig)
+}
+
+func TestGetMinimumPermissionsRequiredWithFailedSufficiencyVerification(t *testing.T) {
+	mpfService, _, checker := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write"})
+	mpfService.deploymentAuthCheckerCleaner = &fakeFailingPermissionsChecker{fakePermissionsChecker: checker, failAfterCalls: 2}
+	mpfService.roleUpdatePropagationWait = 0
+	mpfService.SetSufficiencyVerificationRole(domain.Role{RoleDefinitionName: "verification-role"})
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	assert.False(t, mpfResult.SufficiencyVerification.Passed)
+	assert.Contains(t, mpfResult.SufficiencyVerification.Details, "ResourceGroupNotFound")
+}
+
+func TestGetMinimumPermissionsRequiredWithFailedVerifyMinimal(t *testing.T) {
+	mpfService, _, checker := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write", "Microsoft.ContainerService/managedClusters/write"})
+	// the discovery takes three checks, the first check of the ablation fails
+	mpfService.deploymentAuthCheckerCleaner = &fakeFailingPermissionsChecker{fakePermissionsChecker: checker, failAfterCalls: 3}
+	mpfService.roleUpdatePropagationWait = 0
+	mpfService.SetVerifyMinimal(true)
+
+	_, err := mpfService.GetMinimumPermissionsRequired()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ResourceGroupNotFound")
+	assert.Equal(t, 4, checker.calls)
+}
+
+func TestGetMinimumPermissionsRequiredWithSubtractiveDiscovery(t *testing.T) {
+	mpfService, _, _ := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write", "Microsoft.ContainerService/managedClusters/write"})
+	mpfService.roleUpdatePropagationWait = 0
+	mpfService.SetDiscoveryStrategy(NewSubtractiveDiscoveryStrategy([]string{
+		"Microsoft.ContainerService/managedClusters/read",
+		"Microsoft.ContainerService/managedClusters/write",
+		"Microsoft.Network/virtualNetworks/read",
+		"Microsoft.Network/virtualNetworks/write",
+		"Microsoft.Storage/storageAccounts/write",
+	}))
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.ContainerService/managedClusters/write", "Microsoft.Network/virtualNetworks/write"}, mpfResult.RequiredPermissions[testResourceGroupResourceID])
+}
+
+func TestGetMinimumPermissionsRequiredWithInsufficientBroadPermissions(t *testing.T) {
+	mpfService, _, _ := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write"})
+	mpfService.roleUpdatePropagationWait = 0
+	mpfService.SetDiscoveryStrategy(NewSubtractiveDiscoveryStrategy([]string{"Microsoft.Storage/storageAccounts/write"}))
+
+	_, err := mpfService.GetMinimumPermissionsRequired()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not authorized")
+}
+
+func TestGetMinimumPermissionsRequiredWithFailedBroadPermissionsCheck(t *testing.T) {
+	mpfService, _, checker := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write"})
+	mpfService.deploymentAuthCheckerCleaner = &fakeFailingPermissionsChecker{fakePermissionsChecker: checker, failAfterCalls: 0}
+	mpfService.roleUpdatePropagationWait = 0
+	mpfService.SetDiscoveryStrategy(NewSubtractiveDiscoveryStrategy([]string{"Microsoft.Network/virtualNetworks/write"}))
+
+	_, err := mpfService.GetMinimumPermissionsRequired()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ResourceGroupNotFound")
+	assert.NotContains(t, err.Error(), "not authorized")
+}
+
+// fakeInitialStateDeployer counts the deployments of the initial state, and can fail them
+type fakeInitialStateDeployer struct {
+	deployments int
+	err         error
+}
+
+func (f *fakeInitialStateDeployer) DeployInitialState(mpfConfig domain.MPFConfig) error {
+	f.deployments++
+	return f.err
+}
+
+func TestGetMinimumPermissionsRequiredWithUpdateScenario(t *testing.T) {
+	mpfService, _, _ := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write"})
+	mpfService.roleUpdatePropagationWait = 0
+	mpfService.SetSufficiencyVerificationRole(domain.Role{RoleDefinitionName: "verification-role"})
+	initialStateDeployer := &fakeInitialStateDeployer{}
+	mpfService.SetInitialStateDeployer(initialStateDeployer)
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/write"}, mpfResult.RequiredPermissions[testResourceGroupResourceID])
+	// the initial state is deployed again after the deployment is cleaned for the sufficiency verification
+	assert.Equal(t, 2, initialStateDeployer.deployments)
+}
+
+func TestGetMinimumPermissionsRequiredWithFailedInitialStateDeployment(t *testing.T) {
+	mpfService, _, checker := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write"})
+	mpfService.SetInitialStateDeployer(&fakeInitialStateDeployer{err: fmt.Errorf("deployment failed")})
+
+	_, err := mpfService.GetMinimumPermissionsRequired()
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, checker.calls)
+}
+
+// fakeGraphPermissionManager records the Microsoft Graph permissions granted to the service principal, and assigns the
+// permissions which are not already assigned to it
+type fakeGraphPermissionManager struct {
+	assigned    []string
+	permissions []string
+	revoked     []string
+}
+
+func (f *fakeGraphPermissionManager) AssignGraphPermissionsToSP(SPObjectID string, permissions []string) ([]string, error) {
+	f.permissions = append(f.permissions, permissions...)
+
+	var created []string
+	for _, permission := range permissions {
+		if !slices.Contains(f.assigned, permission) {
+			created = append(created, permission)
+		}
+	}
+	f.assigned = append(f.assigned, created...)
+	return created, nil
+}
+
+func (f *fakeGraphPermissionManager) RevokeGraphPermissionsFromSP(SPObjectID string, permissions []string) error {
+	f.revoked = append(f.revoked, permissions...)
+	return nil
+}
+
+// fakeGraphPermissionsChecker returns a Microsoft Graph authorization error of the azuread provider once the required
+// permissions are granted to the custom role, until the Microsoft Graph permission is granted to the service principal
+type fakeGraphPermissionsChecker struct {
+	*fakePermissionsChecker
+	graphPermissionManager *fakeGraphPermissionManager
+}
+
+func (f *fakeGraphPermissionsChecker) GetDeploymentAuthorizationErrors(mpfConfig domain.MPFConfig) (string, error) {
+	authErrMesg, err := f.fakePermissionsChecker.GetDeploymentAuthorizationErrors(mpfConfig)
+	if authErrMesg != "" || err != nil {
+		return authErrMesg, err
+	}
+	if f.graphPermissionManager != nil && len(f.graphPermissionManager.permissions) > 0 {
+		return "", nil
+	}
+	return "Error: Could not create application\n\n  with azuread_application.example,\n  on main.tf line 10, in resource \"azuread_application\" \"example\":\n\nApplicationsClient.BaseClient.Post(): unexpected status 403 with OData error: Authorization_RequestDenied: Insufficient privileges to complete the operation.\n", nil
+}
+
+func TestGetMinimumPermissionsRequiredWithGraphPermissions(t *testing.T) {
+	mpfService, _, checker := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write"})
+	mpfService.deploymentAuthCheckerCleaner = &fakeGraphPermissionsChecker{fakePermissionsChecker: checker}
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/write"}, mpfResult.RequiredPermissions[testResourceGroupResourceID])
+	assert.Equal(t, []string{"Application.ReadWrite.All"}, mpfResult.GraphPermissions)
+}
+
+func TestGetMinimumPermissionsRequiredWithGraphPermissionManager(t *testing.T) {
+	mpfService, _, checker := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write"})
+	mpfService.roleUpdatePropagationWait = 0
+	graphPermissionManager := &fakeGraphPermissionManager{}
+	mpfService.deploymentAuthCheckerCleaner = &fakeGraphPermissionsChecker{fakePermissionsChecker: checker, graphPermissionManager: graphPermissionManager}
+	mpfService.SetGraphPermissionManager(graphPermissionManager)
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Microsoft.Network/virtualNetworks/write"}, mpfResult.RequiredPermissions[testResourceGroupResourceID])
+	assert.Equal(t, []string{"Application.ReadWrite.All"}, mpfResult.GraphPermissions)
+	assert.Equal(t, []string{"Application.ReadWrite.All"}, graphPermissionManager.permissions)
+	// the Microsoft Graph permissions granted are revoked on clean up
+	assert.Equal(t, []string{"Application.ReadWrite.All"}, graphPermissionManager.revoked)
+}
+
+func TestGetMinimumPermissionsRequiredWithAlreadyAssignedGraphPermissions(t *testing.T) {
+	mpfService, _, checker := getTestMPFService([]string{"Microsoft.Network/virtualNetworks/write"})
+	mpfService.roleUpdatePropagationWait = 0
+	graphPermissionManager := &fakeGraphPermissionManager{assigned: []string{"Application.ReadWrite.All"}}
+	mpfService.deploymentAuthCheckerCleaner = &fakeGraphPermissionsChecker{fakePermissionsChecker: checker, graphPermissionManager: graphPermissionManager}
+	mpfService.SetGraphPermissionManager(graphPermissionManager)
+
+	mpfResult, err := mpfService.GetMinimumPermissionsRequired()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Application.ReadWrite.All"}, mpfResult.GraphPermissions)
+	// the permissions the service principal already had are not revoked on clean up
+	assert.Empty(t, graphPermissionManager.revoked)
+}
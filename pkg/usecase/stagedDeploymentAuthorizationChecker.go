@@ -0,0 +1,89 @@
+package usecase
+
+import (
+	"errors"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+// DeploymentAuthorizationCheckerStageReporter is implemented by checkers which discover permissions in multiple stages.
+// MPFService uses it to report which permissions were found in each stage, and the role required to run each stage
+type DeploymentAuthorizationCheckerStageReporter interface {
+	GetCurrentStage() string
+	// GetStages returns the names of the stages in the order they run
+	GetStages() []string
+}
+
+type DeploymentAuthorizationCheckerStage struct {
+	Name    string
+	Checker DeploymentAuthorizationCheckerCleaner
+}
+
+type stagedDeploymentAuthorizationChecker struct {
+	stages       []DeploymentAuthorizationCheckerStage
+	currentStage int
+}
+
+// NewStagedDeploymentAuthorizationChecker returns a checker which converges with each stage in order.
+// Once a stage no longer returns authorization errors, the next stage is checked with the permissions found so far
+func NewStagedDeploymentAuthorizationChecker(stages ...DeploymentAuthorizationCheckerStage) *stagedDeploymentAuthorizationChecker {
+	return &stagedDeploymentAuthorizationChecker{
+		stages: stages,
+	}
+}
+
+func (c *stagedDeploymentAuthorizationChecker) GetDeploymentAuthorizationErrors(mpfConfig domain.MPFConfig) (string, error) {
+	if len(c.stages) == 0 {
+		return "", errors.New("no deployment authorization checker stages configured")
+	}
+
+	for {
+		stage := c.stages[c.currentStage]
+		authErrMesg, err := stage.Checker.GetDeploymentAuthorizationErrors(mpfConfig)
+		if authErrMesg != "" || err != nil {
+			return authErrMesg, err
+		}
+
+		if c.currentStage == len(c.stages)-1 {
+			return "", nil
+		}
+
+		c.currentStage++
+		log.Infof("Stage %s converged, confirming with stage %s \n", stage.Name, c.stages[c.currentStage].Name)
+	}
+}
+
+func (c *stagedDeploymentAuthorizationChecker) GetCurrentStage() string {
+	if len(c.stages) == 0 {
+		return ""
+	}
+	return c.stages[c.currentStage].Name
+}
+
+func (c *stagedDeploymentAuthorizationChecker) GetStages() []string {
+	stages := make([]string, 0, len(c.stages))
+	for _, stage := range c.stages {
+		stages = append(stages, stage.Name)
+	}
+	return stages
+}
+
+// CleanDeployment cleans the deployment of each stage, and starts over with the first stage, so that the checks which start
+// from a clean deployment after discovery, such as the ablation, the sufficiency verification and the pruning of predicted
+// permissions, run all stages in order, and record the stage each permission is required in
+func (c *stagedDeploymentAuthorizationChecker) CleanDeployment(mpfConfig domain.MPFConfig) error {
+	c.currentStage = 0
+
+	var cleanErr error
+	for _, stage := range c.stages {
+		err := stage.Checker.CleanDeployment(mpfConfig)
+		if err != nil {
+			log.Warnf("Error cleaning deployment for stage %s: %v \n", stage.Name, err)
+			if cleanErr == nil {
+				cleanErr = err
+			}
+		}
+	}
+	return cleanErr
+}
@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDeploymentAuthorizationChecker returns the configured authorization error messages in order, and then no errors
+type fakeDeploymentAuthorizationChecker struct {
+	authErrMesgs []string
+	calls        int
+	cleaned      bool
+}
+
+func (f *fakeDeploymentAuthorizationChecker) GetDeploymentAuthorizationErrors(mpfConfig domain.MPFConfig) (string, error) {
+	f.calls++
+	if len(f.authErrMesgs) == 0 {
+		return "", nil
+	}
+	authErrMesg := f.authErrMesgs[0]
+	f.authErrMesgs = f.authErrMesgs[1:]
+	return authErrMesg, nil
+}
+
+func (f *fakeDeploymentAuthorizationChecker) CleanDeployment(mpfConfig domain.MPFConfig) error {
+	f.cleaned = true
+	return nil
+}
+
+func TestStagedDeploymentAuthorizationChecker(t *testing.T) {
+	first := &fakeDeploymentAuthorizationChecker{authErrMesgs: []string{"first-1", "first-2"}}
+	second := &fakeDeploymentAuthorizationChecker{authErrMesgs: []string{"second-1"}}
+
+	checker := NewStagedDeploymentAuthorizationChecker(
+		DeploymentAuthorizationCheckerStage{Name: "first", Checker: first},
+		DeploymentAuthorizationCheckerStage{Name: "second", Checker: second},
+	)
+
+	expected := []struct {
+		authErrMesg string
+		stage       string
+	}{
+		{"first-1", "first"},
+		{"first-2", "first"},
+		{"second-1", "second"},
+		{"", "second"},
+	}
+
+	for _, e := range expected {
+		authErrMesg, err := checker.GetDeploymentAuthorizationErrors(domain.MPFConfig{})
+		assert.Nil(t, err)
+		assert.Equal(t, e.authErrMesg, authErrMesg)
+		assert.Equal(t, e.stage, checker.GetCurrentStage())
+	}
+
+	assert.Nil(t, checker.CleanDeployment(domain.MPFConfig{}))
+	assert.True(t, first.cleaned)
+	assert.True(t, second.cleaned)
+
+	// a check from a clean deployment starts over with the first stage
+	assert.Equal(t, "first", checker.GetCurrentStage())
+	first.authErrMesgs = []string{"first-3"}
+	authErrMesg, err := checker.GetDeploymentAuthorizationErrors(domain.MPFConfig{})
+	assert.Nil(t, err)
+	assert.Equal(t, "first-3", authErrMesg)
+	assert.Equal(t, "first", checker.GetCurrentStage())
+}
+
+func TestStagedDeploymentAuthorizationCheckerWithoutStages(t *testing.T) {
+	checker := NewStagedDeploymentAuthorizationChecker()
+	_, err := checker.GetDeploymentAuthorizationErrors(domain.MPFConfig{})
+	assert.NotNil(t, err)
+	assert.Equal(t, "", checker.GetCurrentStage())
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/manisbindra/az-mpf/pkg/domain"
+	log "github.com/sirupsen/logrus"
+)
+
+// SetSufficiencyVerificationRole enables the verification stage after the permissions have been discovered. The verification role,
+// which must not exist yet, is created with the permissions of the result and the initial permissions every check needs, and
+// assigned to the service principal in place of the role used for discovery, and the checker is run once more end to end
+func (s *MPFService) SetSufficiencyVerificationRole(verificationRole domain.Role) {
+	s.sufficiencyVerificationRole = &verificationRole
+}
+
+func (s *MPFService) verifySufficiency() error {
+	verificationRole := *s.sufficiencyVerificationRole
+	resultPermissions := getUniqueStrings(s.requiredPermissions[s.mpfConfig.ResourceGroup.ResourceGroupResourceID])
+	initialPermissions := getPermissionsNotInResult(s.initialPermissionsToAdd, resultPermissions)
+	rolePermissions := append(append([]string{}, resultPermissions...), initialPermissions...)
+	log.Infof("Verifying that the %d permissions found are sufficient, with the fresh custom role %s and %d initial permissions\n", len(resultPermissions), verificationRole.RoleDefinitionName, len(initialPermissions))
+
+	err := s.spRoleAssignmentManager.DetachRolesFromSP(s.ctx, s.mpfConfig.SubscriptionID, s.mpfConfig.SP.SPObjectID, s.mpfConfig.Role)
+	if err != nil {
+		return err
+	}
+
+	err = s.spRoleAssignmentManager.DeleteCustomRole(s.mpfConfig.SubscriptionID, s.mpfConfig.Role)
+	if err != nil {
+		log.Warnf("Could not delete custom role used for discovery: %s\n", err)
+	}
+
+	// from here on the verification role is the role cleaned up by CleanUpResources
+	s.mpfConfig.Role = verificationRole
+
+	err = s.spRoleAssignmentManager.CreateUpdateCustomRole(s.mpfConfig.SubscriptionID, s.mpfConfig.Role, rolePermissions)
+	if err != nil {
+		return err
+	}
+
+	err = s.spRoleAssignmentManager.AssignRoleToSP(s.mpfConfig.SubscriptionID, s.mpfConfig.SP.SPObjectID, s.mpfConfig.Role)
+	if err != nil {
+		return err
+	}
+	time.Sleep(s.roleUpdatePropagationWait)
+
+	// start from a clean deployment, so that the verification covers the full deployment, for terraform the apply and destroy
+	err = s.resetDeployment()
+	if err != nil {
+		return err
+	}
+
+	authErrMesg, err := s.deploymentAuthCheckerCleaner.GetDeploymentAuthorizationErrors(s.mpfConfig)
+
+	verification := &domain.SufficiencyVerification{Passed: authErrMesg == "" && err == nil, InitialPermissions: initialPermissions}
+	switch {
+	case authErrMesg != "":
+		verification.Details = authErrMesg
+	case err != nil:
+		verification.Details = err.Error()
+	}
+	s.sufficiencyVerification = verification
+
+	if verification.Passed {
+		log.Infoln("Sufficiency verification passed")
+	} else {
+		log.Warnf("Sufficiency verification failed: %s\n", verification.Details)
+	}
+	return nil
+}
+
+// getPermissionsNotInResult returns the unique permissions which are not in the result permissions
+func getPermissionsNotInResult(permissions []string, resultPermissions []string) []string {
+	inResult := make(map[string]bool)
+	for _, permission := range resultPermissions {
+		inResult[permission] = true
+	}
+
+	var notInResult []string
+	for _, permission := range getUniqueStrings(permissions) {
+		if !inResult[permission] {
+			notInResult = append(notInResult, permission)
+		}
+	}
+	return notInResult
+}